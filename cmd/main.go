@@ -1,28 +1,142 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/template/html/v2"
 	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"github.com/latestcomment/go-websocket-chat/internal/broker"
+	"github.com/latestcomment/go-websocket-chat/internal/config"
 	"github.com/latestcomment/go-websocket-chat/internal/handlers"
+	"github.com/latestcomment/go-websocket-chat/internal/logging"
+	"github.com/latestcomment/go-websocket-chat/internal/metrics"
 	"github.com/latestcomment/go-websocket-chat/internal/models"
 	"github.com/latestcomment/go-websocket-chat/internal/services"
+	"github.com/latestcomment/go-websocket-chat/internal/store"
+	"github.com/latestcomment/go-websocket-chat/internal/tracing"
 )
 
 func main() {
-	engine := html.New("./static", ".html")
+	envErr := godotenv.Load()
+
+	// Defaults, then the config file, the environment and flags, each overriding the last
+	cfg, printOnly, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	if printOnly {
+		fmt.Println(cfg)
+		return
+	}
+	logging.Setup(os.Stdout, cfg.Logging)
+	if envErr != nil {
+		slog.Debug("no .env file found")
+	}
+	slog.Info("effective configuration", "config", cfg)
+
+	// Background loops stop on SIGINT or SIGTERM, before the channels are checkpointed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	engine := html.New(cfg.Server.TemplateDir, ".html")
 	app := fiber.New(fiber.Config{
-		Views: engine,
+		Views:                 engine,
+		DisableStartupMessage: cfg.Logging.Format == "json", // The banner would break line-per-record output
+		// The proxy header is only read on requests from the trusted proxies; others could forge it
+		ProxyHeader:             cfg.Server.ProxyHeader,
+		EnableTrustedProxyCheck: cfg.Server.ProxyHeader != "",
+		TrustedProxies:          cfg.Server.TrustedProxies,
+		EnableIPValidation:      true,
 	})
-	app.Use(logger.New())
+	app.Use(logging.RequestIDs())
+	app.Use(tracing.Middleware())
+	app.Use(logging.Middleware(cfg.Logging))
+	app.Use(handlers.BindWebSocketBase(cfg.Server.WebSocketBase()))
+	app.Use(handlers.Sessions())
+
+	manager := &models.ChannelManager{
+		Channels: make(map[string]*models.Channel),
+		Archives: make(map[string][]*models.Archive),
+	}
+	st, err := store.New(cfg.DataDir)
+	if err != nil {
+		fatal("opening data dir", err)
+	}
+	if !st.Persistent() {
+		slog.Warn("DATA_DIR not set, ratings, tournaments, schedules and live debates will not survive a restart")
+	}
+	ratings, err := services.NewRatingService(st)
+	if err != nil {
+		fatal("loading ratings", err)
+	}
+
+	// Instances sharing a broker relay sockets to whichever of them owns the channel
+	instance := cfg.Cluster.InstanceID
+	if instance == "" {
+		hostname, _ := os.Hostname()
+		instance = hostname + "-" + uuid.NewString()[:8]
+	}
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, instance)
+	if err != nil {
+		fatal("setting up tracing", err)
+	}
+	b, err := broker.New(cfg.Cluster.BrokerURL, instance)
+	if err != nil {
+		fatal("connecting to broker", err)
+	}
+	defer b.Close()
 
-	manager := &models.ChannelManager{Channels: make(map[string]*models.Channel)}
 	service := services.NewChannelService(manager)
-	h := handlers.NewHandler(service)
-	ws := handlers.NewWebSocketHandler(service)
+	service.UseBroker(b)
+	service.Configure(cfg)
+	service.StartLeaseRenewal(ctx, cfg.Cluster.LeaseTTL.Duration)
+	service.OnDebateConcluded(ratings.RecordResult)
+	service.OnDebateConcluded(func(*models.Archive) { metrics.DebatesCompleted.Inc() })
+	metrics.Registry.MustRegister(service.MetricsCollector())
+	service.StartJanitor(ctx, cfg.Limits.JanitorInterval.Duration, cfg.Limits.ChannelIdleTTL.Duration)
+	tournaments, err := services.NewTournamentService(service, ratings, st)
+	if err != nil {
+		fatal("loading tournaments", err)
+	}
+	service.OnDebateConcluded(tournaments.RecordResult)
+
+	// Before schedules, which re-create their channels only when no checkpoint brought them back
+	restored, err := service.Restore(st)
+	if err != nil {
+		fatal("restoring checkpoint", err)
+	}
+	if restored > 0 {
+		slog.Info("resumed channels from the shutdown checkpoint", "channels", restored)
+	}
+
+	matchmaking := services.NewMatchmakingService(service, ratings)
+	matchmaking.Start(ctx, cfg.Limits.MatchmakingInterval.Duration)
+	schedules, err := services.NewScheduleService(service, st)
+	if err != nil {
+		fatal("loading schedules", err)
+	}
+	schedules.Start(ctx, cfg.Limits.ScheduleInterval.Duration, cfg.Limits.ScheduleRetention.Duration)
+	audit, err := services.NewAuditService(st)
+	if err != nil {
+		fatal("loading audit log", err)
+	}
+	limits := handlers.NewRateLimits(cfg.Limits)
+	h := handlers.NewHandler(service, ratings, matchmaking, tournaments, schedules, limits)
+	ws := handlers.NewWebSocketHandler(service, limits)
+	lobby := handlers.NewLobbyHandler(matchmaking, limits)
+	replays := handlers.NewReplayHandler(service, limits)
+	api := handlers.NewAPIHandler(service, ratings, matchmaking, tournaments, schedules, limits)
+	health := handlers.NewHealthHandler(service, st)
+	admin := handlers.NewAdminHandler(service, audit)
 
 
 	// WebSocket route
@@ -31,9 +145,77 @@ func main() {
 	app.Post("/create-channel", h.CreateChannelPage)
 	app.Post("/watch-channel", h.WatchChannel)
 	app.Post("/join-channel", h.JoinChannel)
+	app.Post("/delete-channel", h.DeleteChannelPage)
+	app.Get("/archive/:channel", h.ArchivePage)
+	app.Get("/replay/:channel/:round", h.ReplayPage)
+	app.Get("/leaderboard", h.LeaderboardPage)
+	app.Post("/matchmaking", h.MatchmakingPage)
+	app.Get("/tournaments", h.TournamentsPage)
+	app.Post("/tournaments", h.CreateTournamentPage)
+	app.Get("/tournaments/:tournament", h.TournamentPage)
+	app.Post("/tournaments/:tournament/register", h.RegisterTournamentPage)
+	app.Post("/tournaments/:tournament/start", h.StartTournamentPage)
+	app.Post("/tournaments/:tournament/matches/:match/award", h.AwardMatchPage)
 	app.Post("/chat", h.ChatPage)
-	app.Get("/ws/:channel/:name/:password?", ws.WebSocketMiddleware, websocket.New(ws.HandleWebSocket))
+	maxFrame := cfg.Limits.MaxFrameBytes
+	app.Get("/ws/lobby/:ticket", ws.WebSocketMiddleware, websocket.New(handlers.LimitFrames(maxFrame, lobby.HandleLobby))) // Before the channel route, which would match it
+	app.Get("/ws/replay/:channel/:round", ws.WebSocketMiddleware, websocket.New(handlers.LimitFrames(maxFrame, replays.HandleReplay)))
+	app.Get("/ws/:channel/:name/:password?", ws.WebSocketMiddleware, websocket.New(handlers.LimitFrames(maxFrame, ws.HandleWebSocket)))
+
+	// Probes and Prometheus scraping
+	app.Get("/healthz", health.Healthz)
+	app.Get("/readyz", health.Readyz)
+	app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+
+	// JSON API
+	api.Register(app.Group("/api/v1"))
+
+	// Admin console, only served when a password is configured
+	if cfg.Admin.Enabled() {
+		admin.Register(app.Group("/admin", handlers.AdminAuth(cfg.Admin)))
+	} else {
+		slog.Info("ADMIN_PASSWORD not set, admin console disabled")
+	}
+
+	go func() {
+		var err error
+		if cfg.Server.TLSCert != "" {
+			slog.Info("🚀 Fiber WebSocket server running", "addr", cfg.Server.Addr, "tls", true)
+			err = app.ListenTLS(cfg.Server.Addr, cfg.Server.TLSCert, cfg.Server.TLSKey)
+		} else {
+			slog.Info("🚀 Fiber WebSocket server running", "addr", cfg.Server.Addr)
+			err = app.Listen(cfg.Server.Addr)
+		}
+		if err != nil {
+			fatal("listening", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	slog.Info("shutting down, checkpointing live debates")
+
+	// Closing the listener stops new connections at once; the wait is for requests in flight
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- app.ShutdownWithTimeout(cfg.Server.ShutdownTimeout.Duration)
+	}()
+	if err := service.Shutdown(st); err != nil {
+		slog.Error("checkpointing channels", "err", err)
+	}
+	if err := <-shutdown; err != nil {
+		slog.Error("shutting down", "err", err)
+	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("flushing traces", "err", err)
+	}
+	slog.Info("server stopped")
+}
 
-	log.Println("🚀 Fiber WebSocket server running on :3000")
-	log.Fatal(app.Listen(":3000"))
+// fatal logs err and exits, for failures that leave the server unable to start
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
@@ -0,0 +1,90 @@
+// Package actor runs commands one at a time on a goroutine of their own, so the state only those
+// commands touch needs no locking.
+package actor
+
+import "sync"
+
+// Actor owns a goroutine consuming commands in the order they were sent
+type Actor struct {
+	commands chan func()
+	quit     chan struct{} // Closed by Stop
+	done     chan struct{} // Closed when the goroutine has exited
+	stop     sync.Once
+}
+
+// New starts an actor that queues up to queue commands before senders block
+func New(queue int) *Actor {
+	a := &Actor{
+		commands: make(chan func(), queue),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *Actor) run() {
+	defer close(a.done)
+	for {
+		select {
+		case <-a.quit:
+			return
+		case fn := <-a.commands:
+			fn()
+		}
+	}
+}
+
+// Post queues fn without waiting for it, reporting false if the actor has stopped. Commands must not
+// post to their own actor while its queue may be full.
+func (a *Actor) Post(fn func()) bool {
+	select {
+	case <-a.quit:
+		return false
+	default:
+	}
+	select {
+	case a.commands <- fn:
+		return true
+	case <-a.quit:
+		return false
+	}
+}
+
+// Do runs fn on the actor and waits for it, reporting false if the actor stopped without running it.
+// Calling Do from a command of the same actor deadlocks; commands call each other directly.
+func (a *Actor) Do(fn func()) bool {
+	finished := make(chan struct{})
+	if !a.Post(func() {
+		defer close(finished)
+		fn()
+	}) {
+		return false
+	}
+	select {
+	case <-finished:
+		return true
+	case <-a.done:
+		select {
+		case <-finished:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// Stop ends the actor once the running command returns; queued commands are dropped
+func (a *Actor) Stop() {
+	a.stop.Do(func() { close(a.quit) })
+}
+
+// Pending is how many commands are queued behind the running one
+func (a *Actor) Pending() int {
+	return len(a.commands)
+}
+
+// Done is closed once the actor's goroutine has exited
+func (a *Actor) Done() <-chan struct{} {
+	return a.done
+}
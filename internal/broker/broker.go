@@ -0,0 +1,55 @@
+// Package broker carries channel traffic between server instances sharing one deployment and
+// decides, through expiring leases, which instance owns each channel.
+package broker
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Broker publishes payloads on named topics and grants leases on keys. Payloads published on a
+// topic reach every subscriber of that topic, on every instance, including the publisher.
+type Broker interface {
+	// Instance identifies this server among those sharing the broker
+	Instance() string
+
+	Publish(ctx context.Context, topic string, payload []byte) error
+
+	// Subscribe calls handler, one payload at a time, for everything published on topic until the
+	// returned cancel function is called
+	Subscribe(ctx context.Context, topic string, handler func(payload []byte)) (cancel func(), err error)
+
+	// Acquire takes the lease on key for ttl, or extends it when this instance already holds it;
+	// it reports false when another instance holds it
+	Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Release gives up the lease on key if this instance holds it
+	Release(ctx context.Context, key string) error
+
+	// Owner returns the instance holding the lease on key, or "" when nobody does
+	Owner(ctx context.Context, key string) (string, error)
+
+	Close() error
+}
+
+// New opens the broker at rawURL: the in-process broker when it is empty or "memory://", or a
+// Redis server for redis:// and rediss:// URLs
+func New(rawURL, instance string) (Broker, error) {
+	if rawURL == "" {
+		return NewMemory(instance), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse broker url: %w", err)
+	}
+	switch u.Scheme {
+	case "memory":
+		return NewMemory(instance), nil
+	case "redis", "rediss":
+		return NewRedis(rawURL, instance)
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,244 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The same tests run against every broker; two brokers from one connect function act as two instances
+// sharing a deployment
+type connect func(t *testing.T, instance string) Broker
+
+func memoryBrokers() connect {
+	hub := NewMemory("")
+	return func(t *testing.T, instance string) Broker {
+		return hub.Join(instance)
+	}
+}
+
+// redisBrokers connects to REDIS_TEST_URL, or a local server, and skips the test when none answers
+func redisBrokers(t *testing.T) connect {
+	url := os.Getenv("REDIS_TEST_URL")
+	if url == "" {
+		url = "redis://localhost:6379/15"
+	}
+	probe, err := NewRedis(url, "probe")
+	if err != nil {
+		t.Skipf("no redis server reachable at %s: %v", url, err)
+	}
+	probe.Close()
+	return func(t *testing.T, instance string) Broker {
+		b, err := NewRedis(url, instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { b.Close() })
+		return b
+	}
+}
+
+func eachBroker(t *testing.T, test func(t *testing.T, connect connect)) {
+	t.Run("memory", func(t *testing.T) { test(t, memoryBrokers()) })
+	t.Run("redis", func(t *testing.T) { test(t, redisBrokers(t)) })
+}
+
+// uniqueName keeps runs against a shared Redis server apart
+func uniqueName(prefix string) string {
+	return prefix + "-" + uuid.NewString()
+}
+
+// collector gathers the payloads a subscriber receives
+type collector struct {
+	mu       sync.Mutex
+	payloads []string
+	arrived  chan struct{}
+}
+
+func newCollector() *collector {
+	return &collector{arrived: make(chan struct{}, 1024)}
+}
+
+func (c *collector) handle(payload []byte) {
+	c.mu.Lock()
+	c.payloads = append(c.payloads, string(payload))
+	c.mu.Unlock()
+	c.arrived <- struct{}{}
+}
+
+// wait returns the payloads once n have arrived, failing the test if they do not within a few seconds
+func (c *collector) wait(t *testing.T, n int) []string {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < n; i++ {
+		select {
+		case <-c.arrived:
+		case <-timeout:
+			t.Fatalf("received %d payloads, want %d", i, n)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.payloads...)
+}
+
+// none fails the test if anything arrives within a short while
+func (c *collector) none(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.arrived:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		t.Errorf("unexpected payloads %q", c.payloads)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPublishFansOut(t *testing.T) {
+	eachBroker(t, func(t *testing.T, connect connect) {
+		ctx := context.Background()
+		a, b := connect(t, "a"), connect(t, "b")
+		topic, other := uniqueName("topic"), uniqueName("other")
+
+		onA, onB, elsewhere := newCollector(), newCollector(), newCollector()
+		for _, sub := range []struct {
+			broker Broker
+			topic  string
+			c      *collector
+		}{{a, topic, onA}, {b, topic, onB}, {b, other, elsewhere}} {
+			cancel, err := sub.broker.Subscribe(ctx, sub.topic, sub.c.handle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cancel()
+		}
+
+		if err := a.Publish(ctx, topic, []byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		// The publisher's own subscribers receive it too, as the broker's contract says
+		if got := onA.wait(t, 1); got[0] != "hello" {
+			t.Errorf("publisher's subscriber got %q", got)
+		}
+		if got := onB.wait(t, 1); got[0] != "hello" {
+			t.Errorf("other instance's subscriber got %q", got)
+		}
+		elsewhere.none(t)
+	})
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	eachBroker(t, func(t *testing.T, connect connect) {
+		ctx := context.Background()
+		a := connect(t, "a")
+		topic := uniqueName("topic")
+
+		c := newCollector()
+		cancel, err := a.Subscribe(ctx, topic, c.handle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		cancel() // Cancelling twice is harmless
+
+		if err := a.Publish(ctx, topic, []byte("too late")); err != nil {
+			t.Fatal(err)
+		}
+		c.none(t)
+	})
+}
+
+// TestPublishOrder checks that one publisher's payloads reach a subscriber in the order they were
+// published, which relayed sockets rely on for frames and input alike
+func TestPublishOrder(t *testing.T) {
+	const n = 200
+	eachBroker(t, func(t *testing.T, connect connect) {
+		ctx := context.Background()
+		owner, relay := connect(t, "owner"), connect(t, "relay")
+		topic := uniqueName("relay")
+
+		c := newCollector()
+		cancel, err := relay.Subscribe(ctx, topic, c.handle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cancel()
+
+		for i := 0; i < n; i++ {
+			if err := owner.Publish(ctx, topic, []byte(strconv.Itoa(i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i, payload := range c.wait(t, n) {
+			if payload != strconv.Itoa(i) {
+				t.Fatalf("payload %d = %s, want payloads in the order published", i, payload)
+			}
+		}
+	})
+}
+
+func TestLease(t *testing.T) {
+	const ttl = 300 * time.Millisecond
+	eachBroker(t, func(t *testing.T, connect connect) {
+		ctx := context.Background()
+		a, b := connect(t, "a"), connect(t, "b")
+		key := uniqueName("lease")
+
+		acquire := func(br Broker, want bool, when string) {
+			t.Helper()
+			held, err := br.Acquire(ctx, key, ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if held != want {
+				t.Fatalf("%s: %s acquiring = %v, want %v", when, br.Instance(), held, want)
+			}
+		}
+		owner := func(want, when string) {
+			t.Helper()
+			got, err := a.Owner(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Fatalf("%s: owner = %q, want %q", when, got, want)
+			}
+		}
+
+		owner("", "before anyone acquires it")
+		acquire(a, true, "first acquire")
+		acquire(b, false, "while held")
+		owner("a", "while held")
+
+		// Renewing keeps the lease past the first ttl
+		for i := 0; i < 3; i++ {
+			time.Sleep(ttl / 2)
+			acquire(a, true, fmt.Sprintf("renewal %d", i+1))
+		}
+		acquire(b, false, "after renewals")
+
+		// Without renewals it expires and another instance may take it
+		time.Sleep(ttl + ttl/2)
+		owner("", "after expiry")
+		acquire(b, true, "after expiry")
+		acquire(a, false, "after being taken")
+
+		// Only the holder can release it
+		if err := a.Release(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+		owner("b", "after a release by another instance")
+		if err := b.Release(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+		owner("", "after release")
+		acquire(a, true, "after release")
+		a.Release(ctx, key)
+	})
+}
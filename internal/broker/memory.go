@@ -0,0 +1,146 @@
+package broker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is how many payloads a slow subscriber may fall behind before new ones are dropped,
+// mirroring how Redis drops messages for clients that stop reading
+const subscriberBuffer = 256
+
+// Memory is the in-process broker used when the server runs as a single instance. Brokers created
+// with Join share topics and leases, which stands in for several instances within one process.
+type Memory struct {
+	hub      *memoryHub
+	instance string
+}
+
+type memoryHub struct {
+	mu     sync.Mutex
+	topics map[string]map[*memorySubscription]bool
+	leases map[string]memoryLease
+}
+
+type memorySubscription struct {
+	queue chan []byte
+	done  chan struct{}
+}
+
+type memoryLease struct {
+	owner   string
+	expires time.Time
+}
+
+// NewMemory creates an in-process broker
+func NewMemory(instance string) *Memory {
+	return &Memory{
+		hub: &memoryHub{
+			topics: make(map[string]map[*memorySubscription]bool),
+			leases: make(map[string]memoryLease),
+		},
+		instance: instance,
+	}
+}
+
+// Join returns a broker for another instance sharing this one's topics and leases
+func (m *Memory) Join(instance string) *Memory {
+	return &Memory{hub: m.hub, instance: instance}
+}
+
+func (m *Memory) Instance() string {
+	return m.instance
+}
+
+func (m *Memory) Publish(ctx context.Context, topic string, payload []byte) error {
+	m.hub.mu.Lock()
+	subs := make([]*memorySubscription, 0, len(m.hub.topics[topic]))
+	for sub := range m.hub.topics[topic] {
+		subs = append(subs, sub)
+	}
+	m.hub.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.queue <- payload:
+		case <-sub.done:
+		default:
+			slog.Warn("broker subscriber falling behind, payload dropped", "topic", topic)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, topic string, handler func(payload []byte)) (func(), error) {
+	sub := &memorySubscription{
+		queue: make(chan []byte, subscriberBuffer),
+		done:  make(chan struct{}),
+	}
+	m.hub.mu.Lock()
+	if m.hub.topics[topic] == nil {
+		m.hub.topics[topic] = make(map[*memorySubscription]bool)
+	}
+	m.hub.topics[topic][sub] = true
+	m.hub.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-sub.done:
+				return
+			case payload := <-sub.queue:
+				handler(payload)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.hub.mu.Lock()
+			delete(m.hub.topics[topic], sub)
+			if len(m.hub.topics[topic]) == 0 {
+				delete(m.hub.topics, topic)
+			}
+			m.hub.mu.Unlock()
+			close(sub.done)
+		})
+	}, nil
+}
+
+func (m *Memory) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+
+	now := time.Now()
+	if lease, ok := m.hub.leases[key]; ok && lease.owner != m.instance && now.Before(lease.expires) {
+		return false, nil
+	}
+	m.hub.leases[key] = memoryLease{owner: m.instance, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *Memory) Release(ctx context.Context, key string) error {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+	if m.hub.leases[key].owner == m.instance {
+		delete(m.hub.leases, key)
+	}
+	return nil
+}
+
+func (m *Memory) Owner(ctx context.Context, key string) (string, error) {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+	lease, ok := m.hub.leases[key]
+	if !ok || !time.Now().Before(lease.expires) {
+		return "", nil
+	}
+	return lease.owner, nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,114 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPrefix namespaces the broker's pub/sub channels and lease keys
+const redisPrefix = "debate:"
+
+// Leases are changed with scripts so that checking the holder and changing the key happen atomically
+var (
+	acquireScript = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner == false then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+if owner == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+)
+
+// Redis shares topics and leases between instances through a Redis server, using PUBLISH and
+// SUBSCRIBE for topics and expiring keys for leases
+type Redis struct {
+	client   *redis.Client
+	instance string
+}
+
+// NewRedis connects to the Redis server at rawURL, such as redis://localhost:6379/0
+func NewRedis(rawURL, instance string) (*Redis, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+	return &Redis{client: client, instance: instance}, nil
+}
+
+func (r *Redis) Instance() string {
+	return r.instance
+}
+
+func (r *Redis) Publish(ctx context.Context, topic string, payload []byte) error {
+	return r.client.Publish(ctx, redisPrefix+topic, payload).Err()
+}
+
+func (r *Redis) Subscribe(ctx context.Context, topic string, handler func(payload []byte)) (func(), error) {
+	ps := r.client.Subscribe(ctx, redisPrefix+topic)
+	// Wait for the confirmation so nothing published after Subscribe returns is missed
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, fmt.Errorf("subscribe %s: %w", topic, err)
+	}
+
+	messages := ps.Channel(redis.WithChannelSize(subscriberBuffer))
+	go func() {
+		for msg := range messages {
+			handler([]byte(msg.Payload))
+		}
+	}()
+	return func() { ps.Close() }, nil
+}
+
+func (r *Redis) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	held, err := acquireScript.Run(ctx, r.client, []string{redisPrefix + "lease:" + key}, r.instance, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("acquire lease %s: %w", key, err)
+	}
+	return held == 1, nil
+}
+
+func (r *Redis) Release(ctx context.Context, key string) error {
+	if err := releaseScript.Run(ctx, r.client, []string{redisPrefix + "lease:" + key}, r.instance).Err(); err != nil {
+		return fmt.Errorf("release lease %s: %w", key, err)
+	}
+	return nil
+}
+
+func (r *Redis) Owner(ctx context.Context, key string) (string, error) {
+	owner, err := r.client.Get(ctx, redisPrefix+"lease:"+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read lease %s: %w", key, err)
+	}
+	return owner, nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,574 @@
+// Package config loads the server's settings from built-in defaults, an optional JSON file, the
+// environment and command-line flags, each layer overriding the ones before it.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// Config is the effective configuration of one server instance
+type Config struct {
+	Server  Server  `json:"server"`
+	AI      AI      `json:"ai"`
+	Debate  Debate  `json:"debate"`
+	Limits  Limits  `json:"limits"`
+	Cluster Cluster `json:"cluster"`
+	Logging Logging `json:"logging"`
+	Tracing Tracing `json:"tracing"`
+	Admin   Admin   `json:"admin"`
+	DataDir string  `json:"dataDir"` // Empty keeps everything in memory
+}
+
+type Server struct {
+	Addr            string   `json:"addr"`
+	TemplateDir     string   `json:"templateDir"`
+	PublicURL       string   `json:"publicUrl"` // e.g. https://debate.example.com; empty uses the host of each request
+	TLSCert         string   `json:"tlsCert"`
+	TLSKey          string   `json:"tlsKey"`
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+
+	// Header a reverse proxy passes the client address in, such as X-Real-IP; it is only believed on
+	// requests coming from TrustedProxies, addresses or CIDR ranges. Empty uses the socket's address.
+	ProxyHeader    string   `json:"proxyHeader"`
+	TrustedProxies []string `json:"trustedProxies"`
+}
+
+type AI struct {
+	Endpoint  string   `json:"endpoint"` // OpenAI-compatible chat completions URL
+	APIKey    string   `json:"apiKey"`
+	Model     string   `json:"model"`
+	MaxTokens int      `json:"maxTokens"`
+	Timeout   Duration `json:"timeout"`
+}
+
+type Debate struct {
+	PhaseDurations []Duration `json:"phaseDurations"` // Speaking time of phases 1 to 5
+	DefaultFormat  string     `json:"defaultFormat"`  // Key of models.MatchFormats used when no series length is given
+}
+
+type Limits struct {
+	MaxBestOf           int      `json:"maxBestOf"`
+	ChannelIdleTTL      Duration `json:"channelIdleTtl"`
+	JanitorInterval     Duration `json:"janitorInterval"`
+	ArchiveRetention    Duration `json:"archiveRetention"`   // How long concluded rounds are kept for the archive, replays and transcripts
+	MaxChannelArchives  int      `json:"maxChannelArchives"` // Concluded rounds kept per channel, the oldest dropped first
+	MatchmakingInterval Duration `json:"matchmakingInterval"`
+	ScheduleInterval    Duration `json:"scheduleInterval"`
+	ScheduleRetention   Duration `json:"scheduleRetention"` // How long started, forfeited and cancelled schedules stay listed
+
+	// Requests each IP address and each browser session may make, checked separately
+	ChannelCreateRate Rate `json:"channelCreateRate"` // Also queueing for a match and starting a tournament, which open channels
+	JoinRate          Rate `json:"joinRate"`          // Joining or watching a channel from the pages
+	ConnectRate       Rate `json:"connectRate"`       // WebSocket connections to channels, lobbies and replays
+	MessageRate       Rate `json:"messageRate"`       // Frames sent on channel sockets
+
+	MaxFrameBytes     int `json:"maxFrameBytes"`     // Largest WebSocket frame read; larger ones close the socket
+	MaxChannelClients int `json:"maxChannelClients"` // Sockets connected to one channel, debaters and spectators together
+}
+
+type Cluster struct {
+	BrokerURL    string   `json:"brokerUrl"`  // Empty runs a single instance
+	InstanceID   string   `json:"instanceId"` // Empty uses the hostname plus a random suffix
+	LeaseTTL     Duration `json:"leaseTtl"`
+	AdvertiseURL string   `json:"advertiseUrl"` // Where API requests for channels owned here are redirected; empty answers them with 409
+}
+
+type Logging struct {
+	Level   string `json:"level"`   // debug, info, warn or error
+	Format  string `json:"format"`  // text or json
+	Content bool   `json:"content"` // Log chat message bodies and passwords instead of redacting them
+}
+
+type Tracing struct {
+	Enabled     bool    `json:"enabled"`
+	Endpoint    string  `json:"endpoint"`    // host:port of an OTLP/HTTP collector
+	Insecure    bool    `json:"insecure"`    // Plain HTTP, for a collector on the same host or network
+	SampleRatio float64 `json:"sampleRatio"` // Share of new traces recorded, 0 to 1
+	ServiceName string  `json:"serviceName"`
+}
+
+type Admin struct {
+	User     string `json:"user"`
+	Password string `json:"password"` // Empty disables the admin console
+}
+
+// Enabled reports whether the admin console is served
+func (a Admin) Enabled() bool {
+	return a.Password != ""
+}
+
+// Rate allows Count requests at once and Count per Per after that, written as "10/1m" in config files;
+// a zero count means no limit
+type Rate struct {
+	Count int
+	Per   time.Duration
+}
+
+// Enabled reports whether the rate limits anything
+func (r Rate) Enabled() bool {
+	return r.Count > 0
+}
+
+func (r Rate) String() string {
+	if !r.Enabled() {
+		return "0"
+	}
+	per := r.Per.String()
+	// "1m0s" and "1h0m0s" read better as "1m" and "1h"
+	if strings.HasSuffix(per, "m0s") {
+		per = strings.TrimSuffix(per, "0s")
+	}
+	if strings.HasSuffix(per, "h0m") {
+		per = strings.TrimSuffix(per, "0m")
+	}
+	return strconv.Itoa(r.Count) + "/" + per
+}
+
+// ParseRate reads a rate such as "10/1m" or "5/s"; "0" means no limit
+func ParseRate(value string) (Rate, error) {
+	if value == "0" {
+		return Rate{}, nil
+	}
+	count, per, ok := strings.Cut(value, "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n < 0 {
+		return Rate{}, fmt.Errorf("rate must look like \"10/1m\", got %q", value)
+	}
+	if per != "" && (per[0] < '0' || per[0] > '9') {
+		per = "1" + per
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Rate{}, fmt.Errorf("rate must look like \"10/1m\", got %q", value)
+	}
+	return Rate{Count: n, Per: d}, nil
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("rate must be a string such as \"10/1m\": %w", err)
+	}
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Duration is a time.Duration written as "90s" or "3m" in config files
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"90s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// Default returns the settings used when nothing overrides them
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:            ":3000",
+			TemplateDir:     "./static",
+			ShutdownTimeout: Duration{10 * time.Second},
+			TrustedProxies:  []string{},
+		},
+		AI: AI{
+			Endpoint:  "https://openrouter.ai/api/v1/chat/completions",
+			Model:     "deepseek/deepseek-chat-v3.1:free",
+			MaxTokens: 8192,
+			Timeout:   Duration{2 * time.Minute},
+		},
+		Debate: Debate{
+			PhaseDurations: []Duration{{3 * time.Minute}, {2 * time.Minute}, {2 * time.Minute}, {2 * time.Minute}, {3 * time.Minute}},
+			DefaultFormat:  models.FormatSingle,
+		},
+		Limits: Limits{
+			MaxBestOf:           9,
+			ChannelIdleTTL:      Duration{30 * time.Minute},
+			JanitorInterval:     Duration{time.Minute},
+			ArchiveRetention:    Duration{7 * 24 * time.Hour},
+			MaxChannelArchives:  50,
+			MatchmakingInterval: Duration{2 * time.Second},
+			ScheduleInterval:    Duration{time.Second},
+			ScheduleRetention:   Duration{7 * 24 * time.Hour},
+			ChannelCreateRate:   Rate{Count: 10, Per: time.Minute},
+			JoinRate:            Rate{Count: 30, Per: time.Minute},
+			ConnectRate:         Rate{Count: 30, Per: time.Minute},
+			MessageRate:         Rate{Count: 5, Per: time.Second},
+			MaxFrameBytes:       64 << 10,
+			MaxChannelClients:   200,
+		},
+		Cluster: Cluster{
+			LeaseTTL: Duration{15 * time.Second},
+		},
+		Logging: Logging{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: Tracing{
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+			ServiceName: "go-websocket-chat",
+		},
+		Admin: Admin{
+			User: "admin",
+		},
+	}
+}
+
+// setting is one value that can be given by environment variable and flag
+type setting struct {
+	env, flag, usage string
+	apply            func(*Config, string) error
+}
+
+var settings = []setting{
+	{"LISTEN_ADDR", "addr", "listen address", text(func(c *Config) *string { return &c.Server.Addr })},
+	{"TEMPLATE_DIR", "templates", "directory of the HTML templates", text(func(c *Config) *string { return &c.Server.TemplateDir })},
+	{"PUBLIC_URL", "public-url", "base URL clients reach the server at, used to build WebSocket URLs", text(func(c *Config) *string { return &c.Server.PublicURL })},
+	{"TLS_CERT", "tls-cert", "TLS certificate file; serves HTTPS together with -tls-key", text(func(c *Config) *string { return &c.Server.TLSCert })},
+	{"TLS_KEY", "tls-key", "TLS private key file", text(func(c *Config) *string { return &c.Server.TLSKey })},
+	{"PROXY_HEADER", "proxy-header", "header a reverse proxy passes the client address in, e.g. X-Real-IP; empty uses the socket's address", text(func(c *Config) *string { return &c.Server.ProxyHeader })},
+	{"TRUSTED_PROXIES", "trusted-proxies", "comma-separated addresses or CIDR ranges of the proxies whose proxy header is believed", list(func(c *Config) *[]string { return &c.Server.TrustedProxies })},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "how long shutdown waits for requests in flight", duration(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+	{"AI_ENDPOINT", "ai-endpoint", "OpenAI-compatible chat completions URL", text(func(c *Config) *string { return &c.AI.Endpoint })},
+	{"OPENROUTER_API_KEY", "ai-api-key", "API key sent to the AI endpoint", text(func(c *Config) *string { return &c.AI.APIKey })},
+	{"AI_MODEL", "ai-model", "model used for moderation and judging", text(func(c *Config) *string { return &c.AI.Model })},
+	{"AI_MAX_TOKENS", "ai-max-tokens", "maximum tokens per AI response", integer(func(c *Config) *int { return &c.AI.MaxTokens })},
+	{"AI_TIMEOUT", "ai-timeout", "how long to wait for an AI response", duration(func(c *Config) *Duration { return &c.AI.Timeout })},
+	{"PHASE_DURATIONS", "phase-durations", "comma-separated speaking time of phases 1 to 5, e.g. 3m,2m,2m,2m,3m", durations(func(c *Config) *[]Duration { return &c.Debate.PhaseDurations })},
+	{"DEFAULT_FORMAT", "default-format", "series format of channels created without one (single, bo3)", text(func(c *Config) *string { return &c.Debate.DefaultFormat })},
+	{"MAX_BEST_OF", "max-best-of", "longest series a channel may be created with", integer(func(c *Config) *int { return &c.Limits.MaxBestOf })},
+	{"CHANNEL_IDLE_TTL", "channel-idle-ttl", "how long a channel nobody is connected to lives without activity", duration(func(c *Config) *Duration { return &c.Limits.ChannelIdleTTL })},
+	{"JANITOR_INTERVAL", "janitor-interval", "how often idle channels are looked for", duration(func(c *Config) *Duration { return &c.Limits.JanitorInterval })},
+	{"ARCHIVE_RETENTION", "archive-retention", "how long concluded rounds are kept for the archive, replays and transcripts", duration(func(c *Config) *Duration { return &c.Limits.ArchiveRetention })},
+	{"MAX_CHANNEL_ARCHIVES", "max-channel-archives", "most concluded rounds kept per channel; the oldest are dropped first", integer(func(c *Config) *int { return &c.Limits.MaxChannelArchives })},
+	{"MATCHMAKING_INTERVAL", "matchmaking-interval", "how often queued players are paired", duration(func(c *Config) *Duration { return &c.Limits.MatchmakingInterval })},
+	{"SCHEDULE_INTERVAL", "schedule-interval", "how often scheduled debates are checked", duration(func(c *Config) *Duration { return &c.Limits.ScheduleInterval })},
+	{"SCHEDULE_RETENTION", "schedule-retention", "how long started, forfeited and cancelled schedules stay listed", duration(func(c *Config) *Duration { return &c.Limits.ScheduleRetention })},
+	{"RATE_CHANNEL_CREATE", "rate-channel-create", "channels each IP and session may create, counting match queueing and tournament starts, e.g. 10/1m; 0 for no limit", rate(func(c *Config) *Rate { return &c.Limits.ChannelCreateRate })},
+	{"RATE_JOIN", "rate-join", "channel joins each IP and session may make, e.g. 30/1m; 0 for no limit", rate(func(c *Config) *Rate { return &c.Limits.JoinRate })},
+	{"RATE_CONNECT", "rate-connect", "channel, lobby and replay WebSocket connections each IP and session may open, e.g. 30/1m; 0 for no limit", rate(func(c *Config) *Rate { return &c.Limits.ConnectRate })},
+	{"RATE_MESSAGES", "rate-messages", "frames each IP and session may send on channel sockets, e.g. 5/1s; 0 for no limit", rate(func(c *Config) *Rate { return &c.Limits.MessageRate })},
+	{"MAX_FRAME_BYTES", "max-frame-bytes", "largest WebSocket frame accepted, in bytes", integer(func(c *Config) *int { return &c.Limits.MaxFrameBytes })},
+	{"MAX_CHANNEL_CLIENTS", "max-channel-clients", "most sockets connected to one channel", integer(func(c *Config) *int { return &c.Limits.MaxChannelClients })},
+	{"BROKER_URL", "broker-url", "pub/sub broker shared by all instances, e.g. redis://localhost:6379/0", text(func(c *Config) *string { return &c.Cluster.BrokerURL })},
+	{"INSTANCE_ID", "instance-id", "name of this instance on the broker", text(func(c *Config) *string { return &c.Cluster.InstanceID })},
+	{"CHANNEL_LEASE_TTL", "channel-lease-ttl", "how long an instance keeps a channel after it stops renewing its lease", duration(func(c *Config) *Duration { return &c.Cluster.LeaseTTL })},
+	{"ADVERTISE_URL", "advertise-url", "base URL this instance is reachable at directly; other instances redirect API requests for its channels there", text(func(c *Config) *string { return &c.Cluster.AdvertiseURL })},
+	{"LOG_LEVEL", "log-level", "lowest level logged: debug, info, warn or error", text(func(c *Config) *string { return &c.Logging.Level })},
+	{"LOG_FORMAT", "log-format", "log output format: text or json", text(func(c *Config) *string { return &c.Logging.Format })},
+	{"LOG_CONTENT", "log-content", "log chat message bodies and passwords instead of redacting them (true or false)", boolean(func(c *Config) *bool { return &c.Logging.Content })},
+	{"TRACING_ENABLED", "tracing", "export OpenTelemetry traces (true or false)", boolean(func(c *Config) *bool { return &c.Tracing.Enabled })},
+	{"TRACING_ENDPOINT", "tracing-endpoint", "host:port of the OTLP/HTTP collector", text(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{"TRACING_INSECURE", "tracing-insecure", "send traces over plain HTTP (true or false)", boolean(func(c *Config) *bool { return &c.Tracing.Insecure })},
+	{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "share of new traces recorded, 0 to 1", number(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+	{"TRACING_SERVICE_NAME", "tracing-service-name", "service name traces are reported under", text(func(c *Config) *string { return &c.Tracing.ServiceName })},
+	{"ADMIN_USER", "admin-user", "user name for the admin console", text(func(c *Config) *string { return &c.Admin.User })},
+	{"ADMIN_PASSWORD", "admin-password", "password for the admin console; empty disables it", text(func(c *Config) *string { return &c.Admin.Password })},
+	{"DATA_DIR", "data-dir", "directory for saved data; empty keeps everything in memory", text(func(c *Config) *string { return &c.DataDir })},
+}
+
+func text(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func integer(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("not a whole number: %q", value)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func number(field func(*Config) *float64) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("not a number: %q", value)
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+func boolean(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("not true or false: %q", value)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func duration(field func(*Config) *Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = Duration{d}
+		return nil
+	}
+}
+
+func rate(field func(*Config) *Rate) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		r, err := ParseRate(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = r
+		return nil
+	}
+}
+
+func list(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var items []string
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				items = append(items, part)
+			}
+		}
+		*field(c) = items
+		return nil
+	}
+}
+
+func durations(field func(*Config) *[]Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var list []Duration
+		for _, part := range strings.Split(value, ",") {
+			d, err := time.ParseDuration(strings.TrimSpace(part))
+			if err != nil {
+				return err
+			}
+			list = append(list, Duration{d})
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+// Load builds the configuration from the defaults, the JSON file named by -config or CONFIG_FILE, the
+// environment and args, in that order. printOnly is set by -print-config.
+func Load(args []string) (cfg Config, printOnly bool, err error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "JSON config file")
+	fs.BoolVar(&printOnly, "print-config", false, "print the effective configuration and exit")
+	values := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		values[s.flag] = fs.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, false, err
+	}
+
+	cfg = Default()
+	if *file != "" {
+		if err := cfg.loadFile(*file); err != nil {
+			return Config{}, false, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			if err := s.apply(&cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name {
+				if err := s.apply(&cfg, *values[s.flag]); err != nil {
+					errs = append(errs, fmt.Errorf("-%s: %w", s.flag, err))
+				}
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return Config{}, false, errors.Join(errs...)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, false, err
+	}
+	return cfg, printOnly, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields() // A misspelt key would otherwise be silently ignored
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("reading config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr must not be empty")
+	check(c.Server.TemplateDir != "", "server.templateDir must not be empty")
+	check((c.Server.TLSCert == "") == (c.Server.TLSKey == ""), "server.tlsCert and server.tlsKey must be set together")
+	if c.Server.PublicURL != "" {
+		u, err := url.Parse(c.Server.PublicURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"server.publicUrl must be an absolute http or https URL, got %q", c.Server.PublicURL)
+	}
+	check(c.Server.ShutdownTimeout.Duration > 0, "server.shutdownTimeout must be positive")
+	check(c.Server.ProxyHeader == "" || len(c.Server.TrustedProxies) > 0, "server.trustedProxies must list the proxies setting server.proxyHeader")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(net.ParseIP(proxy) != nil || err == nil, "server.trustedProxies entry %q is not an IP address or CIDR range", proxy)
+	}
+
+	u, err := url.Parse(c.AI.Endpoint)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+		"ai.endpoint must be an absolute http or https URL, got %q", c.AI.Endpoint)
+	check(c.AI.Model != "", "ai.model must not be empty")
+	check(c.AI.MaxTokens > 0, "ai.maxTokens must be positive")
+	check(c.AI.Timeout.Duration > 0, "ai.timeout must be positive")
+
+	check(len(c.Debate.PhaseDurations) == 5, "debate.phaseDurations must list the 5 phases, got %d", len(c.Debate.PhaseDurations))
+	for i, d := range c.Debate.PhaseDurations {
+		check(d.Duration >= time.Second, "debate.phaseDurations[%d] must be at least 1s", i)
+	}
+	bestOf, ok := models.MatchFormats[c.Debate.DefaultFormat]
+	check(ok, "debate.defaultFormat %q is not a known format", c.Debate.DefaultFormat)
+
+	check(c.Limits.MaxBestOf >= 1, "limits.maxBestOf must be at least 1")
+	check(!ok || bestOf <= c.Limits.MaxBestOf, "debate.defaultFormat %q is longer than limits.maxBestOf", c.Debate.DefaultFormat)
+	check(c.Limits.ChannelIdleTTL.Duration > 0, "limits.channelIdleTtl must be positive")
+	check(c.Limits.JanitorInterval.Duration > 0, "limits.janitorInterval must be positive")
+	check(c.Limits.ArchiveRetention.Duration > 0, "limits.archiveRetention must be positive")
+	check(c.Limits.MaxChannelArchives >= 1, "limits.maxChannelArchives must be at least 1")
+	check(c.Limits.MatchmakingInterval.Duration > 0, "limits.matchmakingInterval must be positive")
+	check(c.Limits.ScheduleInterval.Duration > 0, "limits.scheduleInterval must be positive")
+	check(c.Limits.ScheduleRetention.Duration > 0, "limits.scheduleRetention must be positive")
+	check(c.Limits.MaxFrameBytes >= 1024, "limits.maxFrameBytes must be at least 1024")
+	check(c.Limits.MaxChannelClients >= 2, "limits.maxChannelClients must be at least 2")
+
+	check(c.Cluster.LeaseTTL.Duration > 0, "cluster.leaseTtl must be positive")
+	if c.Cluster.AdvertiseURL != "" {
+		u, err := url.Parse(c.Cluster.AdvertiseURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"cluster.advertiseUrl must be an absolute http or https URL, got %q", c.Cluster.AdvertiseURL)
+	}
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Logging.Level)) == nil, "logging.level must be debug, info, warn or error, got %q", c.Logging.Level)
+	check(c.Logging.Format == "text" || c.Logging.Format == "json", "logging.format must be text or json, got %q", c.Logging.Format)
+
+	check(!c.Tracing.Enabled || c.Tracing.Endpoint != "", "tracing.endpoint must be set when tracing is enabled")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio must be between 0 and 1")
+	check(c.Tracing.ServiceName != "", "tracing.serviceName must not be empty")
+	check(!c.Admin.Enabled() || c.Admin.User != "", "admin.user must not be empty when an admin password is set")
+
+	return errors.Join(errs...)
+}
+
+// String renders the configuration as indented JSON with secrets masked
+func (c Config) String() string {
+	var out strings.Builder
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(c.redacted())
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// LogValue logs the configuration as one line of JSON with secrets masked
+func (c Config) LogValue() slog.Value {
+	out, _ := json.Marshal(c.redacted())
+	return slog.StringValue(string(out))
+}
+
+func (c Config) redacted() Config {
+	if c.AI.APIKey != "" {
+		c.AI.APIKey = "<redacted>"
+	}
+	if c.Admin.Password != "" {
+		c.Admin.Password = "<redacted>"
+	}
+	if u, err := url.Parse(c.Cluster.BrokerURL); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "redacted")
+			c.Cluster.BrokerURL = u.String()
+		}
+	}
+	return c
+}
+
+// WebSocketBase is the ws:// or wss:// form of the public URL, without a trailing slash, or empty
+// when no public URL is configured
+func (s Server) WebSocketBase() string {
+	if s.PublicURL == "" {
+		return ""
+	}
+	u, err := url.Parse(s.PublicURL)
+	if err != nil {
+		return ""
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	return strings.TrimSuffix(u.String(), "/")
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/basicauth"
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/config"
+	"github.com/latestcomment/go-websocket-chat/internal/logging"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/services"
+)
+
+const (
+	// adminUserKey is the local the authenticated admin's user name is kept under
+	adminUserKey = "adminUser"
+
+	maxAnnouncementLength = 500
+	adminAuditPageLimit   = 50
+)
+
+// AdminHandler serves the admin console: an HTML view under /admin and a JSON API under /admin/api.
+// Every action goes through a ChannelService operation and is recorded in the audit log.
+type AdminHandler struct {
+	Service *services.ChannelService
+	Audit   *services.AuditService
+}
+
+func NewAdminHandler(service *services.ChannelService, audit *services.AuditService) *AdminHandler {
+	return &AdminHandler{Service: service, Audit: audit}
+}
+
+type AnnounceRequest struct {
+	Text string `json:"text"`
+}
+
+type AnnounceResponse struct {
+	Channels int `json:"channels"` // How many channels received the announcement
+}
+
+type AdminChannelsResponse struct {
+	Channels []models.AdminChannel `json:"channels"`
+}
+
+type AuditResponse struct {
+	Entries []models.AuditEntry `json:"entries"` // Newest first
+}
+
+// AdminAuth asks for the configured admin credentials with HTTP basic auth
+func AdminAuth(cfg config.Admin) fiber.Handler {
+	return basicauth.New(basicauth.Config{
+		Users:           map[string]string{cfg.User: cfg.Password},
+		Realm:           "Admin",
+		ContextUsername: adminUserKey,
+	})
+}
+
+// sameOrigin rejects state-changing requests sent from another site's pages, which browsers would
+// otherwise send with the cached basic auth credentials. Requests without an Origin header, such as
+// those from scripts, are let through since they carry credentials explicitly.
+func sameOrigin(c *fiber.Ctx) error {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return c.Next()
+	}
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" {
+		return c.Next()
+	}
+	// Hosts only, as a TLS-terminating proxy changes the scheme the request arrives with
+	if u, err := url.Parse(origin); err != nil || u.Host != string(c.Request().Host()) {
+		return c.Status(fiber.StatusForbidden).SendString("Cross-origin admin requests are not allowed")
+	}
+	return c.Next()
+}
+
+// Register mounts the console on router, which must already require AdminAuth
+func (h *AdminHandler) Register(router fiber.Router) {
+	router.Use(sameOrigin)
+
+	router.Get("/", h.DashboardPage)
+	router.Post("/announce", h.AnnounceAllPage)
+	router.Get("/channels/:channel", h.ChannelPage)
+	router.Post("/channels/:channel/announce", h.AnnouncePage)
+	router.Post("/channels/:channel/end", h.ForceEndPage)
+	router.Post("/channels/:channel/purge", h.PurgePage)
+	router.Post("/channels/:channel/clients/:client/disconnect", h.DisconnectPage)
+
+	api := router.Group("/api", renderFailures)
+	api.Get("/channels", h.ListChannels)
+	api.Get("/channels/:channel", h.GetChannel)
+	api.Delete("/channels/:channel", h.PurgeChannel)
+	api.Post("/announce", h.AnnounceAll)
+	api.Post("/channels/:channel/announce", h.Announce)
+	api.Post("/channels/:channel/end", h.ForceEnd)
+	api.Post("/channels/:channel/clients/:client/disconnect", h.DisconnectClient)
+	api.Get("/audit", h.ListAudit)
+}
+
+// record adds the action to the audit log, attributed to the authenticated admin
+func (h *AdminHandler) record(c *fiber.Ctx, action, channel, client, detail string, err error) {
+	admin, _ := c.Locals(adminUserKey).(string)
+	requestID, _ := c.Locals(logging.RequestIDKey).(string)
+	entry := models.AuditEntry{
+		Admin:     admin,
+		IP:        c.IP(),
+		RequestId: requestID,
+		Action:    action,
+		Channel:   channel,
+		Client:    client,
+		Detail:    detail,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	h.Audit.Record(entry)
+}
+
+// announcementText validates the text of an announcement
+func announcementText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", errors.New("announcement text required")
+	}
+	if utf8.RuneCountInString(text) > maxAnnouncementLength {
+		return "", fmt.Errorf("announcement must be at most %d characters", maxAnnouncementLength)
+	}
+	return text, nil
+}
+
+func (h *AdminHandler) announceAll(c *fiber.Ctx, text string) (int, error) {
+	text, err := announcementText(text)
+	sent := 0
+	if err == nil {
+		sent = h.Service.AnnounceAll(text)
+	}
+	h.record(c, models.AuditAnnounce, "", "", text, err)
+	return sent, err
+}
+
+func (h *AdminHandler) announce(c *fiber.Ctx, ch *models.Channel, text string) error {
+	text, err := announcementText(text)
+	if err == nil {
+		h.Service.Announce(ch, text)
+	}
+	h.record(c, models.AuditAnnounce, ch.Name, "", text, err)
+	return err
+}
+
+func (h *AdminHandler) forceEnd(c *fiber.Ctx, ch *models.Channel) error {
+	err := h.Service.ForceEnd(ch)
+	h.record(c, models.AuditForceEnd, ch.Name, "", "", err)
+	return err
+}
+
+func (h *AdminHandler) purge(c *fiber.Ctx, name string) error {
+	var err error
+	if !h.Service.PurgeChannel(name) {
+		err = errors.New("channel not found")
+	}
+	h.record(c, models.AuditPurge, name, "", "", err)
+	return err
+}
+
+func (h *AdminHandler) disconnect(c *fiber.Ctx, ch *models.Channel, clientID string) (string, error) {
+	id, err := uuid.Parse(clientID)
+	name := ""
+	if err != nil {
+		err = services.ErrClientNotFound
+	} else {
+		name, err = h.Service.DisconnectClient(ch, id)
+	}
+	h.record(c, models.AuditDisconnect, ch.Name, clientID, name, err)
+	return name, err
+}
+
+// HTML console
+
+// adminURL is path with a notice or error message for the page to show
+func adminURL(path, notice string, err error) string {
+	if err != nil {
+		return path + "?error=" + url.QueryEscape(err.Error())
+	}
+	if notice != "" {
+		return path + "?notice=" + url.QueryEscape(notice)
+	}
+	return path
+}
+
+func adminChannelURL(name string) string {
+	return "/admin/channels/" + url.PathEscape(name)
+}
+
+func (h *AdminHandler) DashboardPage(c *fiber.Ctx) error {
+	return c.Render("admin", fiber.Map{
+		"Admin":    c.Locals(adminUserKey),
+		"Channels": h.Service.AdminChannels(),
+		"Audit":    h.Audit.Entries(adminAuditPageLimit),
+		"Notice":   c.Query("notice"),
+		"Error":    c.Query("error"),
+	})
+}
+
+func (h *AdminHandler) ChannelPage(c *fiber.Ctx) error {
+	ch := h.Service.GetChannel(c.Params("channel"))
+	if ch == nil {
+		return c.Redirect(adminURL("/admin", "", errors.New("channel "+c.Params("channel")+" not found")))
+	}
+	return c.Render("admin_channel", fiber.Map{
+		"Admin":    c.Locals(adminUserKey),
+		"Channel":  h.Service.AdminChannel(ch),
+		"Archives": len(h.Service.GetArchives(ch.Name)),
+		"Notice":   c.Query("notice"),
+		"Error":    c.Query("error"),
+	})
+}
+
+func (h *AdminHandler) AnnounceAllPage(c *fiber.Ctx) error {
+	sent, err := h.announceAll(c, c.FormValue("text"))
+	return c.Redirect(adminURL("/admin", fmt.Sprintf("Announcement sent to %d channels", sent), err))
+}
+
+func (h *AdminHandler) AnnouncePage(c *fiber.Ctx) error {
+	ch := h.Service.GetChannel(c.Params("channel"))
+	if ch == nil {
+		return c.Redirect(adminURL("/admin", "", errors.New("channel "+c.Params("channel")+" not found")))
+	}
+	err := h.announce(c, ch, c.FormValue("text"))
+	return c.Redirect(adminURL(adminChannelURL(ch.Name), "Announcement sent", err))
+}
+
+func (h *AdminHandler) ForceEndPage(c *fiber.Ctx) error {
+	ch := h.Service.GetChannel(c.Params("channel"))
+	if ch == nil {
+		return c.Redirect(adminURL("/admin", "", errors.New("channel "+c.Params("channel")+" not found")))
+	}
+	err := h.forceEnd(c, ch)
+	return c.Redirect(adminURL(adminChannelURL(ch.Name), "Debate ended", err))
+}
+
+func (h *AdminHandler) PurgePage(c *fiber.Ctx) error {
+	name := c.Params("channel")
+	err := h.purge(c, name)
+	return c.Redirect(adminURL("/admin", "Channel "+name+" purged", err))
+}
+
+func (h *AdminHandler) DisconnectPage(c *fiber.Ctx) error {
+	ch := h.Service.GetChannel(c.Params("channel"))
+	if ch == nil {
+		return c.Redirect(adminURL("/admin", "", errors.New("channel "+c.Params("channel")+" not found")))
+	}
+	name, err := h.disconnect(c, ch, c.Params("client"))
+	return c.Redirect(adminURL(adminChannelURL(ch.Name), name+" disconnected", err))
+}
+
+// JSON API
+
+func (h *AdminHandler) lookupChannel(c *fiber.Ctx) (*models.Channel, error) {
+	ch := h.Service.GetChannel(c.Params("channel"))
+	if ch == nil {
+		return nil, failure(fiber.StatusNotFound, "channel_not_found", "Channel not found")
+	}
+	return ch, nil
+}
+
+func (h *AdminHandler) ListChannels(c *fiber.Ctx) error {
+	return c.JSON(AdminChannelsResponse{Channels: h.Service.AdminChannels()})
+}
+
+func (h *AdminHandler) GetChannel(c *fiber.Ctx) error {
+	ch, err := h.lookupChannel(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(h.Service.AdminChannel(ch))
+}
+
+func (h *AdminHandler) AnnounceAll(c *fiber.Ctx) error {
+	var req AnnounceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid_body", "Request body must be a JSON object")
+	}
+	sent, err := h.announceAll(c, req.Text)
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid_text", err.Error())
+	}
+	return c.JSON(AnnounceResponse{Channels: sent})
+}
+
+func (h *AdminHandler) Announce(c *fiber.Ctx) error {
+	ch, err := h.lookupChannel(c)
+	if err != nil {
+		return err
+	}
+	var req AnnounceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid_body", "Request body must be a JSON object")
+	}
+	if err := h.announce(c, ch, req.Text); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid_text", err.Error())
+	}
+	return c.JSON(AnnounceResponse{Channels: 1})
+}
+
+func (h *AdminHandler) ForceEnd(c *fiber.Ctx) error {
+	ch, err := h.lookupChannel(c)
+	if err != nil {
+		return err
+	}
+	if err := h.forceEnd(c, ch); err != nil {
+		return apiError(c, fiber.StatusConflict, "no_debate", "No debate in progress")
+	}
+	return c.JSON(h.Service.AdminChannel(ch))
+}
+
+func (h *AdminHandler) PurgeChannel(c *fiber.Ctx) error {
+	if err := h.purge(c, c.Params("channel")); err != nil {
+		return apiError(c, fiber.StatusNotFound, "channel_not_found", "Channel not found")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AdminHandler) DisconnectClient(c *fiber.Ctx) error {
+	ch, err := h.lookupChannel(c)
+	if err != nil {
+		return err
+	}
+	if _, err := h.disconnect(c, ch, c.Params("client")); err != nil {
+		return apiError(c, fiber.StatusNotFound, "client_not_found", "Client not found")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AdminHandler) ListAudit(c *fiber.Ctx) error {
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(adminAuditPageLimit)))
+	if err != nil || limit < 1 || limit > services.MaxAuditEntries {
+		return apiError(c, fiber.StatusBadRequest, "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", services.MaxAuditEntries))
+	}
+	return c.JSON(AuditResponse{Entries: h.Audit.Entries(limit)})
+}
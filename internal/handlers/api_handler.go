@@ -0,0 +1,731 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/services"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+
+	// channelInstanceHeader names the instance running a channel the request could not be served for here
+	channelInstanceHeader = "X-Channel-Instance"
+)
+
+type APIHandler struct {
+	Service     *services.ChannelService
+	Ratings     *services.RatingService
+	Matchmaking *services.MatchmakingService
+	Tournaments *services.TournamentService
+	Schedules   *services.ScheduleService
+	Limits      *RateLimits
+}
+
+func NewAPIHandler(service *services.ChannelService, ratings *services.RatingService, matchmaking *services.MatchmakingService, tournaments *services.TournamentService, schedules *services.ScheduleService, limits *RateLimits) *APIHandler {
+	return &APIHandler{Service: service, Ratings: ratings, Matchmaking: matchmaking, Tournaments: tournaments, Schedules: schedules, Limits: limits}
+}
+
+// APIError is the body of every non-2xx JSON response
+type APIError struct {
+	Error APIErrorDetail `json:"error"`
+}
+
+type APIErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type CreateChannelRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Motion   string `json:"motion"`
+	Owner    string `json:"owner"`
+	BestOf   int    `json:"bestOf"`
+
+	// StartsAt schedules the debate: it begins automatically at this time if both debaters are ready
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+}
+
+// CreateChannelResponse carries the owner key, which is only ever returned here
+type CreateChannelResponse struct {
+	models.ChannelSummary
+	OwnerKey string `json:"ownerKey"`
+}
+
+type SchedulesResponse struct {
+	Schedules []models.ScheduledDebate `json:"schedules"`
+}
+
+type ArchivesResponse struct {
+	Archives []*models.Archive `json:"archives"`
+}
+
+type MessagePageResponse struct {
+	Messages []models.Message `json:"messages"`
+	Offset   int              `json:"offset"`
+	Limit    int              `json:"limit"`
+	Total    int              `json:"total"`
+}
+
+type EventLogResponse struct {
+	Events []models.ChannelEvent `json:"events"` // Oldest first
+}
+
+type JudgeReportsResponse struct {
+	Reports []models.Message `json:"reports"`
+}
+
+type EnqueueRequest struct {
+	Player  string   `json:"player"`
+	Formats []string `json:"formats"` // single or bo3; any if empty
+	Topics  []string `json:"topics"`  // Motion topics; any if empty
+}
+
+type CreateTournamentRequest struct {
+	Name      string `json:"name"`
+	Format    string `json:"format"` // single_elimination or swiss
+	Motion    string `json:"motion"` // Drawn per match when empty
+	Organizer string `json:"organizer"`
+	Rounds    int    `json:"rounds"` // Swiss rounds; defaults to log2 of the field
+}
+
+// CreateTournamentResponse carries the organizer key, which is only ever returned here
+type CreateTournamentResponse struct {
+	models.Tournament
+	OrganizerKey string `json:"organizerKey"`
+}
+
+type TournamentsResponse struct {
+	Tournaments []models.Tournament `json:"tournaments"`
+}
+
+type RegisterPlayerRequest struct {
+	Player string `json:"player"`
+}
+
+// RegisterPlayerResponse carries the player key, which is only ever returned here
+type RegisterPlayerResponse struct {
+	Player    string `json:"player"`
+	PlayerKey string `json:"playerKey"`
+}
+
+// PlayerMatchResponse is a player's live match together with the channel password
+type PlayerMatchResponse struct {
+	models.TournamentMatch
+	Password string `json:"password"`
+}
+
+type AwardMatchRequest struct {
+	Winner string `json:"winner"` // The proposition or opposition of the match
+}
+
+type PlayerRatingResponse struct {
+	models.Rating
+	History []models.RatingChange `json:"history"` // Newest first
+}
+
+// Routes describes every /api/v1 endpoint; it drives both registration and the OpenAPI document
+func (h *APIHandler) Routes() []APIRoute {
+	channelParam := APIParam{Name: "channel", In: "path", Description: "Channel name", Required: true}
+	ticketParam := APIParam{Name: "ticket", In: "path", Description: "Ticket id returned when queueing", Required: true}
+	tournamentParam := APIParam{Name: "tournament", In: "path", Description: "Tournament id", Required: true}
+	matchParam := APIParam{Name: "match", In: "path", Description: "Match id, such as r1-m2", Required: true}
+	organizerHeader := APIParam{Name: "X-Organizer-Key", In: "header", Description: "Organizer key returned on creation", Required: true}
+	playerHeader := APIParam{Name: "X-Player-Key", In: "header", Description: "Player key returned on registration", Required: true}
+	ownerHeader := APIParam{Name: "X-Owner-Key", In: "header", Description: "Owner key returned on creation", Required: true}
+
+	return []APIRoute{
+		{
+			Method: fiber.MethodGet, Path: "/channels", Summary: "List channels",
+			Params: []APIParam{
+				{Name: "q", In: "query", Description: "Search channel names and motions"},
+				{Name: "sort", In: "query", Description: "name, participants, spectators, phase or created"},
+				{Name: "order", In: "query", Description: "asc or desc"},
+				{Name: "offset", In: "query", Description: "Index of the first channel", Type: "integer"},
+				{Name: "limit", In: "query", Description: "Page size (max 200)", Type: "integer"},
+			},
+			Response: models.ChannelListing{}, Errors: []int{fiber.StatusBadRequest}, Handler: h.ListChannels,
+		},
+		{
+			Method: fiber.MethodPost, Path: "/channels", Summary: "Create a channel",
+			Body: CreateChannelRequest{}, Status: fiber.StatusCreated, Response: CreateChannelResponse{},
+			Errors: []int{fiber.StatusBadRequest, fiber.StatusConflict, fiber.StatusTooManyRequests}, Handler: h.CreateChannel,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/channels/:channel", Summary: "Get a channel",
+			Params: []APIParam{channelParam}, Response: models.ChannelSummary{},
+			Errors: []int{fiber.StatusTemporaryRedirect, fiber.StatusNotFound, fiber.StatusConflict}, Handler: h.GetChannel,
+		},
+		{
+			Method: fiber.MethodDelete, Path: "/channels/:channel", Summary: "Delete a channel",
+			Params: []APIParam{channelParam, ownerHeader}, Status: fiber.StatusNoContent,
+			Errors: []int{fiber.StatusTemporaryRedirect, fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusConflict}, Handler: h.DeleteChannel,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/channels/:channel/state", Summary: "Get the live debate state",
+			Params: []APIParam{channelParam}, Response: models.ChannelState{},
+			Errors: []int{fiber.StatusTemporaryRedirect, fiber.StatusNotFound, fiber.StatusConflict}, Handler: h.GetChannelState,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/channels/:channel/messages", Summary: "Page through message history",
+			Params: []APIParam{
+				channelParam,
+				{Name: "offset", In: "query", Description: "Index of the first message", Type: "integer"},
+				{Name: "limit", In: "query", Description: "Page size (max 200)", Type: "integer"},
+			},
+			Response: MessagePageResponse{}, Errors: []int{fiber.StatusTemporaryRedirect, fiber.StatusBadRequest, fiber.StatusNotFound, fiber.StatusConflict},
+			Handler: h.ListMessages,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/channels/:channel/events", Summary: "Read the channel's event log, without the text of unreleased submissions",
+			Params: []APIParam{
+				channelParam,
+				{Name: "since", In: "query", Description: "Only events with a higher sequence number", Type: "integer"},
+			},
+			Response: EventLogResponse{}, Errors: []int{fiber.StatusTemporaryRedirect, fiber.StatusBadRequest, fiber.StatusNotFound, fiber.StatusConflict},
+			Handler: h.ListEvents,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/channels/:channel/judge-reports", Summary: "List judge verdicts",
+			Params: []APIParam{channelParam}, Response: JudgeReportsResponse{},
+			Errors: []int{fiber.StatusTemporaryRedirect, fiber.StatusNotFound, fiber.StatusConflict}, Handler: h.ListJudgeReports,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/channels/:channel/archives", Summary: "List concluded debates",
+			Params: []APIParam{channelParam}, Response: ArchivesResponse{},
+			Errors: []int{fiber.StatusNotFound}, Handler: h.ListArchives,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/channels/:channel/transcript", Summary: "Export a round's transcript",
+			Params: []APIParam{
+				channelParam,
+				{Name: "round", In: "query", Description: "Round to export, defaults to the round in progress or the latest", Type: "integer"},
+				{Name: "format", In: "query", Description: "json (default), markdown or html"},
+			},
+			Response: models.TranscriptExport{}, Errors: []int{fiber.StatusBadRequest, fiber.StatusNotFound},
+			Handler: h.ExportTranscript,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/channels/:channel/schedule", Summary: "Get a channel's scheduled start",
+			Params: []APIParam{channelParam}, Response: models.ScheduledDebate{},
+			Errors: []int{fiber.StatusNotFound}, Handler: h.GetSchedule,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/channels/:channel/schedule.ics", Summary: "Export a channel's schedule as iCalendar",
+			Params: []APIParam{channelParam}, ContentType: icsContentType,
+			Errors: []int{fiber.StatusNotFound}, Handler: h.GetScheduleICS,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/schedules", Summary: "List scheduled debates",
+			Params: []APIParam{
+				{Name: "all", In: "query", Description: "true to include schedules started, forfeited or cancelled within the retention period", Type: "boolean"},
+			},
+			Response: SchedulesResponse{}, Handler: h.ListSchedules,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/schedules.ics", Summary: "Export upcoming debates as iCalendar",
+			ContentType: icsContentType, Handler: h.ListSchedulesICS,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/ratings", Summary: "Leaderboard by rating",
+			Params: []APIParam{
+				{Name: "provisional", In: "query", Description: "false to leave out provisional ratings", Type: "boolean"},
+				{Name: "offset", In: "query", Description: "Index of the first player", Type: "integer"},
+				{Name: "limit", In: "query", Description: "Page size (max 200)", Type: "integer"},
+			},
+			Response: models.Leaderboard{}, Errors: []int{fiber.StatusBadRequest}, Handler: h.Leaderboard,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/ratings/:player", Summary: "Get a player's rating and history",
+			Params:   []APIParam{{Name: "player", In: "path", Description: "Player name", Required: true}},
+			Response: PlayerRatingResponse{}, Handler: h.GetPlayerRating,
+		},
+		{
+			Method: fiber.MethodPost, Path: "/matchmaking", Summary: "Join the matchmaking queue, replacing the ticket the session cookie already has waiting",
+			Body: EnqueueRequest{}, Status: fiber.StatusCreated, Response: models.MatchTicket{},
+			Errors: []int{fiber.StatusBadRequest, fiber.StatusConflict, fiber.StatusTooManyRequests}, Handler: h.Enqueue,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/matchmaking/:ticket", Summary: "Get a matchmaking ticket",
+			Params: []APIParam{ticketParam}, Response: models.MatchTicket{},
+			Errors: []int{fiber.StatusNotFound}, Handler: h.GetTicket,
+		},
+		{
+			Method: fiber.MethodDelete, Path: "/matchmaking/:ticket", Summary: "Leave the matchmaking queue",
+			Params: []APIParam{ticketParam}, Status: fiber.StatusNoContent,
+			Errors: []int{fiber.StatusNotFound}, Handler: h.CancelTicket,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/tournaments", Summary: "List tournaments",
+			Response: TournamentsResponse{}, Handler: h.ListTournaments,
+		},
+		{
+			Method: fiber.MethodPost, Path: "/tournaments", Summary: "Create a tournament",
+			Body: CreateTournamentRequest{}, Status: fiber.StatusCreated, Response: CreateTournamentResponse{},
+			Errors: []int{fiber.StatusBadRequest}, Handler: h.CreateTournament,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/tournaments/:tournament", Summary: "Get a tournament bracket",
+			Params: []APIParam{tournamentParam}, Response: models.Tournament{},
+			Errors: []int{fiber.StatusNotFound}, Handler: h.GetTournament,
+		},
+		{
+			Method: fiber.MethodPost, Path: "/tournaments/:tournament/players", Summary: "Register for a tournament",
+			Params: []APIParam{tournamentParam}, Body: RegisterPlayerRequest{}, Status: fiber.StatusCreated,
+			Response: RegisterPlayerResponse{}, Errors: []int{fiber.StatusBadRequest, fiber.StatusNotFound, fiber.StatusConflict},
+			Handler: h.RegisterPlayer,
+		},
+		{
+			Method: fiber.MethodPost, Path: "/tournaments/:tournament/start", Summary: "Start a tournament",
+			Params: []APIParam{tournamentParam, organizerHeader}, Response: models.Tournament{},
+			Errors: []int{fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusConflict, fiber.StatusTooManyRequests}, Handler: h.StartTournament,
+		},
+		{
+			Method: fiber.MethodGet, Path: "/tournaments/:tournament/my-match", Summary: "Get your live match and its password",
+			Params: []APIParam{tournamentParam, playerHeader}, Response: PlayerMatchResponse{},
+			Errors: []int{fiber.StatusNotFound}, Handler: h.GetPlayerMatch,
+		},
+		{
+			Method: fiber.MethodPost, Path: "/tournaments/:tournament/matches/:match/winner", Summary: "Award a match to one of its players",
+			Params: []APIParam{tournamentParam, matchParam, organizerHeader}, Body: AwardMatchRequest{}, Response: models.Tournament{},
+			Errors: []int{fiber.StatusBadRequest, fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusConflict}, Handler: h.AwardMatch,
+		},
+	}
+}
+
+// Register mounts the API routes and the generated OpenAPI document on router
+func (h *APIHandler) Register(router fiber.Router) {
+	routes := h.Routes()
+	router.Use(renderFailures)
+	for _, route := range routes {
+		router.Add(route.Method, route.Path, route.Handler)
+	}
+
+	spec := BuildOpenAPI(routes, "/api/v1")
+	router.Get("/openapi.json", func(c *fiber.Ctx) error {
+		return c.JSON(spec)
+	})
+}
+
+func apiError(c *fiber.Ctx, status int, code, message string) error {
+	return c.Status(status).JSON(APIError{Error: APIErrorDetail{Code: code, Message: message}})
+}
+
+// apiFailure is an error that helpers return for the handler to pass on, answered like apiError by
+// renderFailures
+type apiFailure struct {
+	status int
+	detail APIErrorDetail
+}
+
+func (f *apiFailure) Error() string {
+	return f.detail.Message
+}
+
+func failure(status int, code, message string) error {
+	return &apiFailure{status: status, detail: APIErrorDetail{Code: code, Message: message}}
+}
+
+// renderFailures answers an apiFailure returned by the handler with its APIError body
+func renderFailures(c *fiber.Ctx) error {
+	err := c.Next()
+	var f *apiFailure
+	if errors.As(err, &f) {
+		return apiError(c, f.status, f.detail.Code, f.detail.Message)
+	}
+	return err
+}
+
+// lookupChannel finds the channel named in the path. A channel open on another instance is only
+// readable there: the client is redirected to the URL that instance advertises, or told which
+// instance it is when it advertises none.
+func (h *APIHandler) lookupChannel(c *fiber.Ctx) (*models.Channel, error) {
+	name := c.Params("channel")
+	if ch := h.Service.GetChannel(name); ch != nil {
+		return ch, nil
+	}
+	instance, advertiseURL := h.Service.ChannelLocation(name)
+	switch {
+	case instance == "":
+		return nil, failure(fiber.StatusNotFound, "channel_not_found", "Channel not found")
+	case advertiseURL != "":
+		c.Set(fiber.HeaderLocation, strings.TrimSuffix(advertiseURL, "/")+c.OriginalURL())
+		return nil, failure(fiber.StatusTemporaryRedirect, "channel_elsewhere", "Channel is open on instance "+instance)
+	default:
+		c.Set(channelInstanceHeader, instance)
+		return nil, failure(fiber.StatusConflict, "channel_elsewhere", "Channel is open on instance "+instance+", which advertises no URL")
+	}
+}
+
+// pageParams parses the offset and limit query parameters
+func pageParams(c *fiber.Ctx) (int, int, error) {
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil || offset < 0 {
+		return 0, 0, failure(fiber.StatusBadRequest, "invalid_offset", "offset must be a non-negative integer")
+	}
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, 0, failure(fiber.StatusBadRequest, "invalid_limit", "limit must be between 1 and 200")
+	}
+	return offset, limit, nil
+}
+
+func (h *APIHandler) ListChannels(c *fiber.Ctx) error {
+	offset, limit, err := pageParams(c)
+	if err != nil {
+		return err
+	}
+
+	order := c.Query("order", "asc")
+	if order != "asc" && order != "desc" {
+		return apiError(c, fiber.StatusBadRequest, "invalid_order", "order must be asc or desc")
+	}
+
+	return c.JSON(h.Service.ListChannels(models.ChannelQuery{
+		Search: c.Query("q"),
+		Sort:   c.Query("sort"),
+		Desc:   order == "desc",
+		Offset: offset,
+		Limit:  limit,
+	}))
+}
+
+func (h *APIHandler) CreateChannel(c *fiber.Ctx) error {
+	var req CreateChannelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid_body", "Request body must be a JSON object")
+	}
+	if req.Name == "" {
+		return apiError(c, fiber.StatusBadRequest, "name_required", "Channel name required")
+	}
+	if maxBestOf := h.Service.MaxBestOf(); req.BestOf < 0 || req.BestOf > maxBestOf {
+		return apiError(c, fiber.StatusBadRequest, "invalid_best_of", fmt.Sprintf("bestOf must be between 1 and %d", maxBestOf))
+	}
+	if h.Service.ChannelExists(req.Name) {
+		return apiError(c, fiber.StatusConflict, "channel_exists", "Channel already exists")
+	}
+	if ok, msg := allow(c, h.Limits.ChannelCreate); !ok {
+		return apiError(c, fiber.StatusTooManyRequests, "rate_limited", msg)
+	}
+
+	opts := models.ChannelOptions{
+		Name:     req.Name,
+		Password: req.Password,
+		Motion:   req.Motion,
+		Owner:    req.Owner,
+		BestOf:   req.BestOf,
+	}
+	var ch *models.Channel
+	var err error
+	if req.StartsAt == nil {
+		ch, err = h.Service.CreateChannel(opts)
+	} else {
+		opts.StartsAt = *req.StartsAt
+		ch, err = h.Schedules.Schedule(opts)
+	}
+	switch {
+	case errors.Is(err, services.ErrChannelExists), errors.Is(err, services.ErrChannelOwnedElsewhere):
+		return apiError(c, fiber.StatusConflict, "channel_exists", "Channel already exists")
+	case errors.Is(err, services.ErrStartInPast):
+		return apiError(c, fiber.StatusBadRequest, "invalid_starts_at", err.Error())
+	case err != nil:
+		slog.Error("creating channel", "channel", req.Name, "err", err)
+		return apiError(c, fiber.StatusServiceUnavailable, "unavailable", "The channel could not be created, please try again")
+	}
+	return c.Status(fiber.StatusCreated).JSON(CreateChannelResponse{
+		ChannelSummary: h.Service.Summarize(ch),
+		OwnerKey:       ch.OwnerKey,
+	})
+}
+
+func (h *APIHandler) GetChannel(c *fiber.Ctx) error {
+	ch, err := h.lookupChannel(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(h.Service.Summarize(ch))
+}
+
+func (h *APIHandler) DeleteChannel(c *fiber.Ctx) error {
+	ch, err := h.lookupChannel(c)
+	if err != nil {
+		return err
+	}
+
+	if !h.Service.IsOwner(ch, c.Get("X-Owner-Key")) {
+		return apiError(c, fiber.StatusForbidden, "not_owner", "Only the creator can delete channel "+ch.Name)
+	}
+
+	if !h.Service.DeleteChannel(ch.Name) {
+		return apiError(c, fiber.StatusNotFound, "channel_not_found", "Channel not found")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *APIHandler) GetChannelState(c *fiber.Ctx) error {
+	ch, err := h.lookupChannel(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(h.Service.ChannelState(ch))
+}
+
+func (h *APIHandler) ListMessages(c *fiber.Ctx) error {
+	ch, err := h.lookupChannel(c)
+	if err != nil {
+		return err
+	}
+
+	offset, limit, err := pageParams(c)
+	if err != nil {
+		return err
+	}
+
+	messages, total := h.Service.MessagePage(ch, offset, limit)
+	return c.JSON(MessagePageResponse{
+		Messages: messages,
+		Offset:   offset,
+		Limit:    limit,
+		Total:    total,
+	})
+}
+
+func (h *APIHandler) ListEvents(c *fiber.Ctx) error {
+	ch, err := h.lookupChannel(c)
+	if err != nil {
+		return err
+	}
+
+	since, err := strconv.Atoi(c.Query("since", "0"))
+	if err != nil || since < 0 {
+		return apiError(c, fiber.StatusBadRequest, "invalid_since", "since must be a non-negative integer")
+	}
+	return c.JSON(EventLogResponse{Events: h.Service.EventLog(ch, since)})
+}
+
+func (h *APIHandler) ListJudgeReports(c *fiber.Ctx) error {
+	ch, err := h.lookupChannel(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(JudgeReportsResponse{Reports: h.Service.JudgeReports(ch)})
+}
+
+func (h *APIHandler) ListArchives(c *fiber.Ctx) error {
+	archives := h.Service.GetArchives(c.Params("channel"))
+	if len(archives) == 0 {
+		return apiError(c, fiber.StatusNotFound, "archive_not_found", "No concluded debates for this channel")
+	}
+	return c.JSON(ArchivesResponse{Archives: archives})
+}
+
+func (h *APIHandler) Leaderboard(c *fiber.Ctx) error {
+	offset, limit, err := pageParams(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(h.Ratings.Leaderboard(offset, limit, c.QueryBool("provisional", true)))
+}
+
+// GetPlayerRating answers for unrated players too, with the provisional starting rating
+func (h *APIHandler) GetPlayerRating(c *fiber.Ctx) error {
+	player := c.Params("player")
+	return c.JSON(PlayerRatingResponse{
+		Rating:  h.Ratings.GetRating(player),
+		History: h.Ratings.History(player),
+	})
+}
+
+func (h *APIHandler) Enqueue(c *fiber.Ctx) error {
+	var req EnqueueRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid_body", "Request body must be a JSON object")
+	}
+	if req.Player == "" {
+		return apiError(c, fiber.StatusBadRequest, "player_required", "Player name required")
+	}
+	// Every pair matched opens a channel
+	if ok, msg := allow(c, h.Limits.ChannelCreate); !ok {
+		return apiError(c, fiber.StatusTooManyRequests, "rate_limited", msg)
+	}
+
+	ticket, err := h.Matchmaking.Enqueue(req.Player, session(c), req.Formats, req.Topics)
+	switch {
+	case errors.Is(err, services.ErrUnknownFormat):
+		return apiError(c, fiber.StatusBadRequest, "invalid_format", err.Error())
+	case errors.Is(err, services.ErrUnknownTopic):
+		return apiError(c, fiber.StatusBadRequest, "invalid_topic", err.Error()+" (choose from "+strings.Join(services.MotionTopics(), ", ")+")")
+	case errors.Is(err, services.ErrNameTaken):
+		return apiError(c, fiber.StatusConflict, "name_taken", err.Error())
+	case err != nil:
+		slog.Error("joining matchmaking", "player", req.Player, "err", err)
+		return apiError(c, fiber.StatusInternalServerError, "internal_error", "Could not join the queue, please try again")
+	}
+	return c.Status(fiber.StatusCreated).JSON(ticket)
+}
+
+func (h *APIHandler) GetTicket(c *fiber.Ctx) error {
+	ticket, ok := h.Matchmaking.Ticket(c.Params("ticket"))
+	if !ok {
+		return apiError(c, fiber.StatusNotFound, "ticket_not_found", "Ticket not found")
+	}
+	return c.JSON(ticket)
+}
+
+func (h *APIHandler) CancelTicket(c *fiber.Ctx) error {
+	if !h.Matchmaking.Cancel(c.Params("ticket")) {
+		return apiError(c, fiber.StatusNotFound, "ticket_not_found", "No waiting ticket with this id")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *APIHandler) ListTournaments(c *fiber.Ctx) error {
+	return c.JSON(TournamentsResponse{Tournaments: h.Tournaments.List()})
+}
+
+func (h *APIHandler) CreateTournament(c *fiber.Ctx) error {
+	var req CreateTournamentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid_body", "Request body must be a JSON object")
+	}
+	if req.Name == "" {
+		return apiError(c, fiber.StatusBadRequest, "name_required", "Tournament name required")
+	}
+	if req.Rounds < 0 || req.Rounds > 12 {
+		return apiError(c, fiber.StatusBadRequest, "invalid_rounds", "rounds must be between 1 and 12")
+	}
+
+	t, err := h.Tournaments.Create(req.Name, req.Format, req.Motion, req.Organizer, req.Rounds)
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid_format", err.Error())
+	}
+	key := t.OrganizerKey
+	t.OrganizerKey = ""
+	return c.Status(fiber.StatusCreated).JSON(CreateTournamentResponse{Tournament: t, OrganizerKey: key})
+}
+
+func (h *APIHandler) GetTournament(c *fiber.Ctx) error {
+	t, ok := h.Tournaments.Get(c.Params("tournament"))
+	if !ok {
+		return apiError(c, fiber.StatusNotFound, "tournament_not_found", "Tournament not found")
+	}
+	return c.JSON(t)
+}
+
+func (h *APIHandler) RegisterPlayer(c *fiber.Ctx) error {
+	var req RegisterPlayerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid_body", "Request body must be a JSON object")
+	}
+	if req.Player == "" {
+		return apiError(c, fiber.StatusBadRequest, "player_required", "Player name required")
+	}
+
+	key, err := h.Tournaments.Register(c.Params("tournament"), req.Player)
+	if err != nil {
+		return tournamentError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(RegisterPlayerResponse{Player: req.Player, PlayerKey: key})
+}
+
+func (h *APIHandler) StartTournament(c *fiber.Ctx) error {
+	// Starting opens a channel for every match of the first round
+	if ok, msg := allow(c, h.Limits.ChannelCreate); !ok {
+		return apiError(c, fiber.StatusTooManyRequests, "rate_limited", msg)
+	}
+	t, err := h.Tournaments.Start(c.Params("tournament"), c.Get("X-Organizer-Key"))
+	if err != nil {
+		return tournamentError(c, err)
+	}
+	return c.JSON(t)
+}
+
+func (h *APIHandler) AwardMatch(c *fiber.Ctx) error {
+	var req AwardMatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid_body", "Request body must be a JSON object")
+	}
+	t, err := h.Tournaments.AwardMatch(c.Params("tournament"), c.Get("X-Organizer-Key"), c.Params("match"), req.Winner)
+	if err != nil {
+		return tournamentError(c, err)
+	}
+	return c.JSON(t)
+}
+
+func (h *APIHandler) GetPlayerMatch(c *fiber.Ctx) error {
+	match, err := h.Tournaments.PlayerMatch(c.Params("tournament"), c.Get("X-Player-Key"))
+	if err != nil {
+		return tournamentError(c, err)
+	}
+	password := match.Password
+	match.Password = ""
+	return c.JSON(PlayerMatchResponse{TournamentMatch: match, Password: password})
+}
+
+// tournamentError maps tournament service errors to API errors
+func tournamentError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrTournamentNotFound):
+		return apiError(c, fiber.StatusNotFound, "tournament_not_found", "Tournament not found")
+	case errors.Is(err, services.ErrNoLiveMatch):
+		return apiError(c, fiber.StatusNotFound, "no_live_match", "No live match for this player key")
+	case errors.Is(err, services.ErrNotOrganizer):
+		return apiError(c, fiber.StatusForbidden, "not_organizer", err.Error())
+	case errors.Is(err, services.ErrTournamentStarted):
+		return apiError(c, fiber.StatusConflict, "tournament_started", err.Error())
+	case errors.Is(err, services.ErrAlreadyRegistered):
+		return apiError(c, fiber.StatusConflict, "already_registered", err.Error())
+	case errors.Is(err, services.ErrNotEnoughPlayers):
+		return apiError(c, fiber.StatusConflict, "not_enough_players", err.Error())
+	case errors.Is(err, services.ErrMatchNotFound):
+		return apiError(c, fiber.StatusNotFound, "match_not_found", "Match not found")
+	case errors.Is(err, services.ErrMatchDecided):
+		return apiError(c, fiber.StatusConflict, "match_decided", err.Error())
+	case errors.Is(err, services.ErrNotInMatch):
+		return apiError(c, fiber.StatusBadRequest, "invalid_winner", err.Error())
+	default:
+		slog.Error("tournament request", "path", c.Path(), "err", err)
+		return apiError(c, fiber.StatusInternalServerError, "internal_error", "The tournament request failed, please try again")
+	}
+}
+
+const icsContentType = "text/calendar; charset=utf-8"
+
+func (h *APIHandler) GetSchedule(c *fiber.Ctx) error {
+	sd, ok := h.Schedules.Get(c.Params("channel"))
+	if !ok {
+		return apiError(c, fiber.StatusNotFound, "not_scheduled", "Channel has no scheduled start")
+	}
+	return c.JSON(sd)
+}
+
+func (h *APIHandler) GetScheduleICS(c *fiber.Ctx) error {
+	sd, ok := h.Schedules.Get(c.Params("channel"))
+	if !ok {
+		return apiError(c, fiber.StatusNotFound, "not_scheduled", "Channel has no scheduled start")
+	}
+	return sendICS(c, sd.Channel+".ics", []models.ScheduledDebate{sd})
+}
+
+func (h *APIHandler) ListSchedules(c *fiber.Ctx) error {
+	return c.JSON(SchedulesResponse{Schedules: h.Schedules.List(c.QueryBool("all"))})
+}
+
+func (h *APIHandler) ListSchedulesICS(c *fiber.Ctx) error {
+	return sendICS(c, "debates.ics", h.Schedules.List(false))
+}
+
+func sendICS(c *fiber.Ctx, filename string, schedules []models.ScheduledDebate) error {
+	c.Set(fiber.HeaderContentType, icsContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.SendString(buildICS(schedules))
+}
@@ -2,50 +2,189 @@ package handlers
 
 import (
 	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
 	"github.com/latestcomment/go-websocket-chat/internal/services"
 )
 
 type Handler struct {
 	ChannelManager *services.ChannelService
+	Ratings        *services.RatingService
+	Matchmaking    *services.MatchmakingService
+	Tournaments    *services.TournamentService
+	Schedules      *services.ScheduleService
+	Limits         *RateLimits
 }
 
-func NewHandler(cm *services.ChannelService) *Handler {
-	return &Handler{ChannelManager: cm}
+func NewHandler(cm *services.ChannelService, ratings *services.RatingService, matchmaking *services.MatchmakingService, tournaments *services.TournamentService, schedules *services.ScheduleService, limits *RateLimits) *Handler {
+	return &Handler{ChannelManager: cm, Ratings: ratings, Matchmaking: matchmaking, Tournaments: tournaments, Schedules: schedules, Limits: limits}
 }
 
 func (h *Handler) LoginPage(c *fiber.Ctx) error {
 	return c.Render("index", nil)
 }
 
+const channelsPerPage = 24
+
 func (h *Handler) ChannelPage(c *fiber.Ctx) error {
 	name := c.FormValue("name")
-	return c.Render("channel", fiber.Map{
+	return h.renderChannels(c, name, "")
+}
+
+// renderChannels renders the channel list using the search, sort and page form values
+func (h *Handler) renderChannels(c *fiber.Ctx, name, errMsg string) error {
+	page, err := strconv.Atoi(c.FormValue("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	query := models.ChannelQuery{
+		Search: c.FormValue("q"),
+		Sort:   c.FormValue("sort"),
+		Desc:   c.FormValue("order") == "desc",
+		Offset: (page - 1) * channelsPerPage,
+		Limit:  channelsPerPage,
+	}
+	listing := h.ChannelManager.ListChannels(query)
+
+	// Channels this browser created, identified by the owner cookie set on creation
+	owned := make(map[string]bool)
+	for _, summary := range listing.Channels {
+		key := c.Cookies(ownerCookie(summary.Id.String()))
+		if key == "" {
+			continue
+		}
+		if ch := h.ChannelManager.GetChannel(summary.Name); ch != nil && h.ChannelManager.IsOwner(ch, key) {
+			owned[summary.Name] = true
+		}
+	}
+
+	data := fiber.Map{
 		"Name":     name,
-		"Channels": h.ChannelManager.Manager.Channels,
-	})
+		"Channels": listing.Channels,
+		"Owned":    owned,
+		"Query":    query.Search,
+		"Sort":     query.Sort,
+		"Order":    c.FormValue("order"),
+		"Page":     page,
+		"PrevPage": page - 1,
+		"NextPage": 0,
+		"Topics":   services.MotionTopics(),
+	}
+	if listing.Offset+len(listing.Channels) < listing.Total {
+		data["NextPage"] = page + 1
+	}
+	if errMsg != "" {
+		data["Error"] = errMsg
+	}
+	return c.Render("channel", data)
 }
 
 func (h *Handler) CreateChannelPage(c *fiber.Ctx) error {
 	name := c.FormValue("name")                // user's name
 	channelName := c.FormValue("channel")      // new channel name
 	channelPassword := c.FormValue("password") // new channel password
+	motion := c.FormValue("motion")            // proposition to debate
+	// A missing series length parses as 0, which takes the configured default format
+	bestOf, _ := strconv.Atoi(c.FormValue("bestOf"))
 
 	if channelName == "" {
 		return c.Status(fiber.StatusBadRequest).SendString("Channel name required")
 	}
+	if bestOf > h.ChannelManager.MaxBestOf() {
+		return h.renderChannels(c, name, fmt.Sprintf("Series can be at most best of %d", h.ChannelManager.MaxBestOf()))
+	}
 
-	// Create channel if it doesn't exist
-	ch := h.ChannelManager.GetChannel(channelName)
-	if ch == nil {
-		ch = h.ChannelManager.CreateChannel(channelName, channelPassword)
+	startsAt, err := parseLocalTime(c.FormValue("startsAt"), c.FormValue("tzOffset"))
+	if err != nil {
+		return h.renderChannels(c, name, "Invalid start time")
+	}
+
+	// Create channel if it doesn't exist here or on another instance
+	if !h.ChannelManager.ChannelExists(channelName) {
+		if ok, msg := allow(c, h.Limits.ChannelCreate); !ok {
+			return h.renderChannels(c, name, msg)
+		}
+		var ch *models.Channel
+		opts := models.ChannelOptions{
+			Name:     channelName,
+			Password: channelPassword,
+			Motion:   motion,
+			Owner:    name,
+			BestOf:   bestOf,
+			StartsAt: startsAt,
+		}
+		if startsAt.IsZero() {
+			ch, err = h.ChannelManager.CreateChannel(opts)
+		} else {
+			ch, err = h.Schedules.Schedule(opts)
+		}
+		if err != nil {
+			return h.renderChannels(c, name, "Cannot create channel: "+err.Error())
+		}
+		c.Cookie(&fiber.Cookie{
+			Name:     ownerCookie(ch.ChannelId.String()),
+			Value:    ch.OwnerKey,
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
 	}
 
 	// Redirect back to channel list page
-	return c.Render("channel", fiber.Map{
-		"Name":     name,
-		"Channels": h.ChannelManager.Manager.Channels,
+	return h.renderChannels(c, name, "")
+}
+
+// parseLocalTime parses a datetime-local form value, converting it to UTC with the browser's
+// getTimezoneOffset() (minutes behind UTC); an empty value gives the zero time
+func parseLocalTime(value, tzOffset string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02T15:04", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	offset, _ := strconv.Atoi(tzOffset)
+	return t.Add(time.Duration(offset) * time.Minute), nil
+}
+
+const ownerCookiePrefix = "owner_"
+
+func ownerCookie(channelId string) string {
+	return ownerCookiePrefix + channelId
+}
+
+func (h *Handler) DeleteChannelPage(c *fiber.Ctx) error {
+	name := c.FormValue("name")
+	room := c.FormValue("channel")
+
+	ch := h.ChannelManager.GetChannel(room)
+	if ch == nil {
+		return h.renderChannels(c, name, "Channel not found")
+	}
+
+	cookie := ownerCookie(ch.ChannelId.String())
+	if !h.ChannelManager.IsOwner(ch, c.Cookies(cookie)) {
+		return h.renderChannels(c, name, "Only the creator can delete channel "+room)
+	}
+
+	h.ChannelManager.DeleteChannel(room)
+	c.ClearCookie(cookie)
+	return h.renderChannels(c, name, "")
+}
+
+func (h *Handler) ArchivePage(c *fiber.Ctx) error {
+	room := c.Params("channel")
+	archives := h.ChannelManager.GetArchives(room)
+	if len(archives) == 0 {
+		return c.Status(fiber.StatusNotFound).SendString("No concluded debates for channel " + room)
+	}
+	return c.Render("archive", fiber.Map{
+		"Channel":  room,
+		"Archives": archives,
 	})
 }
 
@@ -54,39 +193,53 @@ func (h *Handler) JoinChannel(c *fiber.Ctx) error {
 	room := c.FormValue("channel")
 	password := c.FormValue("password")
 
+	if ok, msg := allow(c, h.Limits.Join); !ok {
+		return h.renderChannels(c, name, msg)
+	}
+
 	ch := h.ChannelManager.GetChannel(room)
 	if ch == nil {
-		return c.Render("channel", fiber.Map{
+		if h.ChannelManager.RemoteOwner(room) == "" {
+			return h.renderChannels(c, name, "Channel not found")
+		}
+		// Open on another instance, which checks the password when the socket connects
+		return c.Render("chat", fiber.Map{
 			"Name":     name,
-			"Channels": h.ChannelManager.Manager.Channels,
-			"Error":    "Channel not found",
+			"Channel":  room,
+			"CanSend":  true,
+			"Password": password,
 		})
 	}
-	
-	if password != ch.Password {
-		return c.Render("channel", fiber.Map{
-			"Name":     name,
-			"Channels": h.ChannelManager.Manager.Channels,
-			"Error":    "Invalid password for channel " + room,
-		})
+
+	if !h.ChannelManager.CheckPassword(ch, password) {
+		return h.renderChannels(c, name, "Invalid password for channel "+room)
 	}
 
 	return c.Render("chat", fiber.Map{
-		"Name":     name,
-		"Channel":  room,
-		"CanSend":  true,
-		"Password": password,
+		"Name":      name,
+		"Channel":   room,
+		"CanSend":   true,
+		"Password":  password,
+		"Moderator": h.ChannelManager.IsOwner(ch, c.Cookies(ownerCookie(ch.ChannelId.String()))),
 	})
 }
 
 func (h *Handler) WatchChannel(c *fiber.Ctx) error {
 	name := c.FormValue("name")
 	room := c.FormValue("channel")
-	fmt.Printf("WatchChannel called: name='%s', room='%s'\n", name, room)
+	slog.Debug("watching channel", "name", name, "channel", room)
+	if ok, msg := allow(c, h.Limits.Join); !ok {
+		return h.renderChannels(c, name, msg)
+	}
+	moderator := false
+	if ch := h.ChannelManager.GetChannel(room); ch != nil {
+		moderator = h.ChannelManager.IsOwner(ch, c.Cookies(ownerCookie(ch.ChannelId.String())))
+	}
 	return c.Render("chat", fiber.Map{
-		"Name":    name,
-		"Channel": room,
-		"CanSend": false,
+		"Name":      name,
+		"Channel":   room,
+		"CanSend":   false,
+		"Moderator": moderator,
 	})
 }
 
@@ -100,3 +253,44 @@ func (h *Handler) ChatPage(c *fiber.Ctx) error {
 		"Password": password,
 	})
 }
+
+const leaderboardSize = 100
+
+func (h *Handler) LeaderboardPage(c *fiber.Ctx) error {
+	showProvisional := c.Query("provisional") != "false"
+	board := h.Ratings.Leaderboard(0, leaderboardSize, showProvisional)
+	return c.Render("leaderboard", fiber.Map{
+		"Ratings":         board.Ratings,
+		"Total":           board.Total,
+		"ShowProvisional": showProvisional,
+		"ProvisionalRD":   services.ProvisionalRD,
+	})
+}
+
+// MatchmakingPage queues the player and shows the lobby, which redirects into the room once matched
+func (h *Handler) MatchmakingPage(c *fiber.Ctx) error {
+	name := c.FormValue("name")
+	if name == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("Name required")
+	}
+
+	var formats, topics []string
+	if format := c.FormValue("format"); format != "" {
+		formats = []string{format}
+	}
+	if topic := c.FormValue("topic"); topic != "" {
+		topics = []string{topic}
+	}
+	if ok, msg := allow(c, h.Limits.ChannelCreate); !ok {
+		return h.renderChannels(c, name, msg)
+	}
+	ticket, err := h.Matchmaking.Enqueue(name, session(c), formats, topics)
+	if err != nil {
+		return h.renderChannels(c, name, "Could not join the queue: "+err.Error())
+	}
+	return c.Render("lobby", fiber.Map{
+		"Name":   name,
+		"Ticket": ticket.Id,
+		"Rating": ticket.Rating,
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/latestcomment/go-websocket-chat/internal/services"
+	"github.com/latestcomment/go-websocket-chat/internal/store"
+)
+
+const (
+	// aiCheckInterval spaces out the provider pings so frequent probes do not hammer it
+	aiCheckInterval = 30 * time.Second
+	aiCheckTimeout  = 5 * time.Second
+)
+
+type HealthHandler struct {
+	Service *services.ChannelService
+	Store   *store.Store
+
+	mu        sync.Mutex
+	aiChecked time.Time
+	aiErr     error
+}
+
+func NewHealthHandler(service *services.ChannelService, st *store.Store) *HealthHandler {
+	return &HealthHandler{Service: service, Store: st}
+}
+
+type HealthStatus struct {
+	Status string            `json:"status"` // "ok" or "unavailable"
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Healthz reports that the process is up and serving requests
+func (h *HealthHandler) Healthz(c *fiber.Ctx) error {
+	return c.JSON(HealthStatus{Status: "ok"})
+}
+
+// Readyz reports whether this instance should receive traffic: it is not draining, the store is
+// writable and the AI provider is reachable
+func (h *HealthHandler) Readyz(c *fiber.Ctx) error {
+	checks := map[string]string{"store": "ok", "ai": "ok"}
+	ready := true
+	if h.Service.Draining() {
+		checks["shutdown"] = "draining"
+		ready = false
+	}
+	if err := h.Store.Check(); err != nil {
+		checks["store"] = err.Error()
+		ready = false
+	}
+	if err := h.checkAI(c.UserContext()); err != nil {
+		checks["ai"] = err.Error()
+		ready = false
+	}
+
+	if !ready {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(HealthStatus{Status: "unavailable", Checks: checks})
+	}
+	return c.JSON(HealthStatus{Status: "ok", Checks: checks})
+}
+
+// checkAI pings the provider at most once per aiCheckInterval, answering from the last ping in between
+func (h *HealthHandler) checkAI(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if time.Since(h.aiChecked) < aiCheckInterval {
+		return h.aiErr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, aiCheckTimeout)
+	defer cancel()
+	h.aiErr = h.Service.AI().Ping(ctx)
+	h.aiChecked = time.Now()
+	return h.aiErr
+}
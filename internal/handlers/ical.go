@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/services"
+)
+
+const (
+	icsTimeFormat = "20060102T150405Z"
+	icsUIDDomain  = "go-websocket-chat" // Keeps event UIDs stable whatever host the calendar was fetched from
+)
+
+// buildICS renders scheduled debates as an iCalendar (RFC 5545) document, one event each with an
+// alarm matching the in-app reminder
+func buildICS(schedules []models.ScheduledDebate) string {
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		b.WriteString(foldICSLine(fmt.Sprintf(format, args...)))
+		b.WriteString("\r\n")
+	}
+
+	now := time.Now().UTC().Format(icsTimeFormat)
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//go-websocket-chat//Debate Schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	for _, sd := range schedules {
+		status := "CONFIRMED"
+		if sd.Status == models.ScheduleCancelled {
+			status = "CANCELLED"
+		}
+		description := "Debate channel " + sd.Channel
+		if sd.Motion != "" {
+			description = "Motion: " + sd.Motion + "\n" + description
+		}
+		if sd.Owner != "" {
+			description += ", organised by " + sd.Owner
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:%s@%s", sd.ChannelId, icsUIDDomain)
+		line("DTSTAMP:%s", now)
+		line("DTSTART:%s", sd.StartsAt.UTC().Format(icsTimeFormat))
+		line("DTEND:%s", sd.StartsAt.Add(services.ScheduledDebateLength).UTC().Format(icsTimeFormat))
+		line("SUMMARY:%s", escapeICSText("Debate: "+sd.Channel))
+		line("DESCRIPTION:%s", escapeICSText(description))
+		line("STATUS:%s", status)
+		line("BEGIN:VALARM")
+		line("ACTION:DISPLAY")
+		line("DESCRIPTION:%s", escapeICSText("Debate "+sd.Channel+" starts soon"))
+		line("TRIGGER:-PT%dM", int(services.ScheduleReminderLead.Minutes()))
+		line("END:VALARM")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICSText escapes a TEXT value
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits a content line into 75-octet chunks without breaking UTF-8 sequences
+func foldICSLine(s string) string {
+	const limit = 75
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/config"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/ratelimit"
+	"github.com/latestcomment/go-websocket-chat/internal/services"
+)
+
+// ipKey is the local the client address is kept under for sockets, which cannot ask the request
+const ipKey = "ip"
+
+// sessionCookie holds the random key the server gives each browser. Per-user rate limits are kept
+// under it rather than the name typed into a form, which anyone could borrow to use up someone
+// else's allowance.
+const (
+	sessionCookie = "session"
+	sessionKey    = "session"
+)
+
+// Sessions gives every request a session key: the one in its session cookie, or a new one set on the
+// response when it has none
+func Sessions() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Cookies(sessionCookie)
+		if _, err := uuid.Parse(key); err != nil {
+			key = uuid.NewString()
+			c.Cookie(&fiber.Cookie{
+				Name:     sessionCookie,
+				Value:    key,
+				HTTPOnly: true,
+				SameSite: fiber.CookieSameSiteLaxMode,
+			})
+		}
+		c.Locals(sessionKey, key)
+		return c.Next()
+	}
+}
+
+// session returns the session key Sessions gave the request
+func session(c *fiber.Ctx) string {
+	key, _ := c.Locals(sessionKey).(string)
+	return key
+}
+
+// RateLimits are the per-IP and per-session request limits shared by the page, API and socket handlers
+type RateLimits struct {
+	ChannelCreate *ratelimit.Limiter
+	Join          *ratelimit.Limiter
+	Connect       *ratelimit.Limiter
+}
+
+func NewRateLimits(cfg config.Limits) *RateLimits {
+	return &RateLimits{
+		ChannelCreate: ratelimit.New(cfg.ChannelCreateRate.Count, cfg.ChannelCreateRate.Per),
+		Join:          ratelimit.New(cfg.JoinRate.Count, cfg.JoinRate.Per),
+		Connect:       ratelimit.New(cfg.ConnectRate.Count, cfg.ConnectRate.Per),
+	}
+}
+
+// allow takes a token for the request from limiter, keyed by its IP and session. When the limit is
+// hit it sets the 429 status and Retry-After header and returns the message to show.
+func allow(c *fiber.Ctx, limiter *ratelimit.Limiter) (bool, string) {
+	ok, wait := limiter.Allow(ratelimit.Keys(c.IP(), session(c))...)
+	if ok {
+		return true, ""
+	}
+	retry := ratelimit.RetryAfter(wait)
+	c.Status(fiber.StatusTooManyRequests)
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retry))
+	return false, fmt.Sprintf("Too many requests, please try again in %s", time.Duration(retry)*time.Second)
+}
+
+// allowSocket takes a connection token for an upgraded socket, keyed by the IP and session its
+// upgrade request had. When the limit is hit it sends the client an error event saying when to
+// retry and closes the socket.
+func allowSocket(c *websocket.Conn, limiter *ratelimit.Limiter, channel string, log *slog.Logger) bool {
+	ip, _ := c.Locals(ipKey).(string)
+	session, _ := c.Locals(sessionKey).(string)
+	ok, wait := limiter.Allow(ratelimit.Keys(ip, session)...)
+	if ok {
+		return true
+	}
+	log.Warn("socket rate limited", "ip", ip)
+	services.RejectSocket(c, channel, websocket.CloseTryAgainLater, models.ErrorData{
+		Code:              models.ErrorRateLimited,
+		Message:           "Too many connections, please try again later.",
+		RetryAfterSeconds: ratelimit.RetryAfter(wait),
+	})
+	return false
+}
+
+// LimitFrames caps the size of the frames handler's sockets read; a larger frame closes the socket
+// with 1009 (message too big)
+func LimitFrames(maxBytes int, handler func(*websocket.Conn)) func(*websocket.Conn) {
+	return func(c *websocket.Conn) {
+		c.SetReadLimit(int64(maxBytes))
+		handler(c)
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/latestcomment/go-websocket-chat/internal/services"
+	"github.com/latestcomment/go-websocket-chat/internal/socket"
+)
+
+type LobbyHandler struct {
+	Matchmaking *services.MatchmakingService
+	Limits      *RateLimits
+}
+
+func NewLobbyHandler(matchmaking *services.MatchmakingService, limits *RateLimits) *LobbyHandler {
+	return &LobbyHandler{Matchmaking: matchmaking, Limits: limits}
+}
+
+// HandleLobby keeps a queued player's socket open until they are matched or leave
+func (h *LobbyHandler) HandleLobby(c *websocket.Conn) {
+	defer func() {
+		_ = c.Close()
+	}()
+
+	ticket := c.Params("ticket")
+	if !allowSocket(c, h.Limits.Connect, "", slog.With("ticket", ticket)) {
+		return
+	}
+	out := socket.New(c)
+	defer out.Stop()
+	if !h.Matchmaking.AttachLobby(ticket, out) {
+		return // Unknown or cancelled ticket
+	}
+	defer h.Matchmaking.DetachLobby(ticket, out)
+
+	// The lobby only pushes; reading detects the client going away
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// APIRoute describes one JSON endpoint
+type APIRoute struct {
+	Method      string
+	Path        string // Fiber-style path relative to the API prefix, e.g. /channels/:channel
+	Summary     string
+	Params      []APIParam
+	Body        any    // Zero value of the request body type, nil if none
+	Status      int    // Success status, defaults to 200
+	Response    any    // Zero value of the response body type, nil if none
+	ContentType string // Media type of a non-JSON success body, documented as a plain string
+	Errors      []int  // Error statuses the endpoint can return
+	Handler     fiber.Handler
+}
+
+type APIParam struct {
+	Name        string
+	In          string // "path", "query" or "header"
+	Description string
+	Type        string // JSON schema type, defaults to "string"
+	Required    bool
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var uuidType = reflect.TypeOf(uuid.UUID{})
+
+// BuildOpenAPI generates an OpenAPI 3 document from the route table, deriving schemas from the Go types
+func BuildOpenAPI(routes []APIRoute, prefix string) fiber.Map {
+	schemas := fiber.Map{}
+	paths := fiber.Map{}
+
+	for _, route := range routes {
+		path := openAPIPath(route.Path)
+		item, ok := paths[path].(fiber.Map)
+		if !ok {
+			item = fiber.Map{}
+			paths[path] = item
+		}
+
+		op := fiber.Map{
+			"summary":     route.Summary,
+			"operationId": route.operationID(),
+		}
+
+		if len(route.Params) > 0 {
+			params := make([]fiber.Map, 0, len(route.Params))
+			for _, p := range route.Params {
+				typ := p.Type
+				if typ == "" {
+					typ = "string"
+				}
+				params = append(params, fiber.Map{
+					"name":        p.Name,
+					"in":          p.In,
+					"description": p.Description,
+					"required":    p.Required || p.In == "path",
+					"schema":      fiber.Map{"type": typ},
+				})
+			}
+			op["parameters"] = params
+		}
+
+		if route.Body != nil {
+			op["requestBody"] = fiber.Map{
+				"required": true,
+				"content": fiber.Map{
+					"application/json": fiber.Map{"schema": schemaRef(reflect.TypeOf(route.Body), schemas)},
+				},
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = fiber.StatusOK
+		}
+		success := fiber.Map{"description": http.StatusText(status)}
+		if route.ContentType != "" {
+			success["content"] = fiber.Map{
+				strings.SplitN(route.ContentType, ";", 2)[0]: fiber.Map{"schema": fiber.Map{"type": "string"}},
+			}
+		} else if route.Response != nil {
+			success["content"] = fiber.Map{
+				"application/json": fiber.Map{"schema": schemaRef(reflect.TypeOf(route.Response), schemas)},
+			}
+		}
+		responses := fiber.Map{strconv.Itoa(status): success}
+
+		errorSchema := schemaRef(reflect.TypeOf(APIError{}), schemas)
+		for _, code := range route.Errors {
+			responses[strconv.Itoa(code)] = fiber.Map{
+				"description": http.StatusText(code),
+				"content":     fiber.Map{"application/json": fiber.Map{"schema": errorSchema}},
+			}
+		}
+		op["responses"] = responses
+
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return fiber.Map{
+		"openapi": "3.0.3",
+		"info": fiber.Map{
+			"title":   "Debate Chat API",
+			"version": "v1",
+		},
+		"servers":    []fiber.Map{{"url": prefix}},
+		"paths":      paths,
+		"components": fiber.Map{"schemas": schemas},
+	}
+}
+
+// operationID derives an operation id from the method and path, e.g. get_channels_channel_state
+func (r APIRoute) operationID() string {
+	name := strings.ToLower(r.Method)
+	for _, part := range strings.Split(strings.Trim(r.Path, "/"), "/") {
+		part = strings.TrimPrefix(part, ":")
+		part = strings.NewReplacer("-", "_", ".", "_").Replace(part)
+		name += "_" + part
+	}
+	return name
+}
+
+// openAPIPath converts Fiber's :param segments to OpenAPI's {param}
+func openAPIPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "{" + strings.TrimSuffix(part[1:], "?") + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// schemaRef returns a schema for t, registering named struct types under components
+func schemaRef(t reflect.Type, schemas fiber.Map) fiber.Map {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return fiber.Map{"type": "string", "format": "date-time"}
+	case t == uuidType:
+		return fiber.Map{"type": "string", "format": "uuid"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return fiber.Map{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fiber.Map{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return fiber.Map{"type": "number"}
+	case reflect.String:
+		return fiber.Map{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return fiber.Map{"type": "array", "items": schemaRef(t.Elem(), schemas)}
+	case reflect.Map:
+		return fiber.Map{"type": "object", "additionalProperties": schemaRef(t.Elem(), schemas)}
+	case reflect.Struct:
+		name := t.Name()
+		if _, seen := schemas[name]; !seen {
+			schemas[name] = fiber.Map{} // Placeholder guards against recursive types
+			schemas[name] = structSchema(t, schemas)
+		}
+		return fiber.Map{"$ref": "#/components/schemas/" + name}
+	default:
+		return fiber.Map{}
+	}
+}
+
+func structSchema(t reflect.Type, schemas fiber.Map) fiber.Map {
+	properties := fiber.Map{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		// Embedded structs are flattened by encoding/json, so flatten their properties too
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			for name, schema := range structSchema(field.Type, schemas)["properties"].(fiber.Map) {
+				properties[name] = schema
+			}
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		properties[name] = schemaRef(field.Type, schemas)
+	}
+	return fiber.Map{"type": "object", "properties": properties}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/services"
+)
+
+type ReplayHandler struct {
+	Service *services.ChannelService
+	Limits  *RateLimits
+}
+
+func NewReplayHandler(service *services.ChannelService, limits *RateLimits) *ReplayHandler {
+	return &ReplayHandler{Service: service, Limits: limits}
+}
+
+// HandleReplay streams an archived round to the viewer and applies their playback commands
+func (h *ReplayHandler) HandleReplay(c *websocket.Conn) {
+	defer func() {
+		_ = c.Close()
+	}()
+
+	channel := c.Params("channel")
+	if !allowSocket(c, h.Limits.Connect, channel, slog.With("channel", channel, "replay", true)) {
+		return // Each replay runs a player of its own
+	}
+
+	round, _ := strconv.Atoi(c.Params("round"))
+	phase, _ := strconv.Atoi(c.Query("phase"))
+	speed, _ := strconv.ParseFloat(c.Query("speed", "1"), 64)
+	replay, err := h.Service.NewReplay(channel, round, phase, speed)
+	if err != nil {
+		c.WriteJSON(models.Message{
+			SenderType: "system",
+			SenderName: "system",
+			Text:       "❌ Cannot replay: " + err.Error(),
+			Timestamp:  time.Now(),
+		})
+		return
+	}
+
+	// Stop the player and wait for it before the connection is closed, since it writes to it
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		replay.Play(c, done)
+		close(stopped)
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	for {
+		_, data, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		replay.Command(string(data))
+	}
+}
+
+// ReplayPage shows an archived round in the chat view, played back over the replay socket
+func (h *Handler) ReplayPage(c *fiber.Ctx) error {
+	room := c.Params("channel")
+	round, _ := strconv.Atoi(c.Params("round"))
+	if len(h.ChannelManager.GetArchives(room)) == 0 {
+		return c.Status(fiber.StatusNotFound).SendString("No concluded debates for channel " + room)
+	}
+
+	speed := c.Query("speed", "1")
+	if _, err := strconv.ParseFloat(speed, 64); err != nil {
+		speed = "1"
+	}
+	return c.Render("chat", fiber.Map{
+		"Name":    c.Query("name", "viewer"),
+		"Channel": room,
+		"CanSend": false,
+		"Replay": fiber.Map{
+			"Round": round,
+			"Phase": c.QueryInt("phase"),
+			"Speed": speed,
+		},
+	})
+}
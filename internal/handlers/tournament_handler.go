@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+func organizerCookie(tournamentId string) string {
+	return "tournament_organizer_" + tournamentId
+}
+
+func playerCookie(tournamentId string) string {
+	return "tournament_player_" + tournamentId
+}
+
+func (h *Handler) TournamentsPage(c *fiber.Ctx) error {
+	return c.Render("tournaments", fiber.Map{
+		"Name":        c.Query("name"),
+		"Tournaments": h.Tournaments.List(),
+		"Formats":     tournamentFormats,
+		"Error":       c.Query("error"),
+	})
+}
+
+func (h *Handler) CreateTournamentPage(c *fiber.Ctx) error {
+	name := c.FormValue("name")
+	rounds, _ := strconv.Atoi(c.FormValue("rounds", "0"))
+	if c.FormValue("tournament") == "" {
+		return c.Redirect("/tournaments?name=" + url.QueryEscape(name) + "&error=" + url.QueryEscape("Tournament name required"))
+	}
+
+	t, err := h.Tournaments.Create(c.FormValue("tournament"), c.FormValue("format"), c.FormValue("motion"), name, rounds)
+	if err != nil {
+		return c.Redirect("/tournaments?name=" + url.QueryEscape(name) + "&error=" + url.QueryEscape(err.Error()))
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     organizerCookie(t.Id),
+		Value:    t.OrganizerKey,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return c.Redirect(tournamentURL(t.Id, name, ""))
+}
+
+// TournamentPage shows the bracket, with a way into the viewer's own live match if they are playing
+func (h *Handler) TournamentPage(c *fiber.Ctx) error {
+	id := c.Params("tournament")
+	t, ok := h.Tournaments.Get(id)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("Tournament not found")
+	}
+
+	data := fiber.Map{
+		"Name":       c.Query("name"),
+		"Tournament": t,
+		"Organizer":  h.Tournaments.IsOrganizer(id, c.Cookies(organizerCookie(id))),
+		"Error":      c.Query("error"),
+	}
+	key := c.Cookies(playerCookie(id))
+	if player, ok := h.Tournaments.PlayerFor(id, key); ok {
+		data["Player"] = player
+		if match, err := h.Tournaments.PlayerMatch(id, key); err == nil {
+			data["MyMatch"] = match
+		}
+	}
+	return c.Render("tournament", data)
+}
+
+func (h *Handler) RegisterTournamentPage(c *fiber.Ctx) error {
+	id := c.Params("tournament")
+	name := c.FormValue("name")
+	if name == "" {
+		return c.Redirect(tournamentURL(id, name, "Enter a name to register"))
+	}
+
+	key, err := h.Tournaments.Register(id, name)
+	if err != nil {
+		return c.Redirect(tournamentURL(id, name, err.Error()))
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     playerCookie(id),
+		Value:    key,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return c.Redirect(tournamentURL(id, name, ""))
+}
+
+func (h *Handler) StartTournamentPage(c *fiber.Ctx) error {
+	id := c.Params("tournament")
+	name := c.FormValue("name")
+	if ok, msg := allow(c, h.Limits.ChannelCreate); !ok {
+		return c.Redirect(tournamentURL(id, name, msg))
+	}
+	if _, err := h.Tournaments.Start(id, c.Cookies(organizerCookie(id))); err != nil {
+		return c.Redirect(tournamentURL(id, name, err.Error()))
+	}
+	return c.Redirect(tournamentURL(id, name, ""))
+}
+
+func (h *Handler) AwardMatchPage(c *fiber.Ctx) error {
+	id := c.Params("tournament")
+	name := c.FormValue("name")
+	if _, err := h.Tournaments.AwardMatch(id, c.Cookies(organizerCookie(id)), c.Params("match"), c.FormValue("winner")); err != nil {
+		return c.Redirect(tournamentURL(id, name, err.Error()))
+	}
+	return c.Redirect(tournamentURL(id, name, ""))
+}
+
+func tournamentURL(id, name, errMsg string) string {
+	query := url.Values{}
+	if name != "" {
+		query.Set("name", name)
+	}
+	if errMsg != "" {
+		query.Set("error", errMsg)
+	}
+	if len(query) == 0 {
+		return "/tournaments/" + id
+	}
+	return "/tournaments/" + id + "?" + query.Encode()
+}
+
+// tournamentFormats are the choices offered on the tournament list page
+var tournamentFormats = []struct{ Value, Label string }{
+	{models.TournamentSingleElimination, "Single elimination"},
+	{models.TournamentSwiss, "Swiss"},
+}
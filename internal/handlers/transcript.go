@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/services"
+)
+
+// Transcript export formats accepted by the format query parameter
+const (
+	TranscriptJSON     = "json"
+	TranscriptMarkdown = "markdown"
+	TranscriptHTML     = "html"
+)
+
+func (h *APIHandler) ExportTranscript(c *fiber.Ctx) error {
+	format := c.Query("format", TranscriptJSON)
+	if format != TranscriptJSON && format != TranscriptMarkdown && format != TranscriptHTML {
+		return apiError(c, fiber.StatusBadRequest, "invalid_format", "format must be json, markdown or html")
+	}
+	round := c.QueryInt("round")
+	if round < 0 {
+		return apiError(c, fiber.StatusBadRequest, "invalid_round", "round must be positive")
+	}
+
+	export, err := h.Service.ExportTranscript(c.Params("channel"), round)
+	switch err {
+	case nil:
+	case services.ErrRoundNotFound:
+		return apiError(c, fiber.StatusNotFound, "round_not_found", "No such round in this channel")
+	default:
+		return apiError(c, fiber.StatusNotFound, "transcript_not_found", "No debate recorded for this channel")
+	}
+
+	filename := fmt.Sprintf("%s-round-%d", export.Channel, export.Round)
+	switch format {
+	case TranscriptMarkdown:
+		c.Set(fiber.HeaderContentType, "text/markdown; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename+".md"))
+		return c.SendString(buildTranscriptMarkdown(export))
+	case TranscriptHTML:
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename+".html"))
+		return c.Render("transcript", fiber.Map{
+			"Export": export,
+			"Phases": services.TranscriptPhases(export.Messages),
+			"Result": transcriptResult(export),
+		})
+	default:
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		return c.JSON(export)
+	}
+}
+
+// transcriptResult describes the outcome of an exported round in one line
+func transcriptResult(export models.TranscriptExport) string {
+	switch {
+	case !export.Concluded:
+		return "In progress"
+	case export.Forfeit && export.Winner != "":
+		return export.Winner + " won by forfeit"
+	case export.Forfeit:
+		return "Forfeited, no winner"
+	case export.Winner != "":
+		return export.Winner + " won"
+	default:
+		return "No clear winner"
+	}
+}
+
+// buildTranscriptMarkdown renders a round as Markdown: a header with the motion and sides, one section
+// per phase with the debaters' statements and moderator analyses, and the judge's verdict
+func buildTranscriptMarkdown(export models.TranscriptExport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s, round %d\n\n", export.Channel, export.Round)
+	if export.Motion != "" {
+		fmt.Fprintf(&b, "**Motion:** %s\n\n", export.Motion)
+	}
+	for _, debater := range export.Participants {
+		side := export.Sides[debater]
+		if side == "" {
+			side = "debater"
+		}
+		fmt.Fprintf(&b, "- **%s**: %s\n", debater, side)
+	}
+	if len(export.Participants) > 0 {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "**Result:** %s", transcriptResult(export))
+	if export.ConcludedAt != nil {
+		fmt.Fprintf(&b, " (%s)", export.ConcludedAt.UTC().Format("Jan 2, 2006 15:04 MST"))
+	}
+	b.WriteString("\n")
+
+	for _, phase := range services.TranscriptPhases(export.Messages) {
+		if phase.Id == 0 {
+			b.WriteString("\n## Lobby\n\n")
+		} else {
+			fmt.Fprintf(&b, "\n## Phase %d: %s\n\n", phase.Id, phase.Title)
+		}
+		inList := false // System notices are list items; close the list before a heading
+		for _, msg := range phase.Messages {
+			at := msg.Timestamp.UTC().Format("15:04:05")
+			if msg.SenderType == "user" || msg.SenderType == "ai" {
+				if inList {
+					b.WriteString("\n")
+					inList = false
+				}
+			}
+			switch msg.SenderType {
+			case "user":
+				fmt.Fprintf(&b, "### %s (%s)\n\n%s\n\n", msg.SenderName, at, markdownQuote(msg.Text))
+			case "ai":
+				fmt.Fprintf(&b, "### 🤖 Moderator analysis (%s)\n\n%s\n\n", at, markdownQuote(msg.Text))
+			default:
+				fmt.Fprintf(&b, "- _%s_ %s\n", at, strings.ReplaceAll(msg.Text, "\n", " "))
+				inList = true
+			}
+		}
+	}
+
+	if v := export.Verdict; v != nil {
+		b.WriteString("\n## ⚖️ Verdict\n")
+		for _, section := range []struct{ title, text string }{
+			{"Winner Declaration", v.WinnerDeclaration},
+			{"Argument Analysis", v.ArgumentAnalysis},
+			{"Debate Performance", v.DebatePerformance},
+			{"Evidence & Logic", v.EvidenceLogic},
+			{"Persuasiveness", v.Persuasiveness},
+			{"Key Turning Points", v.KeyTurningPoints},
+			{"Final Score", v.FinalScore},
+		} {
+			if section.text != "" {
+				fmt.Fprintf(&b, "\n### %s\n\n%s\n", section.title, section.text)
+			}
+		}
+		if a := v.Audience; a != nil {
+			fmt.Fprintf(&b, "\n### Audience\n\nFor the motion: %.0f%% before, %.0f%% after", a.PreForPercent, a.PostForPercent)
+			if a.Swing != nil {
+				fmt.Fprintf(&b, " (%+.0f points)", *a.Swing)
+			}
+			b.WriteString(".\n")
+		}
+		if e := v.Engagement; e != nil && e.TotalReactions > 0 {
+			fmt.Fprintf(&b, "\n### Engagement\n\n%d reactions to the debaters' messages.\n", e.TotalReactions)
+		}
+	}
+	return b.String()
+}
+
+// markdownQuote renders text as a block quote, keeping its line breaks
+func markdownQuote(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import "github.com/gofiber/fiber/v2"
+
+// BindWebSocketBase makes {{.WSBase}} available to every template: base, the ws:// or wss:// form of
+// the configured public URL, or when that is empty the scheme and host the request arrived on
+func BindWebSocketBase(base string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		wsBase := base
+		if wsBase == "" {
+			scheme := "ws"
+			if c.Protocol() == "https" {
+				scheme = "wss"
+			}
+			wsBase = scheme + "://" + string(c.Request().Host())
+		}
+		if err := c.Bind(fiber.Map{"WSBase": wsBase}); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
@@ -1,23 +1,46 @@
 package handlers
 
 import (
+	"context"
+	"log/slog"
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
 	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/logging"
 	"github.com/latestcomment/go-websocket-chat/internal/models"
 	"github.com/latestcomment/go-websocket-chat/internal/services"
+	"github.com/latestcomment/go-websocket-chat/internal/socket"
+	"github.com/latestcomment/go-websocket-chat/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type WebSocketHandler struct {
 	Service *services.ChannelService
+	Limits  *RateLimits
 }
 
-func NewWebSocketHandler(service *services.ChannelService) *WebSocketHandler {
-	return &WebSocketHandler{Service: service}
+func NewWebSocketHandler(service *services.ChannelService, limits *RateLimits) *WebSocketHandler {
+	return &WebSocketHandler{Service: service, Limits: limits}
 }
 
 func (h *WebSocketHandler) WebSocketMiddleware(c *fiber.Ctx) error {
+	if h.Service.Draining() {
+		// Shutting down; clients retry once the restarted server is up
+		return fiber.ErrServiceUnavailable
+	}
 	if websocket.IsWebSocketUpgrade(c) {
+		// Owner cookies are named after the channel id, which an instance relaying the socket does not know
+		var ownerKeys []string
+		c.Request().Header.VisitAllCookie(func(key, value []byte) {
+			if strings.HasPrefix(string(key), ownerCookiePrefix) {
+				ownerKeys = append(ownerKeys, string(value))
+			}
+		})
+		c.Locals("ownerKeys", ownerKeys)
+		c.Locals(ipKey, c.IP())
 		return c.Next()
 	}
 	return fiber.ErrUpgradeRequired
@@ -35,27 +58,47 @@ func (h *WebSocketHandler) HandleWebSocket(c *websocket.Conn) {
 	if name == "" {
 		name = "Guest"
 	}
+	requestID, _ := c.Locals(logging.RequestIDKey).(string)
+	ip, _ := c.Locals(ipKey).(string)
+	session, _ := c.Locals(sessionKey).(string)
+	log := slog.With("channel", channelName, "request_id", requestID)
+
+	// Refused after the upgrade rather than before, so the client gets an error event saying why
+	if !allowSocket(c, h.Limits.Connect, channelName, log.With("client", name)) {
+		return
+	}
 
 	// Get channel
 	ch := h.Service.GetChannel(channelName)
 	if ch == nil {
+		// The channel may be open on another instance, which then serves the socket through this one
+		if h.Service.RemoteOwner(channelName) != "" {
+			ownerKeys, _ := c.Locals("ownerKeys").([]string)
+			if key := c.Query("owner"); key != "" {
+				ownerKeys = append(ownerKeys, key)
+			}
+			log.Info("relaying socket to channel owner", "client", name)
+			h.Service.Relay(channelName, c, name, password, ip, session, ownerKeys)
+		}
 		return // Channel doesn't exist
 	}
 
 	// Register client
 	client := &models.Client{
-		Id:   uuid.New(),
-		Name: name,
-		Conn: c,
+		Id:      uuid.New(),
+		Name:    name,
+		IP:      ip,
+		Session: session,
 	}
 	
 	// Determine client permissions
 	if password != "" {
 		// User is trying to join with a password - validate it
-		if password == ch.Password {
+		if h.Service.CheckPassword(ch, password) {
 			client.CanSend = true
 		} else {
 			// Invalid password for join attempt - disconnect
+			log.Warn("client rejected, wrong password", "client", name)
 			return
 		}
 	} else {
@@ -63,7 +106,40 @@ func (h *WebSocketHandler) HandleWebSocket(c *websocket.Conn) {
 		client.CanSend = false
 	}
 	
-	h.Service.AddClient(ch, client)
-	h.Service.LoopMessages(ch, c, client)
+	// The owner moderates, identified by the cookie set on creation or an owner key query parameter
+	client.IsModerator = h.Service.IsOwner(ch, c.Cookies(ownerCookie(ch.ChannelId.String()))) ||
+		h.Service.IsOwner(ch, c.Query("owner"))
+
+	role := "spectator"
+	if client.CanSend {
+		role = "debater"
+	}
+	log = log.With("channel_id", ch.ChannelId.String(), "client_id", client.Id.String())
+	log.Info("client connected", "client", name, "role", role, "moderator", client.IsModerator)
+
+	// The connection's span is a child of the upgrade request's and parents each inbound frame
+	ctx, _ := c.Locals(tracing.ContextKey).(context.Context)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := tracing.Tracer.Start(ctx, "websocket.connection", trace.WithAttributes(
+		attribute.String("channel.name", ch.Name),
+		attribute.String("channel.id", ch.ChannelId.String()),
+		attribute.String("client.id", client.Id.String()),
+		attribute.String("client.role", role),
+	))
+	defer span.End()
+
+	// From here on the socket is only written by its writer, which must stop before the handler returns
+	client.Socket = socket.New(c)
+	defer client.Socket.Stop()
+
+	if err := h.Service.AddClient(ch, client); err != nil {
+		log.Warn("client rejected, channel full", "client", name)
+		services.RejectClient(client.Socket, ch.Name, websocket.CloseTryAgainLater, h.Service.ChannelFullError())
+		return
+	}
+	h.Service.LoopMessages(ctx, ch, c, client)
 	h.Service.RemoveClient(ch, client)
+	log.Info("client disconnected")
 }
@@ -0,0 +1,101 @@
+// Package logging sets up the structured logger every package writes to through log/slog
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/latestcomment/go-websocket-chat/internal/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// Redacted replaces the value of sensitive attributes
+	Redacted = "[redacted]"
+	// RequestIDKey is the local the request id is kept under, which upgraded sockets inherit
+	RequestIDKey = "requestid"
+)
+
+// Attribute keys whose values are redacted unless content logging is enabled
+var sensitive = map[string]bool{
+	"text":     true, // Chat message bodies
+	"password": true,
+}
+
+// New returns a logger writing to w at the configured level and format
+func New(w io.Writer, cfg config.Logging) *slog.Logger {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.Level)) // Checked by config.Validate
+
+	opts := &slog.HandlerOptions{Level: level}
+	if !cfg.Content {
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if sensitive[a.Key] {
+				return slog.String(a.Key, Redacted)
+			}
+			return a
+		}
+	}
+	if cfg.Format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Setup makes the configured logger the default, which the standard log package then writes through too
+func Setup(w io.Writer, cfg config.Logging) {
+	slog.SetDefault(New(w, cfg))
+}
+
+// RequestIDs assigns each request an id, or keeps the X-Request-ID it arrived with
+func RequestIDs() fiber.Handler {
+	return requestid.New(requestid.Config{ContextKey: RequestIDKey})
+}
+
+// Middleware logs each request once handled, in place of Fiber's logger; it must come after
+// RequestIDs, and after tracing.Middleware for the trace id to be logged. Passwords in route
+// parameters are redacted from the logged path like any other.
+func Middleware(cfg config.Logging) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		path := c.Path()
+		if password := c.Params("password"); password != "" && !cfg.Content {
+			path = strings.Replace(path, "/"+password, "/"+Redacted, 1)
+		}
+		id, _ := c.Locals(RequestIDKey).(string)
+		attrs := []slog.Attr{
+			slog.String("request_id", id),
+			slog.String("method", c.Method()),
+			slog.String("path", path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("ip", c.IP()),
+		}
+		if sc := trace.SpanContextFromContext(c.UserContext()); sc.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("err", err.Error()))
+		}
+		slog.LogAttrs(c.UserContext(), level, "request", attrs...)
+		return err
+	}
+}
@@ -0,0 +1,66 @@
+// Package metrics holds the Prometheus metrics the server exposes on /metrics
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric below along with the Go runtime and process collectors
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+var (
+	MessagesBroadcast = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "debate_messages_total",
+		Help: "Messages broadcast to channels, by sender type",
+	}, []string{"sender_type"})
+
+	BroadcastSeconds = factory.NewHistogram(prometheus.HistogramOpts{
+		Name:    "debate_broadcast_duration_seconds",
+		Help:    "Time taken to record a message and write it to every client of its channel",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 14),
+	})
+
+	AIRequestSeconds = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "debate_ai_request_duration_seconds",
+		Help:    "Latency of AI provider requests, by model and debate phase",
+		Buckets: []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"model", "phase"})
+
+	AIRequestErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "debate_ai_request_errors_total",
+		Help: "AI provider requests that failed or returned no answer, by model and debate phase",
+	}, []string{"model", "phase"})
+
+	DebatesCompleted = factory.NewCounter(prometheus.CounterOpts{
+		Name: "debate_debates_completed_total",
+		Help: "Debate rounds concluded and archived",
+	})
+)
+
+// Descriptions of the gauges read from the live channels at scrape time
+var (
+	ActiveChannels = prometheus.NewDesc("debate_active_channels",
+		"Channels owned by this instance", nil, nil)
+	ConnectedClients = prometheus.NewDesc("debate_connected_clients",
+		"Clients connected to the channels owned by this instance, by role", []string{"role"}, nil)
+	QueueDepth = prometheus.NewDesc("debate_channel_queue_depth",
+		"Commands, including message writes, waiting for channel actors, summed over channels", nil, nil)
+	QueueDepthMax = prometheus.NewDesc("debate_channel_queue_depth_max",
+		"Commands waiting for the busiest channel actor", nil, nil)
+)
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
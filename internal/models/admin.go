@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// AdminChannel is a channel as listed in the admin console
+type AdminChannel struct {
+	ChannelSummary
+	ClientCount int     `json:"clientCount"`
+	AIUsage     AIUsage `json:"aiUsage"`
+	AgeSeconds  int     `json:"ageSeconds"`
+}
+
+// Age is how long ago the channel was created, to the second
+func (c AdminChannel) Age() time.Duration {
+	return time.Duration(c.AgeSeconds) * time.Second
+}
+
+// AdminChannelDetail is the live state of one channel for the admin console
+type AdminChannelDetail struct {
+	AdminChannel
+	State          ChannelState `json:"state"`
+	EventCount     int          `json:"eventCount"`
+	QueuedCommands int          `json:"queuedCommands"` // Commands waiting for the channel's actor
+	RecentMessages []Message    `json:"recentMessages"` // Oldest first
+}
+
+// Admin actions recorded in the audit log
+const (
+	AuditForceEnd   = "force_end"
+	AuditAnnounce   = "announce"
+	AuditDisconnect = "disconnect"
+	AuditPurge      = "purge"
+)
+
+// AuditEntry records one action taken in the admin console
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Admin     string    `json:"admin"`
+	IP        string    `json:"ip"`
+	RequestId string    `json:"requestId,omitempty"`
+	Action    string    `json:"action"`
+	Channel   string    `json:"channel,omitempty"` // Empty for actions on every channel
+	Client    string    `json:"client,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	Error     string    `json:"error,omitempty"` // Why the action failed, empty when it succeeded
+}
+
+// AuditLog is what the audit service persists
+type AuditLog struct {
+	Entries []AuditEntry `json:"entries"` // Oldest first
+}
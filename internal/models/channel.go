@@ -5,29 +5,145 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/actor"
 )
 
 type Channel struct {
-	ChannelId              uuid.UUID
-	Name                   string
-	Password               string
-	Clients                map[uuid.UUID]*Client
-	Messages               []Message
-	PendingMessages        []Message                   // Messages waiting to be revealed simultaneously
-	ClientCount            int
-	Phase                  Phase
-	PhaseParticipants      map[string]map[string]bool // Track which participants contributed in each phase
-	Mu                     sync.Mutex
+	ChannelId         uuid.UUID
+	Name              string
+	Password          string
+	Motion            string // The proposition being debated
+	Owner             string // Name of the creator
+	OwnerKey          string // Secret handed to the creator, required to delete the channel
+	Status            string // "open" or "archived"
+	Round             int    // Current round of the series, starting at 1
+	Series            Series
+	Sides             map[string]string // Debater name -> SideProposition or SideOpposition
+	Identities        map[string]string // Debater name -> identity of the browser that engaged under it
+	RematchRequests   map[string]bool   // Debaters who asked for a rematch since the last verdict
+	RematchSwap       bool              // Swap sides when the rematch starts
+	Pause             *Pause            // Set while the debate clock is stopped
+	TimeoutsUsed      map[string]int    // Timeouts called per debater this round
+	PendingExtension  *ExtensionRequest // Extension awaiting the opponent's answer
+	Votes             AudienceVotes     // Spectator votes for the current round
+	SpectatorChat     []Message         // Spectator side-chat, kept apart from the debate floor and never sent to the AI
+	Muted             map[string]bool   // Spectators the moderator has muted in the side-chat
+	AIUsage           AIUsage           // AI requests made for the channel's analyses and verdicts
+	StartsAt          time.Time         // Scheduled start, zero when the debate begins as soon as both debaters engage
+	CreatedAt         time.Time
+	LastActivity      time.Time
+	Clients           map[uuid.UUID]*Client
+	Messages          []Message
+	PendingMessages   []Message // Messages waiting to be revealed simultaneously
+	ClientCount       int
+	Phase             Phase
+	PhaseParticipants map[string]map[string]bool // Track which participants contributed in each phase
+	Events            []ChannelEvent             // Append-only log of the state changes above
+	Actor             *actor.Actor               // Owns the fields above; only the identity, password and owner key may be read elsewhere
+}
+
+const (
+	ChannelOpen     = "open"
+	ChannelArchived = "archived" // Debate concluded; transcript and verdict are read-only
+)
+
+const (
+	SideProposition = "proposition"
+	SideOpposition  = "opposition"
+)
+
+// Series tracks a best-of-N match played as consecutive rounds in one channel
+type Series struct {
+	BestOf int            `json:"bestOf"`
+	Scores map[string]int `json:"scores"` // Round wins by debater name
+	Winner string         `json:"winner,omitempty"`
+}
+
+// WinsNeeded is the number of round wins that decides the series
+func (s Series) WinsNeeded() int {
+	return s.BestOf/2 + 1
+}
+
+// AIUsage counts AI requests and the tokens the provider reported for them
+type AIUsage struct {
+	Requests         int `json:"requests"`
+	Errors           int `json:"errors"` // Requests that failed or returned no answer
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+}
+
+// Add accumulates other into u
+func (u *AIUsage) Add(other AIUsage) {
+	u.Requests += other.Requests
+	u.Errors += other.Errors
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+}
+
+// ChannelOptions are the settings a channel is created with
+type ChannelOptions struct {
+	Name     string
+	Password string
+	Motion   string
+	Owner    string
+	BestOf   int       // Rounds in the series, defaults to 1
+	StartsAt time.Time // Scheduled start, zero for none
+
+	// Set when re-creating a persisted channel after a restart so links and owner cookies keep working
+	Id       uuid.UUID
+	OwnerKey string
 }
 
 type Phase struct {
-	Id        int
-	Name      string
-	StartTime time.Time
-	Duration  time.Duration
+	Id          int
+	Name        string
+	StartTime   time.Time
+	Duration    time.Duration
+	Extension   time.Duration // Extra time granted by accepted extension requests
+	PausedAt    time.Time     // Zero unless the clock is currently stopped
+	PausedTotal time.Duration // Time spent paused in completed pauses
+}
+
+// Pause records who stopped the clock and why
+type Pause struct {
+	By          string      `json:"by"`
+	Reason      string      `json:"reason"` // PauseTimeout or PauseModerator
+	Since       time.Time   `json:"since"`
+	AutoResume  time.Time   `json:"autoResume"` // When a timeout ends on its own, zero for moderator pauses
+	ResumeTimer *time.Timer `json:"-"`
+}
+
+const (
+	PauseTimeout   = "timeout"
+	PauseModerator = "moderator"
+)
+
+// ExtensionRequest is a debater's request for more time in the current phase
+type ExtensionRequest struct {
+	By     string
+	Phase  int
+	Amount time.Duration
+}
+
+// Archive is the read-only record of a concluded debate, kept after the live channel is freed
+type Archive struct {
+	ChannelId    uuid.UUID         `json:"channelId"`
+	ChannelName  string            `json:"channelName"`
+	Round        int               `json:"round"`
+	Motion       string            `json:"motion"`
+	Participants []string          `json:"participants"`
+	Sides        map[string]string `json:"sides"`
+	Winner       string            `json:"winner,omitempty"` // Empty when the verdict named no clear winner
+	Messages     []Message         `json:"messages"`
+	Verdict      *JudgeReport      `json:"verdict,omitempty"`
+	Forfeit      bool              `json:"forfeit,omitempty"` // Decided by a no-show at the scheduled start, not by a verdict
+	Identities   map[string]string `json:"-"`                 // Debater name -> browser identity, for rating the debate
+	CreatedAt    time.Time         `json:"createdAt"`
+	ConcludedAt  time.Time         `json:"concludedAt"`
 }
 
 type ChannelManager struct {
 	Channels map[string]*Channel
+	Archives map[string][]*Archive // Concluded debates of the channel last open under each name, oldest first
 	Mu       sync.Mutex
 }
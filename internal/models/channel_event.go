@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Channel event types. Changes to a channel's debate state are recorded as events in the channel's
+// log and applied by a single reducer, so the state can be rebuilt by replaying the log.
+const (
+	ChannelCreated = "channel_created"
+	ClientJoined   = "client_joined"
+	ClientLeft     = "client_left"
+	SidesAssigned  = "sides_assigned" // Sides set by the server before anyone engages
+	Engaged        = "engaged"
+	MessagePosted  = "message_posted" // Added to the debate floor
+	Submitted      = "submitted"      // Held back until everyone has submitted in the phase
+	PhaseReleased  = "phase_released"
+	PhaseAdvanced  = "phase_advanced"
+	Verdict        = "verdict" // The round is over, decided by the judge or by a forfeit
+	RematchStarted = "rematch_started"
+
+	Paused             = "paused" // The clock stopped for a timeout or by the moderator
+	Resumed            = "resumed"
+	ExtensionRequested = "extension_requested"
+	ExtensionGranted   = "extension_granted"
+	ExtensionDeclined  = "extension_declined"
+	RematchRequested   = "rematch_requested"
+	VoteCast           = "vote_cast"
+	ReactionToggled    = "reaction_toggled"
+	SpectatorChatted   = "spectator_chatted"
+	SpectatorMuted     = "spectator_muted"
+	SpectatorUnmuted   = "spectator_unmuted"
+	AIUsageRecorded    = "ai_usage_recorded"
+	ServerRestarted    = "server_restarted" // Restored from a checkpoint; clocks move on by the downtime
+)
+
+// Ballots a VoteCast event records
+const (
+	BallotPre   = "pre"   // Stance on the motion before the debate
+	BallotPost  = "post"  // Stance on the motion after the closing statements
+	BallotPhase = "phase" // Who won a phase
+)
+
+// ChannelEvent is one entry of a channel's event log; only the fields its type uses are set
+type ChannelEvent struct {
+	Seq       int       `json:"seq"`
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+
+	ChannelId uuid.UUID `json:"channelId,omitzero"`
+	Motion    string    `json:"motion,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	BestOf    int       `json:"bestOf,omitempty"`
+	StartsAt  time.Time `json:"startsAt,omitzero"`
+
+	Client    uuid.UUID         `json:"client,omitzero"`
+	Name      string            `json:"name,omitempty"`
+	Identity  string            `json:"identity,omitempty"` // Browser identity of an engaging debater; withheld from the event log API
+	CanSend   bool              `json:"canSend,omitempty"`
+	Moderator bool              `json:"moderator,omitempty"`
+	Side      string            `json:"side,omitempty"`
+	Sides     map[string]string `json:"sides,omitempty"`
+
+	Phase           int      `json:"phase,omitempty"`
+	DurationSeconds int      `json:"durationSeconds,omitempty"`
+	Message         *Message `json:"message,omitempty"`
+	Winner          string   `json:"winner,omitempty"`
+	Forfeit         bool     `json:"forfeit,omitempty"`
+	Swap            bool     `json:"swap,omitempty"`
+	Reason          string   `json:"reason,omitempty"` // PauseTimeout or PauseModerator
+
+	Ballot    string `json:"ballot,omitempty"`
+	Choice    string `json:"choice,omitempty"` // VoteFor or VoteAgainst, or the debater who won the phase
+	MessageId string `json:"messageId,omitempty"`
+	Reaction  string `json:"reaction,omitempty"`
+
+	Usage    *AIUsage      `json:"usage,omitempty"`
+	Downtime time.Duration `json:"downtime,omitempty"`
+
+	Live *Client `json:"-"` // Connected client a ClientJoined event is recorded for; nil when a log is replayed
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Checkpoint is the live state saved on shutdown so debates resume after a restart
+type Checkpoint struct {
+	SavedAt  time.Time             `json:"savedAt"`
+	Channels []ChannelCheckpoint   `json:"channels"`
+	Archives map[string][]*Archive `json:"archives"`
+}
+
+// ChannelCheckpoint is a channel's event log plus the secrets kept out of it
+type ChannelCheckpoint struct {
+	Events   []ChannelEvent `json:"events"`
+	Password string         `json:"password"`
+	OwnerKey string         `json:"ownerKey"`
+}
@@ -1,14 +1,22 @@
 package models
 
 import (
-	"github.com/gofiber/websocket/v2"
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/socket"
 )
 
 type Client struct {
-	Id      uuid.UUID       `json:"clientid"`
-	Name    string          `json:"clientname"`
-	Conn    *websocket.Conn `json:"-"`
-	CanSend bool
-	Ready   bool            `json:"ready"` // Track if client is ready to engage
+	Id          uuid.UUID      `json:"clientid"`
+	Name        string         `json:"clientname"`
+	Socket      *socket.Writer `json:"-"` // Writes to the client's socket when it is held here
+	CanSend     bool
+	Ready       bool        `json:"ready"`     // Track if client is ready to engage
+	IsModerator bool        `json:"moderator"` // Channel owner, may pause and resume the debate
+	ChatSent    []time.Time `json:"-"`         // Recent side-chat sends, for rate limiting
+	Instance    string      `json:"-"`         // Instance holding the socket when it is relayed, empty for local sockets
+	IP          string      `json:"-"`         // Address the socket connected from, for rate limits
+	Session     string      `json:"-"`         // Session key of the browser, for rate limits
+	LimitedAt   time.Time   `json:"-"`         // When the client was last told it is sending too fast
 }
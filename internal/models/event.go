@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// Event is a structured state update pushed to clients alongside the chat messages.
+// Unlike Message it has a Type and is never stored in the channel history.
+type Event struct {
+	Type      string    `json:"type"`
+	Channel   string    `json:"channel"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+const (
+	EventPhaseChanged     = "phase_changed"
+	EventReadinessChanged = "readiness_changed"
+	EventSubmissionStatus = "submission_status"
+	EventVoteTally        = "vote_tally"
+	EventReactionUpdated  = "reaction_updated"
+	EventDebateReminder   = "debate_reminder"
+	EventReplayStatus     = "replay_status" // Sent on replay sockets only
+	EventQueueStatus      = "queue_status"  // Sent on the matchmaking lobby socket
+	EventMatchFound       = "match_found"
+	EventError            = "error" // Sent to the one client whose request was refused
+)
+
+// Error event codes
+const (
+	ErrorRateLimited = "rate_limited"
+	ErrorChannelFull = "channel_full"
+)
+
+// ErrorData says why a client's request was refused and, for rate limits, when to try again
+type ErrorData struct {
+	Code              string `json:"code"`
+	Message           string `json:"message"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty"`
+}
+
+// PhaseChangedData is sent whenever the phase or its clock changes (start, pause, resume, extension)
+type PhaseChangedData struct {
+	Id               int        `json:"id"`
+	Name             string     `json:"name"`
+	Title            string     `json:"title"` // e.g. "Opening Statements"
+	Status           string     `json:"status"`
+	Round            int        `json:"round"`
+	StartTime        *time.Time `json:"startTime,omitempty"`
+	Deadline         *time.Time `json:"deadline,omitempty"` // Omitted for untimed phases and while paused
+	DurationSeconds  int        `json:"durationSeconds"`
+	RemainingSeconds int        `json:"remainingSeconds"`
+	Paused           bool       `json:"paused"`
+	AllowedSpeakers  []string   `json:"allowedSpeakers"`
+	StartsAt         *time.Time `json:"startsAt,omitempty"` // Scheduled start, sent while the lobby counts down
+}
+
+type ReadinessChangedData struct {
+	Debaters   []DebaterReadiness `json:"debaters"`
+	ReadyCount int                `json:"readyCount"`
+	Required   int                `json:"required"`
+}
+
+type DebaterReadiness struct {
+	Name  string `json:"name"`
+	Side  string `json:"side,omitempty"`
+	Ready bool   `json:"ready"`
+}
+
+// SubmissionStatusData says who has submitted in the current phase without revealing what
+type SubmissionStatusData struct {
+	Phase     int      `json:"phase"`
+	Submitted []string `json:"submitted"`
+	Waiting   []string `json:"waiting"`
+}
+
+// ReactionUpdatedData carries the new reaction counts of one message
+type ReactionUpdatedData struct {
+	MessageId string         `json:"messageId"`
+	Counts    map[string]int `json:"counts"`
+}
+
+// DebateReminderData is sent shortly before a scheduled debate starts
+type DebateReminderData struct {
+	StartsAt    time.Time `json:"startsAt"`
+	SecondsLeft int       `json:"secondsLeft"`
+	Ready       []string  `json:"ready"`   // Debaters present and ready
+	Waiting     []string  `json:"waiting"` // Expected debaters not yet present or not ready
+}
+
+// ReplayStatusData reports the playback state of a replayed debate
+type ReplayStatusData struct {
+	Playing  bool    `json:"playing"`
+	Speed    float64 `json:"speed"`
+	Phase    int     `json:"phase"`
+	Position int     `json:"position"` // Messages sent so far
+	Total    int     `json:"total"`
+	Phases   []int   `json:"phases"` // Phases present in the recording, for seeking
+	Finished bool    `json:"finished"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+const (
+	FormatSingle  = "single" // One round
+	FormatBestOf3 = "bo3"    // Best of three rounds
+)
+
+// MatchFormats maps each format players can queue for to its series length
+var MatchFormats = map[string]int{
+	FormatSingle:  1,
+	FormatBestOf3: 3,
+}
+
+const (
+	TicketQueued    = "queued"
+	TicketMatched   = "matched"
+	TicketCancelled = "cancelled"
+)
+
+// MatchTicket is a player's place in the matchmaking queue
+type MatchTicket struct {
+	Id       string      `json:"id"`
+	Player   string      `json:"player"`
+	Identity string      `json:"-"` // Browser that queued; a new ticket from it replaces this one
+	Rating   float64     `json:"rating"`
+	Formats  []string    `json:"formats"` // Acceptable formats, any if empty
+	Topics   []string    `json:"topics"`  // Acceptable motion topics, any if empty
+	Status   string      `json:"status"`
+	JoinedAt time.Time   `json:"joinedAt"`
+	Match    *MatchFound `json:"match,omitempty"` // Set once the player has been paired
+}
+
+// MatchFound tells a player where their debate is and which side they argue
+type MatchFound struct {
+	Channel        string  `json:"channel"`
+	Password       string  `json:"password"`
+	Motion         string  `json:"motion"`
+	Topic          string  `json:"topic"`
+	Format         string  `json:"format"`
+	BestOf         int     `json:"bestOf"`
+	Side           string  `json:"side"`
+	Opponent       string  `json:"opponent"`
+	OpponentRating float64 `json:"opponentRating"`
+}
+
+// QueueStatusData keeps a waiting player informed while the matcher searches
+type QueueStatusData struct {
+	Ticket       string  `json:"ticket"`
+	Position     int     `json:"position"` // 1 for the longest-waiting player
+	QueueSize    int     `json:"queueSize"`
+	WaitSeconds  int     `json:"waitSeconds"`
+	RatingWindow float64 `json:"ratingWindow"` // How far apart in rating an opponent may currently be
+}
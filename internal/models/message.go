@@ -3,19 +3,25 @@ package models
 import "time"
 
 type Message struct {
-	SenderType string       `json:"senderType"` // "user", "system", "ai", "judge"
-	SenderName string       `json:"sender"`     // username or "system" or "AI Moderator" or "AI Judge"
-	Text       string       `json:"text"`
-	Timestamp  time.Time    `json:"timestamp"`
-	JudgeData  *JudgeReport `json:"judgeData,omitempty"` // Structured judge report
+	Id         string              `json:"id,omitempty"`
+	SenderType string              `json:"senderType"` // "user", "system", "ai", "judge", "spectator"
+	SenderName string              `json:"sender"`     // username or "system" or "AI Moderator" or "AI Judge"
+	Text       string              `json:"text"`
+	Timestamp  time.Time           `json:"timestamp"`
+	Phase      int                 `json:"phase"`               // Debate phase the message was sent in, 0 for the lobby
+	JudgeData  *JudgeReport        `json:"judgeData,omitempty"` // Structured judge report
+	Reactions  map[string][]string `json:"reactions,omitempty"` // Reaction -> names of those who reacted
 }
 
 type JudgeReport struct {
-	WinnerDeclaration string `json:"winnerDeclaration"`
-	ArgumentAnalysis  string `json:"argumentAnalysis"`
-	DebatePerformance string `json:"debatePerformance"`
-	EvidenceLogic     string `json:"evidenceLogic"`
-	Persuasiveness    string `json:"persuasiveness"`
-	KeyTurningPoints  string `json:"keyTurningPoints"`
-	FinalScore        string `json:"finalScore"`
+	Winner            string            `json:"winner,omitempty"` // Debater the judge named in the Winner section, empty when none
+	WinnerDeclaration string            `json:"winnerDeclaration"`
+	ArgumentAnalysis  string            `json:"argumentAnalysis"`
+	DebatePerformance string            `json:"debatePerformance"`
+	EvidenceLogic     string            `json:"evidenceLogic"`
+	Persuasiveness    string            `json:"persuasiveness"`
+	KeyTurningPoints  string            `json:"keyTurningPoints"`
+	FinalScore        string            `json:"finalScore"`
+	Audience          *AudienceReport   `json:"audience,omitempty"`   // Spectator votes, when any were cast
+	Engagement        *EngagementReport `json:"engagement,omitempty"` // Reactions to the debaters' messages, when any were given
 }
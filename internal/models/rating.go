@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Rating is a player's Glicko-2 rating, shown on the usual 1500-centred scale
+type Rating struct {
+	Player      string    `json:"player"`
+	Rating      float64   `json:"rating"`
+	RD          float64   `json:"rd"` // Rating deviation; high while we know little about the player
+	Volatility  float64   `json:"volatility"`
+	Games       int       `json:"games"`
+	Wins        int       `json:"wins"`
+	Losses      int       `json:"losses"`
+	Provisional bool      `json:"provisional"`    // RD still too high for the rating to be trusted
+	Rank        int       `json:"rank,omitempty"` // Leaderboard position, established players only
+	LastPlayed  time.Time `json:"lastPlayed"`
+}
+
+// RatingChange records how one debate moved a player's rating
+type RatingChange struct {
+	At           time.Time `json:"at"`
+	Channel      string    `json:"channel"`
+	Round        int       `json:"round"`
+	Opponent     string    `json:"opponent"`
+	Won          bool      `json:"won"`
+	RatingBefore float64   `json:"ratingBefore"`
+	RatingAfter  float64   `json:"ratingAfter"`
+	RDBefore     float64   `json:"rdBefore"`
+	RDAfter      float64   `json:"rdAfter"`
+}
+
+// RatingBook is everything the rating system persists
+type RatingBook struct {
+	Players map[string]*Rating        `json:"players"`
+	History map[string][]RatingChange `json:"history"` // Player -> changes, oldest first
+	Owners  map[string]string         `json:"owners"`  // Player -> identity of the browser whose debates rated the name
+}
+
+type Leaderboard struct {
+	Ratings []Rating `json:"ratings"`
+	Total   int      `json:"total"`
+	Offset  int      `json:"offset"`
+	Limit   int      `json:"limit"`
+}
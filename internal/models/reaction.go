@@ -0,0 +1,24 @@
+package models
+
+// AllowedReactions are the reactions clients may add to debate messages
+var AllowedReactions = []string{"👍", "👎", "👏", "🔥", "🤔", "😂"}
+
+// ReactionCounts aggregates a message's reactions into counts
+func (m Message) ReactionCounts() map[string]int {
+	counts := make(map[string]int, len(m.Reactions))
+	for reaction, names := range m.Reactions {
+		if len(names) > 0 {
+			counts[reaction] = len(names)
+		}
+	}
+	return counts
+}
+
+// EngagementReport summarizes how the room reacted to each debater's messages
+type EngagementReport struct {
+	TotalReactions int                       `json:"totalReactions"`
+	ByDebater      map[string]int            `json:"byDebater"`            // Debater -> reactions received
+	ByReaction     map[string]map[string]int `json:"byReaction"`           // Debater -> reaction -> count
+	TopMessage     *Message                  `json:"topMessage,omitempty"` // Most reacted-to debate message
+	TopCount       int                       `json:"topCount"`
+}
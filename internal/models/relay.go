@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Relay envelope kinds, exchanged on a channel's broker topic between the instance that owns the
+// channel and instances holding sockets for it
+const (
+	RelayJoin  = "join"  // A socket connected to another instance
+	RelayInput = "input" // A frame read from that socket
+	RelayLeave = "leave" // The socket closed
+	RelayFrame = "frame" // JSON for the owner to have written to the listed sockets
+	RelayClose = "close" // Sockets the owner wants closed
+)
+
+// RelayEnvelope carries one client's traffic between instances
+type RelayEnvelope struct {
+	Kind      string          `json:"kind"`
+	Origin    string          `json:"origin"` // Publishing instance
+	Client    uuid.UUID       `json:"client,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	IP        string          `json:"ip,omitempty"`      // Address the socket connected from
+	Session   string          `json:"session,omitempty"` // Session key of the browser that connected it
+	Password  string          `json:"password,omitempty"`
+	OwnerKeys []string        `json:"ownerKeys,omitempty"`
+	Text      string          `json:"text,omitempty"`
+	To        []uuid.UUID     `json:"to,omitempty"`
+	Frame     json.RawMessage `json:"frame,omitempty"`
+	Code      int             `json:"code,omitempty"` // Close code for RelayClose, zero to just drop the sockets
+	Reason    string          `json:"reason,omitempty"`
+}
+
+// InstanceAnnouncement is what each instance periodically publishes about itself, so that the others
+// can list its channels and send clients its way
+type InstanceAnnouncement struct {
+	Instance     string           `json:"instance"`
+	AdvertiseURL string           `json:"advertiseUrl,omitempty"`
+	Channels     []ChannelSummary `json:"channels"`
+}
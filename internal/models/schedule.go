@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScheduleUpcoming  = "upcoming"
+	ScheduleStarted   = "started"
+	ScheduleForfeited = "forfeited" // One or both debaters were missing or not ready at the start time
+	ScheduleCancelled = "cancelled" // The channel was deleted before it started
+)
+
+// ScheduledDebate is a channel booked to start at a fixed time
+type ScheduledDebate struct {
+	ChannelId    uuid.UUID `json:"channelId"`
+	Channel      string    `json:"channel"`
+	Motion       string    `json:"motion"`
+	Owner        string    `json:"owner"`
+	BestOf       int       `json:"bestOf"`
+	StartsAt     time.Time `json:"startsAt"`
+	Status       string    `json:"status"`
+	ReminderSent bool      `json:"reminderSent"`
+	Winner       string    `json:"winner,omitempty"`  // Debater awarded the forfeit, empty if nobody was ready
+	NoShows      []string  `json:"noShows,omitempty"` // Debaters missing or not ready at the start time
+	CreatedAt    time.Time `json:"createdAt"`
+	ResolvedAt   time.Time `json:"resolvedAt,omitzero"` // When it started, was forfeited or was cancelled
+	Password     string    `json:"-"`
+	OwnerKey     string    `json:"-"`
+}
+
+// ScheduleStore is what the schedule service persists, secrets included
+type ScheduleStore struct {
+	Schedules []StoredSchedule `json:"schedules"`
+}
+
+type StoredSchedule struct {
+	ScheduledDebate
+	Password string `json:"password"`
+	OwnerKey string `json:"ownerKey"`
+}
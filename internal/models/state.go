@@ -0,0 +1,128 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChannelSummary is an immutable listing view of a channel, safe to hand to templates and APIs
+type ChannelSummary struct {
+	Id               uuid.UUID  `json:"id"`
+	Name             string     `json:"name"`
+	Motion           string     `json:"motion"`
+	ParticipantCount int        `json:"participantCount"`
+	SpectatorCount   int        `json:"spectatorCount"`
+	PhaseId          int        `json:"phaseId"`
+	PhaseName        string     `json:"phaseName"`
+	Locked           bool       `json:"locked"` // Joining as a debater requires a password
+	Status           string     `json:"status"`
+	Round            int        `json:"round"`
+	BestOf           int        `json:"bestOf"`
+	Owner            string     `json:"owner"`
+	StartsAt         *time.Time `json:"startsAt,omitempty"` // Scheduled start
+	CreatedAt        time.Time  `json:"createdAt"`
+	LastActivity     time.Time  `json:"lastActivity"`
+	Instance         string     `json:"instance,omitempty"` // Instance running the channel, when it is another one
+}
+
+// ChannelQuery filters, sorts and pages a channel listing
+type ChannelQuery struct {
+	Search string // Case-insensitive match against name and motion
+	Sort   string // "name" (default), "participants", "spectators", "phase" or "created"
+	Desc   bool
+	Offset int
+	Limit  int // Zero means no limit
+}
+
+type ChannelListing struct {
+	Channels []ChannelSummary `json:"channels"`
+	Total    int              `json:"total"` // Matches before paging
+	Offset   int              `json:"offset"`
+	Limit    int              `json:"limit"`
+}
+
+// ChannelState is a read-only snapshot of a channel's live debate state
+type ChannelState struct {
+	Name         string             `json:"name"`
+	Status       string             `json:"status"`
+	Round        int                `json:"round"`
+	Series       Series             `json:"series"`
+	Phase        PhaseState         `json:"phase"`
+	Participants []ParticipantState `json:"participants"`
+	ClientCount  int                `json:"clientCount"`
+	PendingCount int                `json:"pendingCount"` // Submissions held back until everyone has answered
+}
+
+type PhaseState struct {
+	Id               int        `json:"id"`
+	Name             string     `json:"name"`
+	StartTime        *time.Time `json:"startTime,omitempty"`
+	DurationSeconds  int        `json:"durationSeconds"`
+	Deadline         *time.Time `json:"deadline,omitempty"` // Omitted while paused
+	RemainingSeconds int        `json:"remainingSeconds"`
+	ExtensionSeconds int        `json:"extensionSeconds"`
+	Paused           bool       `json:"paused"`
+	Pause            *Pause     `json:"pause,omitempty"`
+}
+
+type ParticipantState struct {
+	Id        uuid.UUID `json:"clientId"`
+	Name      string    `json:"clientName"`
+	Role      string    `json:"role"` // "debater" or "spectator"
+	Side      string    `json:"side,omitempty"`
+	Ready     bool      `json:"ready"`
+	Submitted bool      `json:"submitted"` // Submitted for the current phase
+}
+
+// Paused reports whether the phase clock is stopped
+func (p Phase) Paused() bool {
+	return !p.PausedAt.IsZero()
+}
+
+// Elapsed returns the running time of the phase at now, excluding time spent paused
+func (p Phase) Elapsed(now time.Time) time.Duration {
+	if p.StartTime.IsZero() {
+		return 0
+	}
+	if p.Paused() {
+		now = p.PausedAt
+	}
+	return now.Sub(p.StartTime) - p.PausedTotal
+}
+
+// Deadline returns when the phase runs out if the clock keeps running, or the zero time for
+// untimed phases; while paused it is only meaningful once the pause ends
+func (p Phase) Deadline() time.Time {
+	if p.Duration == 0 || p.StartTime.IsZero() {
+		return time.Time{}
+	}
+	return p.StartTime.Add(p.Duration + p.Extension + p.PausedTotal)
+}
+
+// Remaining returns the time left in the phase at now, never negative; it does not shrink while paused
+func (p Phase) Remaining(now time.Time) time.Duration {
+	if p.Duration == 0 || p.StartTime.IsZero() {
+		return 0
+	}
+	remaining := p.Duration + p.Extension - p.Elapsed(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// PauseAt stops the clock at now
+func (p *Phase) PauseAt(now time.Time) {
+	if !p.Paused() {
+		p.PausedAt = now
+	}
+}
+
+// ResumeAt restarts the clock, adding the pause to PausedTotal so deadlines shift accordingly
+func (p *Phase) ResumeAt(now time.Time) {
+	if p.Paused() {
+		p.PausedTotal += now.Sub(p.PausedAt)
+		p.PausedAt = time.Time{}
+	}
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+const (
+	TournamentSingleElimination = "single_elimination"
+	TournamentSwiss             = "swiss"
+)
+
+const (
+	TournamentRegistering = "registering"
+	TournamentRunning     = "running"
+	TournamentFinished    = "finished"
+)
+
+const (
+	MatchPending   = "pending" // Paired, its channel not open yet
+	MatchLive      = "live"
+	MatchCompleted = "completed"
+)
+
+type Tournament struct {
+	Id           string            `json:"id"`
+	Name         string            `json:"name"`
+	Format       string            `json:"format"`
+	Motion       string            `json:"motion,omitempty"` // Used for every match; drawn per match when empty
+	Organizer    string            `json:"organizer"`
+	OrganizerKey string            `json:"-"`
+	Status       string            `json:"status"`
+	Players      []string          `json:"players"`
+	PlayerKeys   map[string]string `json:"-"` // Player -> key that reveals their match passwords
+	Seeds        map[string]int    `json:"seeds,omitempty"`
+	SwissRounds  int               `json:"swissRounds,omitempty"`
+	Rounds       []TournamentRound `json:"rounds"`
+	Standings    []Standing        `json:"standings,omitempty"` // Swiss only
+	Winner       string            `json:"winner,omitempty"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	StartedAt    *time.Time        `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time        `json:"finishedAt,omitempty"`
+}
+
+type TournamentRound struct {
+	Number  int               `json:"number"`
+	Matches []TournamentMatch `json:"matches"`
+}
+
+// TournamentMatch is one pairing; a bye has no opponent and counts as a win
+type TournamentMatch struct {
+	Id          string     `json:"id"`
+	Round       int        `json:"round"`
+	Proposition string     `json:"proposition"`
+	Opposition  string     `json:"opposition,omitempty"`
+	Bye         bool       `json:"bye"`
+	Channel     string     `json:"channel,omitempty"`
+	Password    string     `json:"-"`
+	Motion      string     `json:"motion,omitempty"`
+	Status      string     `json:"status"`
+	LivePhase   string     `json:"livePhase,omitempty"` // Phase title of the running debate, filled in when read
+	Winner      string     `json:"winner,omitempty"`
+	Awarded     bool       `json:"awarded,omitempty"` // Decided by the organizer rather than a verdict
+	ConcludedAt *time.Time `json:"concludedAt,omitempty"`
+}
+
+// Standing is a player's Swiss score; Buchholz (opponents' points) breaks ties
+type Standing struct {
+	Rank     int     `json:"rank"`
+	Player   string  `json:"player"`
+	Points   float64 `json:"points"`
+	Wins     int     `json:"wins"`
+	Losses   int     `json:"losses"`
+	Byes     int     `json:"byes"`
+	Buchholz float64 `json:"buchholz"`
+}
+
+// TournamentStore is what the tournament service persists, keys included
+type TournamentStore struct {
+	Tournaments []StoredTournament `json:"tournaments"`
+}
+
+type StoredTournament struct {
+	Tournament
+	OrganizerKey string            `json:"organizerKey"`
+	PlayerKeys   map[string]string `json:"playerKeys"`
+	Passwords    map[string]string `json:"passwords"` // Match id -> channel password
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TranscriptExportVersion is bumped whenever the export format changes incompatibly
+const TranscriptExportVersion = 1
+
+// TranscriptExport is the machine-readable export of one round of a debate
+type TranscriptExport struct {
+	Version      int               `json:"version"`
+	ChannelId    uuid.UUID         `json:"channelId"`
+	Channel      string            `json:"channel"`
+	Round        int               `json:"round"`
+	Motion       string            `json:"motion,omitempty"`
+	Concluded    bool              `json:"concluded"` // False while the round is still being debated
+	Participants []string          `json:"participants"`
+	Sides        map[string]string `json:"sides"`
+	Winner       string            `json:"winner,omitempty"`
+	Forfeit      bool              `json:"forfeit,omitempty"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	ConcludedAt  *time.Time        `json:"concludedAt,omitempty"`
+	ExportedAt   time.Time         `json:"exportedAt"`
+	Verdict      *JudgeReport      `json:"verdict,omitempty"`
+	Messages     []Message         `json:"messages"`
+}
+
+// TranscriptPhase is the part of a transcript sent during one debate phase, for readable exports
+type TranscriptPhase struct {
+	Id       int
+	Title    string
+	Messages []Message
+}
@@ -0,0 +1,63 @@
+package models
+
+const (
+	VoteFor     = "for"
+	VoteAgainst = "against"
+)
+
+// AudienceVotes holds spectator ballots keyed by spectator name, so re-voting replaces the old ballot
+type AudienceVotes struct {
+	Pre    map[string]string         // Stance on the motion before the debate: VoteFor or VoteAgainst
+	Post   map[string]string         // Stance on the motion after the closing statements
+	Phases map[int]map[string]string // Phase id -> spectator -> debater who won that phase
+}
+
+func NewAudienceVotes() AudienceVotes {
+	return AudienceVotes{
+		Pre:    make(map[string]string),
+		Post:   make(map[string]string),
+		Phases: make(map[int]map[string]string),
+	}
+}
+
+type StanceTally struct {
+	For     int `json:"for"`
+	Against int `json:"against"`
+}
+
+// ForPercent is the share of ballots for the motion, 0 when nobody voted
+func (t StanceTally) ForPercent() float64 {
+	total := t.For + t.Against
+	if total == 0 {
+		return 0
+	}
+	return float64(t.For) * 100 / float64(total)
+}
+
+// VoteTally is the live count broadcast as a vote_tally event
+type VoteTally struct {
+	Pre    StanceTally            `json:"pre"`
+	Post   StanceTally            `json:"post"`
+	Phases map[int]map[string]int `json:"phases"` // Phase id -> debater -> votes
+}
+
+// AudienceReport compares the audience's verdict with the AI judge's
+type AudienceReport struct {
+	Pre             StanceTally    `json:"pre"`
+	Post            StanceTally    `json:"post"`
+	PreForPercent   float64        `json:"preForPercent"`
+	PostForPercent  float64        `json:"postForPercent"`
+	Swing           *float64       `json:"swing,omitempty"`          // Percentage points moved toward the motion, nil unless the audience voted both before and after
+	AudienceWinner  string         `json:"audienceWinner,omitempty"` // Debater whose side gained ground
+	JudgeWinner     string         `json:"judgeWinner,omitempty"`
+	AgreesWithJudge bool           `json:"agreesWithJudge"`
+	PhaseWins       map[string]int `json:"phaseWins"` // Debater -> phases the audience awarded them
+}
+
+// SwingPoints is the swing for display, 0 when none was measured
+func (r AudienceReport) SwingPoints() float64 {
+	if r.Swing == nil {
+		return 0
+	}
+	return *r.Swing
+}
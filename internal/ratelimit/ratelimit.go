@@ -0,0 +1,112 @@
+// Package ratelimit keeps token buckets keyed by client identity, such as an IP address or a browser
+// session, so one noisy client cannot starve the others.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter hands out up to burst tokens per key, refilling each bucket at burst tokens per period
+type Limiter struct {
+	burst  float64
+	perSec float64 // Tokens added per second
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time // When tokens was last brought up to date
+}
+
+// New returns a limiter allowing burst requests per key at once and burst per period after that.
+// A limiter with a burst or period of zero allows everything.
+func New(burst int, period time.Duration) *Limiter {
+	l := &Limiter{buckets: make(map[string]*bucket), swept: time.Now()}
+	if burst > 0 && period > 0 {
+		l.burst = float64(burst)
+		l.perSec = float64(burst) / period.Seconds()
+	}
+	return l
+}
+
+// Allow takes a token from the bucket of every key, or from none of them when any is empty, in which
+// case it also returns how long until that bucket has a token again
+func (l *Limiter) Allow(keys ...string) (bool, time.Duration) {
+	if l == nil || l.burst == 0 {
+		return true, 0
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	var wait time.Duration
+	for _, key := range keys {
+		b := l.refill(key, now)
+		if b.tokens < 1 {
+			if w := time.Duration((1 - b.tokens) / l.perSec * float64(time.Second)); w > wait {
+				wait = w
+			}
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+	for _, key := range keys {
+		l.buckets[key].tokens--
+	}
+	return true, 0
+}
+
+// refill brings the key's bucket up to date, creating it full; runs under l.mu
+func (l *Limiter) refill(key string, now time.Time) *bucket {
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: l.burst, at: now}
+		l.buckets[key] = b
+		return b
+	}
+	b.tokens += now.Sub(b.at).Seconds() * l.perSec
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.at = now
+	return b
+}
+
+// sweep drops buckets that have refilled completely, which behave the same as missing ones, at
+// most once per refill period; runs under l.mu
+func (l *Limiter) sweep(now time.Time) {
+	full := time.Duration(l.burst / l.perSec * float64(time.Second))
+	if now.Sub(l.swept) < full {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.at).Seconds()*l.perSec >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Keys names the buckets of a client identified by IP address and session, skipping empty ones
+func Keys(ip, session string) []string {
+	keys := make([]string, 0, 2)
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	if session != "" {
+		keys = append(keys, "session:"+session)
+	}
+	return keys
+}
+
+// RetryAfter rounds a wait up to whole seconds, for Retry-After headers and error events
+func RetryAfter(wait time.Duration) int {
+	return int((wait + time.Second - 1) / time.Second)
+}
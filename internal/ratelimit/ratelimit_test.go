@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// age moves the limiter's clock readings back by d, as if d had passed
+func age(l *Limiter, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.swept = l.swept.Add(-d)
+	for _, b := range l.buckets {
+		b.at = b.at.Add(-d)
+	}
+}
+
+func TestBurst(t *testing.T) {
+	l := New(3, time.Minute)
+	for i := range 3 {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d of a burst of 3 refused", i+1)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("request past the burst allowed")
+	}
+	if wait <= 19*time.Second || wait > 20*time.Second {
+		t.Errorf("wait = %v, want the 20s one token takes to refill", wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("another key refused for a's burst")
+	}
+
+	if ok, _ := New(0, time.Minute).Allow("a"); !ok {
+		t.Error("limiter without a burst refused a request")
+	}
+	var disabled *Limiter
+	if ok, _ := disabled.Allow("a"); !ok {
+		t.Error("nil limiter refused a request")
+	}
+}
+
+func TestRefill(t *testing.T) {
+	l := New(2, 10*time.Second)
+	l.Allow("a")
+	l.Allow("a")
+
+	age(l, 5*time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("refused after half a period refilled a token")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("allowed a second request with one token refilled")
+	}
+
+	// A long wait fills the bucket only up to the burst
+	age(l, time.Hour)
+	for i := range 2 {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d after an hour refused", i+1)
+		}
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("bucket refilled past its burst")
+	}
+}
+
+// TestAllowAllOrNothing checks that a request refused for one key takes no token from the others
+func TestAllowAllOrNothing(t *testing.T) {
+	l := New(1, time.Minute)
+	if ok, _ := l.Allow(Keys("10.0.0.1", "ann")...); !ok {
+		t.Fatal("first request refused")
+	}
+
+	ok, wait := l.Allow(Keys("10.0.0.1", "bob")...)
+	if ok {
+		t.Fatal("allowed from an IP address out of tokens")
+	}
+	if wait <= 59*time.Second || wait > time.Minute {
+		t.Errorf("wait = %v, want the minute the IP address's bucket takes to refill", wait)
+	}
+	if ok, _ := l.Allow(Keys("10.0.0.2", "bob")...); !ok {
+		t.Error("bob's session was charged for the refused request")
+	}
+	if ok, _ := l.Allow(Keys("10.0.0.3", "ann")...); ok {
+		t.Error("allowed from a session out of tokens")
+	}
+}
+
+func TestKeys(t *testing.T) {
+	if got := Keys("10.0.0.1", "ann"); len(got) != 2 || got[0] != "ip:10.0.0.1" || got[1] != "session:ann" {
+		t.Errorf("Keys = %q", got)
+	}
+	if got := Keys("", ""); len(got) != 0 {
+		t.Errorf("Keys of nothing = %q, want none", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, tt := range []struct {
+		wait time.Duration
+		want int
+	}{
+		{0, 0},
+		{time.Nanosecond, 1},
+		{999 * time.Millisecond, 1},
+		{time.Second, 1},
+		{1001 * time.Millisecond, 2},
+		{20 * time.Second, 20},
+	} {
+		if got := RetryAfter(tt.wait); got != tt.want {
+			t.Errorf("RetryAfter(%v) = %d, want %d", tt.wait, got, tt.want)
+		}
+	}
+}
+
+// TestSweep checks that sweeping forgets the buckets that have refilled, and only those, at most once
+// per refill period
+func TestSweep(t *testing.T) {
+	l := New(2, 10*time.Second)
+	l.Allow("idle")
+	age(l, 10*time.Second)
+	l.Allow("spent")
+	l.Allow("spent")
+
+	l.mu.Lock()
+	_, idle := l.buckets["idle"]
+	_, spent := l.buckets["spent"]
+	l.mu.Unlock()
+	if idle || !spent {
+		t.Fatalf("after a sweep: idle kept %v, spent kept %v, want only the spent bucket kept", idle, spent)
+	}
+
+	l.Allow("recent")
+	age(l, 5*time.Second)
+	l.Allow("other")
+	l.mu.Lock()
+	_, recent := l.buckets["recent"]
+	l.mu.Unlock()
+	if !recent {
+		t.Error("swept again within a refill period")
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// AdminRecentMessages is how many of the latest floor messages the admin drill-down shows
+const AdminRecentMessages = 20
+
+var (
+	ErrNoDebate       = errors.New("no debate in progress")
+	ErrClientNotFound = errors.New("client not found")
+)
+
+// The operations below back the admin console. They act on the channels this instance owns;
+// channels owned by another instance are managed from that instance's console.
+
+// AdminChannels lists every channel with its AI usage and age, oldest first
+func (s *ChannelService) AdminChannels() []models.AdminChannel {
+	s.Manager.Mu.Lock()
+	channels := make([]*models.Channel, 0, len(s.Manager.Channels))
+	for _, ch := range s.Manager.Channels {
+		channels = append(channels, ch)
+	}
+	s.Manager.Mu.Unlock()
+
+	now := time.Now()
+	listing := make([]models.AdminChannel, 0, len(channels))
+	for _, ch := range channels {
+		var entry models.AdminChannel
+		if ch.Actor.Do(func() {
+			entry = adminChannel(ch, now)
+		}) {
+			listing = append(listing, entry)
+		}
+	}
+	sort.Slice(listing, func(i, j int) bool {
+		if !listing[i].CreatedAt.Equal(listing[j].CreatedAt) {
+			return listing[i].CreatedAt.Before(listing[j].CreatedAt)
+		}
+		return listing[i].Name < listing[j].Name
+	})
+	return listing
+}
+
+// AdminChannel returns the live state of one channel for the admin drill-down
+func (s *ChannelService) AdminChannel(ch *models.Channel) models.AdminChannelDetail {
+	var detail models.AdminChannelDetail
+	ch.Actor.Do(func() {
+		start := len(ch.Messages) - AdminRecentMessages
+		if start < 0 {
+			start = 0
+		}
+		recent := make([]models.Message, len(ch.Messages)-start)
+		copy(recent, ch.Messages[start:])
+
+		detail = models.AdminChannelDetail{
+			AdminChannel:   adminChannel(ch, time.Now()),
+			State:          channelState(ch),
+			EventCount:     len(ch.Events),
+			QueuedCommands: ch.Actor.Pending(),
+			RecentMessages: recent,
+		}
+	})
+	return detail
+}
+
+// adminChannel snapshots the channel for the admin listing; runs on the channel's actor
+func adminChannel(ch *models.Channel, now time.Time) models.AdminChannel {
+	return models.AdminChannel{
+		ChannelSummary: summarize(ch),
+		ClientCount:    ch.ClientCount,
+		AIUsage:        ch.AIUsage,
+		AgeSeconds:     int(now.Sub(ch.CreatedAt).Seconds()),
+	}
+}
+
+// ForceEnd stops the running debate without a verdict and archives the round
+func (s *ChannelService) ForceEnd(ch *models.Channel) error {
+	err := ErrNoDebate
+	ch.Actor.Do(func() {
+		if ch.Status != models.ChannelOpen || ch.Phase.Id == 0 {
+			return
+		}
+		err = nil
+		s.broadcast(ch, models.Message{
+			SenderType: "system",
+			SenderName: "system",
+			Text:       "🛑 An administrator has ended this debate.",
+			Timestamp:  time.Now(),
+		})
+		// An AI answer still in flight finds the channel archived and is dropped
+		s.concludeWithVerdict(ch, nil)
+		channelLog(ch).Info("debate ended by an administrator", "phase", ch.Phase.Id)
+	})
+	return err
+}
+
+// Announce posts a system announcement to the channel's floor
+func (s *ChannelService) Announce(ch *models.Channel, text string) {
+	ch.Actor.Do(func() {
+		s.announce(ch, text)
+	})
+}
+
+// AnnounceAll posts a system announcement to every channel, returning how many received it
+func (s *ChannelService) AnnounceAll(text string) int {
+	s.Manager.Mu.Lock()
+	channels := make([]*models.Channel, 0, len(s.Manager.Channels))
+	for _, ch := range s.Manager.Channels {
+		channels = append(channels, ch)
+	}
+	s.Manager.Mu.Unlock()
+
+	sent := 0
+	for _, ch := range channels {
+		if ch.Actor.Do(func() {
+			s.announce(ch, text)
+		}) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// announce broadcasts an administrator's announcement; runs on the channel's actor
+func (s *ChannelService) announce(ch *models.Channel, text string) {
+	s.broadcast(ch, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       "📢 " + text,
+		Timestamp:  time.Now(),
+	})
+}
+
+// DisconnectClient closes a client's socket with a policy violation; the socket's handler then
+// removes the client from the channel as for any other disconnect
+func (s *ChannelService) DisconnectClient(ch *models.Channel, id uuid.UUID) (string, error) {
+	name := ""
+	err := ErrClientNotFound
+	ch.Actor.Do(func() {
+		client, ok := ch.Clients[id]
+		if !ok {
+			return
+		}
+		name, err = client.Name, nil
+		s.notifyClient(ch, client, "🚫 You have been disconnected by an administrator.")
+		s.disconnect(ch, []*models.Client{client}, websocket.ClosePolicyViolation, "disconnected by an administrator")
+		channelLog(ch).Info("client disconnected by an administrator", "client_id", id.String(), "client", name)
+	})
+	return name, err
+}
+
+// PurgeChannel deletes the channel, if it is still open, together with its archived rounds,
+// reporting false when there was neither
+func (s *ChannelService) PurgeChannel(name string) bool {
+	deleted := s.DeleteChannel(name)
+
+	s.Manager.Mu.Lock()
+	_, archived := s.Manager.Archives[name]
+	delete(s.Manager.Archives, name)
+	s.Manager.Mu.Unlock()
+
+	if archived {
+		slog.Info("channel archives purged", "channel", name)
+	}
+	return deleted || archived
+}
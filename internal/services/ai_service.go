@@ -2,13 +2,21 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"os"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/latestcomment/go-websocket-chat/internal/config"
+	"github.com/latestcomment/go-websocket-chat/internal/metrics"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type RequestPayload struct {
@@ -23,27 +31,47 @@ type Message struct {
 }
 
 type ApiResponse struct {
+	Model   string `json:"model"`
 	Choices []struct {
 		Message struct {
 			Role    string `json:"role"`
 			Content string `json:"content"`
 		}
 	}
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
 }
 
-func SendAIRequestWithCustomPrompt(customPrompt, context string) (string, error) {
-	if err := godotenv.Load(); err != nil {
-		fmt.Println("No .env file found")
-	}
+// AIClient sends prompts to the configured chat completions endpoint
+type AIClient struct {
+	cfg    config.AI
+	client *http.Client
+}
 
-	apiKey := os.Getenv("OPENROUTER_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("OPENROUTER_API_KEY not found in environment")
-	}
+func NewAIClient(cfg config.AI) *AIClient {
+	return &AIClient{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout.Duration}}
+}
 
-	modelName := os.Getenv("AI_MODEL")
-	if modelName == "" {
-		modelName = "deepseek/deepseek-chat-v3.1:free" // Default fallback
+// SendAIRequestWithCustomPrompt asks the model to answer input under customPrompt, also returning the
+// usage of the request; phase labels the request in the metrics and trace, a phase number or "verdict"
+func (a *AIClient) SendAIRequestWithCustomPrompt(ctx context.Context, phase, customPrompt, input string) (string, models.AIUsage, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "chat "+a.cfg.Model, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.GenAIOperationNameKey.String("chat"),
+		semconv.GenAIRequestModelKey.String(a.cfg.Model),
+		semconv.GenAIRequestMaxTokensKey.Int(a.cfg.MaxTokens),
+		attribute.String("debate.phase", phase),
+	))
+	defer span.End()
+
+	usage := models.AIUsage{Requests: 1}
+	failed := models.AIUsage{Requests: 1, Errors: 1}
+	if a.cfg.APIKey == "" {
+		metrics.AIRequestErrors.WithLabelValues(a.cfg.Model, phase).Inc()
+		err := fmt.Errorf("OPENROUTER_API_KEY not configured")
+		tracing.Fail(span, err)
+		return "", failed, err
 	}
 
 	systemMessage := Message{
@@ -53,41 +81,73 @@ func SendAIRequestWithCustomPrompt(customPrompt, context string) (string, error)
 
 	userMessage := Message{
 		Role:    "user",
-		Content: context,
+		Content: input,
 	}
 
 	payload := RequestPayload{
-		Model:     modelName,
+		Model:     a.cfg.Model,
 		Messages:  []Message{systemMessage, userMessage},
-		MaxTokens: 8192,
+		MaxTokens: a.cfg.MaxTokens,
 	}
 
 	jsonData, _ := json.Marshal(payload)
 
-	req, _ := http.NewRequest(
-		"POST",
-		"https://openrouter.ai/api/v1/chat/completions",
-		bytes.NewBuffer(jsonData),
-	)
+	req, err := http.NewRequestWithContext(ctx, "POST", a.cfg.Endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", failed, err
+	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Authorization", "Bearer "+a.cfg.APIKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	start := time.Now()
+	resp, err := a.client.Do(req)
 	if err != nil {
-		fmt.Println("Error sending request:", err)
+		metrics.AIRequestErrors.WithLabelValues(a.cfg.Model, phase).Inc()
+		err = fmt.Errorf("sending AI request: %w", err)
+		tracing.Fail(span, err)
+		return "", failed, err
 	}
 	defer resp.Body.Close()
 	body, _ := io.ReadAll(resp.Body)
+	metrics.AIRequestSeconds.WithLabelValues(a.cfg.Model, phase).Observe(time.Since(start).Seconds())
+	span.SetAttributes(semconv.HTTPResponseStatusCodeKey.Int(resp.StatusCode))
 
 	var apiResponse ApiResponse
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		fmt.Println("Error parsing JSON:", err)
+		slog.Warn("AI response is not JSON", "model", a.cfg.Model, "phase", phase, "err", err)
 	}
+	span.SetAttributes(
+		semconv.GenAIResponseModelKey.String(apiResponse.Model),
+		semconv.GenAIUsageInputTokensKey.Int(apiResponse.Usage.PromptTokens),
+		semconv.GenAIUsageOutputTokensKey.Int(apiResponse.Usage.CompletionTokens),
+	)
+	usage.PromptTokens = apiResponse.Usage.PromptTokens
+	usage.CompletionTokens = apiResponse.Usage.CompletionTokens
 	if len(apiResponse.Choices) > 0 {
-		return apiResponse.Choices[0].Message.Content, nil
+		return apiResponse.Choices[0].Message.Content, usage, nil
 	} else {
-		fmt.Println("No response choices received")
-		return fmt.Sprintf("Full response: %s", string(body)), nil
+		usage.Errors = 1
+		metrics.AIRequestErrors.WithLabelValues(a.cfg.Model, phase).Inc()
+		tracing.Fail(span, fmt.Errorf("no response choices, status %d", resp.StatusCode))
+		slog.Warn("AI response has no choices", "model", a.cfg.Model, "phase", phase, "status", resp.StatusCode)
+		return fmt.Sprintf("Full response: %s", string(body)), usage, nil
+	}
+}
+
+// Ping checks that the provider answers HTTP at all; any status below 500 counts, since the endpoint
+// only accepts authenticated POSTs
+func (a *AIClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, a.cfg.Endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("AI provider answered %s", resp.Status)
 	}
+	return nil
 }
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/store"
+)
+
+const (
+	auditDocument = "audit"
+
+	// MaxAuditEntries is how many admin actions are kept; older ones are dropped
+	MaxAuditEntries = 1000
+)
+
+// AuditService keeps the log of actions taken in the admin console, persisted so it survives restarts
+type AuditService struct {
+	mu      sync.Mutex
+	saving  sync.Mutex          // Held from snapshot to write, so a slow save cannot overwrite a newer one
+	entries []models.AuditEntry // Oldest first
+	store   *store.Store
+}
+
+func NewAuditService(st *store.Store) (*AuditService, error) {
+	as := &AuditService{store: st}
+
+	var saved models.AuditLog
+	if _, err := st.Load(auditDocument, &saved); err != nil {
+		return nil, err
+	}
+	as.entries = saved.Entries
+	return as, nil
+}
+
+// Record appends entry to the log and writes it to the application log as well
+func (as *AuditService) Record(entry models.AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	slog.Info("admin action",
+		"admin", entry.Admin,
+		"ip", entry.IP,
+		"request_id", entry.RequestId,
+		"action", entry.Action,
+		"channel", entry.Channel,
+		"client", entry.Client,
+		"detail", entry.Detail,
+		"error", entry.Error,
+	)
+
+	as.saving.Lock()
+	defer as.saving.Unlock()
+
+	as.mu.Lock()
+	as.entries = append(as.entries, entry)
+	if len(as.entries) > MaxAuditEntries {
+		as.entries = append([]models.AuditEntry(nil), as.entries[len(as.entries)-MaxAuditEntries:]...)
+	}
+	saved := models.AuditLog{Entries: make([]models.AuditEntry, len(as.entries))}
+	copy(saved.Entries, as.entries)
+	as.mu.Unlock()
+
+	if err := as.store.Save(auditDocument, saved); err != nil {
+		slog.Error("saving audit log", "err", err)
+	}
+}
+
+// Entries returns up to limit of the latest actions, newest first
+func (as *AuditService) Entries(limit int) []models.AuditEntry {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if limit <= 0 || limit > len(as.entries) {
+		limit = len(as.entries)
+	}
+	entries := make([]models.AuditEntry, 0, limit)
+	for i := len(as.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, as.entries[i])
+	}
+	return entries
+}
@@ -1,50 +1,172 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/websocket/v2"
 	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/actor"
+	"github.com/latestcomment/go-websocket-chat/internal/broker"
+	"github.com/latestcomment/go-websocket-chat/internal/config"
+	"github.com/latestcomment/go-websocket-chat/internal/metrics"
 	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/ratelimit"
+	"github.com/latestcomment/go-websocket-chat/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// channelQueue is how many commands may wait for a channel's actor before senders block
+const channelQueue = 64
+
+// Each channel's state is owned by its actor: the exported methods below run their work as commands
+// on it, and the unexported ones expect to be called from such a command.
+
 type ChannelService struct {
-	Manager *models.ChannelManager
+	Manager        *models.ChannelManager
+	concludedHooks []func(*models.Archive)
+
+	clusterMu sync.Mutex
+	broker    broker.Broker
+	leaseTTL  time.Duration
+	owned     map[string]*ownedChannel   // Channels leased by this instance
+	relayed   map[string]*relayedChannel // Channels owned elsewhere with sockets relayed from here
+	instances map[string]*remoteInstance // What the other instances last announced, by instance
+	advertise string                     // URL announced for reaching this instance directly
+
+	draining atomic.Bool // Set by Shutdown
+
+	ai        *AIClient
+	debate    config.Debate
+	maxBestOf int
+
+	maxClients   int                // Sockets one channel accepts
+	messageLimit *ratelimit.Limiter // Frames per IP and per user
+
+	archiveRetention time.Duration // How long concluded rounds are kept
+	maxArchives      int           // Concluded rounds kept per channel
 }
 
 func NewChannelService(manager *models.ChannelManager) *ChannelService {
-	return &ChannelService{Manager: manager}
+	defaults := config.Default()
+	return &ChannelService{
+		Manager:   manager,
+		broker:    broker.NewMemory(uuid.NewString()),
+		leaseTTL:  ChannelLeaseTTL,
+		owned:     make(map[string]*ownedChannel),
+		relayed:   make(map[string]*relayedChannel),
+		instances: make(map[string]*remoteInstance),
+		ai:        NewAIClient(defaults.AI),
+		debate:    defaults.Debate,
+		maxBestOf: defaults.Limits.MaxBestOf,
+
+		maxClients:   defaults.Limits.MaxChannelClients,
+		messageLimit: newLimiter(defaults.Limits.MessageRate),
+
+		archiveRetention: defaults.Limits.ArchiveRetention.Duration,
+		maxArchives:      defaults.Limits.MaxChannelArchives,
+	}
+}
+
+// Configure applies the AI, debate and limit settings; call it before any channel is created
+func (s *ChannelService) Configure(cfg config.Config) {
+	s.ai = NewAIClient(cfg.AI)
+	s.debate = cfg.Debate
+	s.maxBestOf = cfg.Limits.MaxBestOf
+	s.maxClients = cfg.Limits.MaxChannelClients
+	s.messageLimit = newLimiter(cfg.Limits.MessageRate)
+	s.advertise = cfg.Cluster.AdvertiseURL
+	s.archiveRetention = cfg.Limits.ArchiveRetention.Duration
+	s.maxArchives = cfg.Limits.MaxChannelArchives
+}
+
+// AI is the client used for phase analyses and verdicts
+func (s *ChannelService) AI() *AIClient {
+	return s.ai
+}
+
+// MaxBestOf is the longest series a channel may be created with
+func (s *ChannelService) MaxBestOf() int {
+	return s.maxBestOf
 }
 
-func (s *ChannelService) CreateChannel(name string, inputPassword string) *models.Channel {
+var ErrChannelExists = errors.New("channel already exists")
 
-	inputPswd := string([]byte(inputPassword))
-	phase := models.Phase{
-		Id:       0,
-		Name:     "Phase 0",
-		Duration: 0, // No time limit for lobby phase
+// CreateChannel opens a channel owned by this instance. It returns ErrChannelExists when a channel of
+// that name is open here and ErrChannelOwnedElsewhere when another instance holds its lease.
+func (s *ChannelService) CreateChannel(opts models.ChannelOptions) (*models.Channel, error) {
+	bestOf := opts.BestOf
+	if bestOf < 1 {
+		bestOf = models.MatchFormats[s.debate.DefaultFormat]
 	}
 
-	ch := &models.Channel{
-		ChannelId:             uuid.New(),
-		Name:                  name,
-		Password:              inputPswd,
-		Clients:               make(map[uuid.UUID]*models.Client),
-		Messages:              []models.Message{},
-		PendingMessages:       []models.Message{},
-		ClientCount:           0,
-		Phase:                 phase,
-		PhaseParticipants:     make(map[string]map[string]bool),
+	id := opts.Id
+	if id == uuid.Nil {
+		id = uuid.New()
 	}
+	ownerKey := opts.OwnerKey
+	if ownerKey == "" {
+		ownerKey = uuid.NewString()
+	}
+
+	ch := newChannel()
+	ch.Password = opts.Password // Secrets stay out of the event log
+	ch.OwnerKey = ownerKey
+	record(ch, models.ChannelEvent{
+		Type:      models.ChannelCreated,
+		ChannelId: id,
+		Name:      opts.Name,
+		Motion:    strings.TrimSpace(opts.Motion),
+		Owner:     opts.Owner,
+		BestOf:    bestOf,
+		StartsAt:  opts.StartsAt,
+	})
+	if err := s.openChannel(ch); err != nil {
+		return nil, err
+	}
+
+	channelLog(ch).Info("channel created")
+	return ch, nil
+}
 
+// openChannel starts the actor of a new channel and adds it under its name, unless a channel of that
+// name is already open here or the lease on it cannot be taken
+func (s *ChannelService) openChannel(ch *models.Channel) error {
 	s.Manager.Mu.Lock()
-	s.Manager.Channels[name] = ch
+	if _, ok := s.Manager.Channels[ch.Name]; ok {
+		s.Manager.Mu.Unlock()
+		return ErrChannelExists
+	}
+	ch.Actor = actor.New(channelQueue)
+	s.Manager.Channels[ch.Name] = ch
 	s.Manager.Mu.Unlock()
 
-	fmt.Printf("Channel created: %s (%s)\n", name, ch.ChannelId)
-	return ch
+	if err := s.claimChannel(ch.Name); err != nil {
+		s.Manager.Mu.Lock()
+		if s.Manager.Channels[ch.Name] == ch {
+			delete(s.Manager.Channels, ch.Name)
+		}
+		s.Manager.Mu.Unlock()
+		ch.Actor.Stop()
+		return err
+	}
+
+	// Rounds archived under the name belong to an earlier channel that had it
+	s.Manager.Mu.Lock()
+	if archives := s.Manager.Archives[ch.Name]; len(archives) > 0 && archives[0].ChannelId != ch.ChannelId {
+		delete(s.Manager.Archives, ch.Name)
+	}
+	s.Manager.Mu.Unlock()
+	return nil
 }
 
 func (s *ChannelService) GetChannel(name string) *models.Channel {
@@ -53,11 +175,281 @@ func (s *ChannelService) GetChannel(name string) *models.Channel {
 	return s.Manager.Channels[name]
 }
 
-func (s *ChannelService) AddClient(ch *models.Channel, c *models.Client) {
-	ch.Mu.Lock()
-	ch.Clients[c.Id] = c
-	ch.ClientCount++
-	ch.Mu.Unlock()
+// CheckPassword reports whether password unlocks the channel for debaters. The password never
+// changes after creation, so it is read without going through the actor.
+func (s *ChannelService) CheckPassword(ch *models.Channel, password string) bool {
+	return password == ch.Password
+}
+
+// IsOwner reports whether key is the owner key handed out when the channel was created
+func (s *ChannelService) IsOwner(ch *models.Channel, key string) bool {
+	return key != "" && key == ch.OwnerKey
+}
+
+// Summarize returns an immutable snapshot of the channel for listings
+func (s *ChannelService) Summarize(ch *models.Channel) models.ChannelSummary {
+	var summary models.ChannelSummary
+	ch.Actor.Do(func() {
+		summary = summarize(ch)
+	})
+	return summary
+}
+
+// summarize snapshots the channel for listings; runs on the channel's actor
+func summarize(ch *models.Channel) models.ChannelSummary {
+	summary := models.ChannelSummary{
+		Id:           ch.ChannelId,
+		Name:         ch.Name,
+		Motion:       ch.Motion,
+		PhaseId:      ch.Phase.Id,
+		PhaseName:    ch.Phase.Name,
+		Locked:       ch.Password != "",
+		Status:       ch.Status,
+		Round:        ch.Round,
+		BestOf:       ch.Series.BestOf,
+		Owner:        ch.Owner,
+		CreatedAt:    ch.CreatedAt,
+		LastActivity: ch.LastActivity,
+	}
+	if !ch.StartsAt.IsZero() {
+		startsAt := ch.StartsAt
+		summary.StartsAt = &startsAt
+	}
+	for _, c := range ch.Clients {
+		if c.CanSend {
+			summary.ParticipantCount++
+		} else {
+			summary.SpectatorCount++
+		}
+	}
+	return summary
+}
+
+// ListChannels returns snapshots of all channels matching the query, those other instances announced included
+func (s *ChannelService) ListChannels(q models.ChannelQuery) models.ChannelListing {
+	s.Manager.Mu.Lock()
+	channels := make([]*models.Channel, 0, len(s.Manager.Channels))
+	for _, ch := range s.Manager.Channels {
+		channels = append(channels, ch)
+	}
+	s.Manager.Mu.Unlock()
+
+	search := strings.ToLower(strings.TrimSpace(q.Search))
+	summaries := make([]models.ChannelSummary, 0, len(channels))
+	for _, ch := range channels {
+		summaries = append(summaries, s.Summarize(ch))
+	}
+	summaries = append(summaries, s.remoteSummaries()...)
+
+	matching := summaries[:0]
+	for _, summary := range summaries {
+		if search != "" &&
+			!strings.Contains(strings.ToLower(summary.Name), search) &&
+			!strings.Contains(strings.ToLower(summary.Motion), search) {
+			continue
+		}
+		matching = append(matching, summary)
+	}
+	summaries = matching
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if q.Desc {
+			a, b = b, a
+		}
+		switch q.Sort {
+		case "participants":
+			if a.ParticipantCount != b.ParticipantCount {
+				return a.ParticipantCount < b.ParticipantCount
+			}
+		case "spectators":
+			if a.SpectatorCount != b.SpectatorCount {
+				return a.SpectatorCount < b.SpectatorCount
+			}
+		case "phase":
+			if a.PhaseId != b.PhaseId {
+				return a.PhaseId < b.PhaseId
+			}
+		case "created":
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		}
+		return a.Name < b.Name
+	})
+
+	listing := models.ChannelListing{Total: len(summaries), Offset: q.Offset, Limit: q.Limit}
+	start := q.Offset
+	if start > len(summaries) {
+		start = len(summaries)
+	}
+	end := len(summaries)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+	listing.Channels = summaries[start:end]
+	return listing
+}
+
+// DeleteChannel removes a channel and disconnects everyone still in it
+func (s *ChannelService) DeleteChannel(name string) bool {
+	s.Manager.Mu.Lock()
+	ch, ok := s.Manager.Channels[name]
+	if ok {
+		delete(s.Manager.Channels, name)
+	}
+	s.Manager.Mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	ch.Actor.Do(func() {
+		closeMsg := models.Message{
+			SenderType: "system",
+			SenderName: "system",
+			Text:       "🗑️ This channel has been deleted.",
+			Timestamp:  time.Now(),
+		}
+		s.broadcast(ch, closeMsg)
+		s.disconnect(ch, channelClients(ch), websocket.CloseNormalClosure, "channel deleted")
+	})
+	ch.Actor.Stop()
+	s.releaseChannel(name)
+
+	channelLog(ch).Info("channel deleted")
+	return true
+}
+
+// ChannelState returns a snapshot of the channel's phase, participants and timers
+func (s *ChannelService) ChannelState(ch *models.Channel) models.ChannelState {
+	var state models.ChannelState
+	ch.Actor.Do(func() {
+		state = channelState(ch)
+	})
+	return state
+}
+
+// channelState snapshots the channel for the state API; runs on the channel's actor
+func channelState(ch *models.Channel) models.ChannelState {
+	now := time.Now()
+	phase := models.PhaseState{
+		Id:               ch.Phase.Id,
+		Name:             ch.Phase.Name,
+		DurationSeconds:  int(ch.Phase.Duration.Seconds()),
+		RemainingSeconds: int(ch.Phase.Remaining(now).Seconds()),
+		ExtensionSeconds: int(ch.Phase.Extension.Seconds()),
+		Paused:           ch.Phase.Paused(),
+	}
+	if ch.Pause != nil {
+		pause := *ch.Pause
+		phase.Pause = &pause
+	}
+	if !ch.Phase.StartTime.IsZero() {
+		start := ch.Phase.StartTime
+		phase.StartTime = &start
+	}
+	if deadline := ch.Phase.Deadline(); !deadline.IsZero() && !ch.Phase.Paused() {
+		phase.Deadline = &deadline
+	}
+
+	submitted := ch.PhaseParticipants[phaseKey(ch.Phase.Id)]
+	participants := make([]models.ParticipantState, 0, len(ch.Clients))
+	for _, c := range ch.Clients {
+		role := "spectator"
+		if c.CanSend {
+			role = "debater"
+		}
+		participants = append(participants, models.ParticipantState{
+			Id:        c.Id,
+			Name:      c.Name,
+			Role:      role,
+			Side:      ch.Sides[c.Name],
+			Ready:     c.Ready,
+			Submitted: submitted[c.Name],
+		})
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].Name < participants[j].Name
+	})
+
+	return models.ChannelState{
+		Name:         ch.Name,
+		Status:       ch.Status,
+		Round:        ch.Round,
+		Series:       copySeries(ch.Series),
+		Phase:        phase,
+		Participants: participants,
+		ClientCount:  ch.ClientCount,
+		PendingCount: len(ch.PendingMessages),
+	}
+}
+
+func copySeries(series models.Series) models.Series {
+	scores := make(map[string]int, len(series.Scores))
+	for name, wins := range series.Scores {
+		scores[name] = wins
+	}
+	series.Scores = scores
+	return series
+}
+
+// MessagePage returns up to limit released messages starting at offset, plus the total count
+func (s *ChannelService) MessagePage(ch *models.Channel, offset, limit int) ([]models.Message, int) {
+	page := []models.Message{}
+	total := 0
+	ch.Actor.Do(func() {
+		total = len(ch.Messages)
+		if offset > total {
+			offset = total
+		}
+		end := offset + limit
+		if end > total {
+			end = total
+		}
+
+		page = make([]models.Message, end-offset)
+		copy(page, ch.Messages[offset:end])
+	})
+	return page, total
+}
+
+// JudgeReports returns every verdict message broadcast in the channel
+func (s *ChannelService) JudgeReports(ch *models.Channel) []models.Message {
+	reports := []models.Message{}
+	ch.Actor.Do(func() {
+		for _, msg := range ch.Messages {
+			if msg.SenderType == "judge" && msg.JudgeData != nil {
+				reports = append(reports, msg)
+			}
+		}
+	})
+	return reports
+}
+
+// AddClient admits the client to the channel, or returns ErrChannelFull when it has as many clients
+// as it accepts
+func (s *ChannelService) AddClient(ch *models.Channel, c *models.Client) error {
+	err := ErrChannelFull
+	ch.Actor.Do(func() {
+		if len(ch.Clients) >= s.maxClients {
+			return
+		}
+		err = nil
+		s.addClient(ch, c)
+	})
+	return err
+}
+
+func (s *ChannelService) addClient(ch *models.Channel, c *models.Client) {
+	record(ch, models.ChannelEvent{
+		Type:      models.ClientJoined,
+		Client:    c.Id,
+		Name:      c.Name,
+		CanSend:   c.CanSend,
+		Moderator: c.IsModerator,
+		Live:      c,
+	})
 
 	joinMsg := models.Message{
 		SenderType: "system",
@@ -65,7 +457,14 @@ func (s *ChannelService) AddClient(ch *models.Channel, c *models.Client) {
 		Text:       fmt.Sprintf("%s joined the chat", c.Name),
 		Timestamp:  time.Now(),
 	}
-	s.BroadcastMessage(ch, joinMsg)
+	s.broadcast(ch, joinMsg)
+	s.sendStateEvents(ch, c)
+	if !c.CanSend {
+		s.sendSpectatorHistory(ch, c)
+	}
+	if c.CanSend {
+		s.emitReadinessChanged(ch)
+	}
 
 	// When 2 people have joined, ask if they're ready
 	if ch.ClientCount == 2 {
@@ -75,15 +474,18 @@ func (s *ChannelService) AddClient(ch *models.Channel, c *models.Client) {
 			Text:       "🤔 Two participants have joined! Are you ready to engage in the debate? Click 'Engage' when you're ready to begin.",
 			Timestamp:  time.Now(),
 		}
-		s.BroadcastMessage(ch, readyMsg)
+		s.broadcast(ch, readyMsg)
 	}
 }
 
 func (s *ChannelService) RemoveClient(ch *models.Channel, c *models.Client) {
-	ch.Mu.Lock()
-	delete(ch.Clients, c.Id)
-	ch.ClientCount--
-	ch.Mu.Unlock()
+	ch.Actor.Do(func() {
+		s.removeClient(ch, c)
+	})
+}
+
+func (s *ChannelService) removeClient(ch *models.Channel, c *models.Client) {
+	record(ch, models.ChannelEvent{Type: models.ClientLeft, Client: c.Id, Name: c.Name})
 
 	leaveMsg := models.Message{
 		SenderType: "system",
@@ -91,85 +493,139 @@ func (s *ChannelService) RemoveClient(ch *models.Channel, c *models.Client) {
 		Text:       fmt.Sprintf("%s left the chat", c.Name),
 		Timestamp:  time.Now(),
 	}
-	s.BroadcastMessage(ch, leaveMsg)
+	s.broadcast(ch, leaveMsg)
+	if c.CanSend {
+		s.emitReadinessChanged(ch)
+	}
 }
 
+// BroadcastMessage adds msg to the debate floor and sends it to everyone in the channel
 func (s *ChannelService) BroadcastMessage(ch *models.Channel, msg models.Message) {
-	ch.Mu.Lock()
-	ch.Messages = append(ch.Messages, msg)
-	ch.Mu.Unlock()
+	ch.Actor.Do(func() {
+		s.broadcast(ch, msg)
+	})
+}
 
+func (s *ChannelService) broadcast(ch *models.Channel, msg models.Message) {
+	start := time.Now()
+	defer func() { metrics.BroadcastSeconds.Observe(time.Since(start).Seconds()) }()
+	metrics.MessagesBroadcast.WithLabelValues(msg.SenderType).Inc()
+
+	if msg.Id == "" {
+		msg.Id = uuid.NewString()
+	}
+	msg.Phase = ch.Phase.Id
+	record(ch, models.ChannelEvent{Type: models.MessagePosted, Message: &msg})
+
+	s.deliverAll(ch, channelClients(ch), msg)
+	channelLog(ch).Debug("message broadcast", "sender", msg.SenderName, "sender_type", msg.SenderType, "text", msg.Text)
+}
+
+// channelLog is the default logger with the channel's name and id attached
+func channelLog(ch *models.Channel) *slog.Logger {
+	return slog.With("channel", ch.Name, "channel_id", ch.ChannelId.String())
+}
+
+// startSpan starts a span for work on ch, tagged with the channel
+func startSpan(ctx context.Context, ch *models.Channel, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("channel.name", ch.Name), attribute.String("channel.id", ch.ChannelId.String()))
+	return tracing.Tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// channelClients lists everyone connected to the channel; runs on the channel's actor
+func channelClients(ch *models.Channel) []*models.Client {
+	clients := make([]*models.Client, 0, len(ch.Clients))
 	for _, client := range ch.Clients {
-		if client.Conn != nil {
-			client.Conn.WriteJSON(msg)
-		}
+		clients = append(clients, client)
 	}
-	fmt.Printf("[%s] %s: %s\n", ch.Name, msg.SenderName, msg.Text)
+	return clients
 }
 
-func (s *ChannelService) LoopMessages(ch *models.Channel, c *websocket.Conn, client *models.Client) {
+func (s *ChannelService) LoopMessages(ctx context.Context, ch *models.Channel, c *websocket.Conn, client *models.Client) {
 	for {
 		_, data, err := c.ReadMessage()
 		if err != nil {
 			break
 		}
+		s.HandleInput(ctx, ch, client, string(data))
+	}
+}
 
-		messageText := string(data)
+// HandleInput handles one frame from a client, whether read from its socket here or relayed by another instance
+func (s *ChannelService) HandleInput(ctx context.Context, ch *models.Channel, client *models.Client, messageText string) {
+	command := "message"
+	if name, _, ok := parseCommand(messageText); ok {
+		command = name
+	}
+	ctx, span := startSpan(ctx, ch, "channel.input", attribute.String("client.id", client.Id.String()), attribute.String("debate.command", command))
+	defer span.End()
 
-		// Handle special engage command
-		if messageText == "__ENGAGE__" && client.CanSend {
-			s.HandleClientEngage(ch, client)
-			continue
-		}
+	ch.Actor.Do(func() {
+		span.AddEvent("dequeued") // Time before this was spent waiting for the channel's actor
+		s.handleInput(ctx, ch, client, messageText)
+	})
+}
 
-		if !client.CanSend {
-			// Notify the client they are read-only
-			c.WriteJSON(models.Message{
-				SenderType: "system",
-				SenderName: "system",
-				Text:       "You are read-only in this room",
-				Timestamp:  time.Now(),
-			})
-			continue
-		}
+func (s *ChannelService) handleInput(ctx context.Context, ch *models.Channel, client *models.Client, messageText string) {
+	if !s.allowInput(ch, client) {
+		return
+	}
+
+	// Protocol commands look like __NAME__ or __NAME__:arg
+	if name, arg, ok := parseCommand(messageText); ok {
+		s.handleCommand(ctx, ch, client, name, arg)
+		return
+	}
 
+	archived := ch.Status == models.ChannelArchived
+	paused := ch.Pause != nil
 
-		msg := models.Message{
-			SenderType: "user",
-			SenderName: client.Name,
-			Text:       messageText,
+	if !client.CanSend {
+		// Spectators talk in the side-chat, never on the debate floor
+		s.HandleSpectatorChat(ch, client, messageText)
+		return
+	}
+
+	if archived {
+		s.deliver(ch, client, models.Message{
+			SenderType: "system",
+			SenderName: "system",
+			Text:       "🏁 This debate has concluded. The transcript is read-only.",
 			Timestamp:  time.Now(),
-		}
-		
-		// During active debate phases (1-5), store messages as pending
-		ch.Mu.Lock()
-		currentPhase := ch.Phase.Id
-		ch.Mu.Unlock()
-		
-		if currentPhase >= 1 && currentPhase <= 5 {
-			s.handlePhaseMessage(ch, msg, client)
-		} else {
-			// In phase 0 (lobby), broadcast immediately
-			s.BroadcastMessage(ch, msg)
-		}
+		})
+		return
 	}
-}
 
-// handlePhaseMessage handles messages during active debate phases
-func (s *ChannelService) handlePhaseMessage(ch *models.Channel, msg models.Message, client *models.Client) {
-	ch.Mu.Lock()
+	if paused {
+		s.notifyClient(ch, client, "⏸️ The debate is paused. Your response was not submitted; send it again once the clock restarts.")
+		return
+	}
+
+	msg := models.Message{
+		SenderType: "user",
+		SenderName: client.Name,
+		Text:       messageText,
+		Timestamp:  time.Now(),
+	}
 	
+	// During active debate phases (1-5), store messages as pending
 	currentPhase := ch.Phase.Id
-	phaseKey := fmt.Sprintf("phase_%d", currentPhase)
 	
-	// Initialize phase participants if needed
-	if ch.PhaseParticipants[phaseKey] == nil {
-		ch.PhaseParticipants[phaseKey] = make(map[string]bool)
+	if currentPhase >= 1 && currentPhase <= 5 {
+		s.handlePhaseMessage(ctx, ch, msg, client)
+	} else {
+		// In phase 0 (lobby), broadcast immediately
+		s.broadcast(ch, msg)
 	}
+}
+
+// handlePhaseMessage handles messages during active debate phases
+func (s *ChannelService) handlePhaseMessage(ctx context.Context, ch *models.Channel, msg models.Message, client *models.Client) {
+	currentPhase := ch.Phase.Id
+	key := phaseKey(currentPhase)
 	
 	// Check if this participant has already submitted for this phase
-	if ch.PhaseParticipants[phaseKey][client.Name] {
-		ch.Mu.Unlock()
+	if ch.PhaseParticipants[key][client.Name] {
 		// Already submitted, send notification
 		errorMsg := models.Message{
 			SenderType: "system",
@@ -178,15 +634,12 @@ func (s *ChannelService) handlePhaseMessage(ch *models.Channel, msg models.Messa
 			Timestamp:  time.Now(),
 		}
 		// Send only to this client
-		if client.Conn != nil {
-			client.Conn.WriteJSON(errorMsg)
-		}
+		s.deliver(ch, client, errorMsg)
 		return
 	}
 	
 	// Add message to pending and mark participant as contributed
-	ch.PendingMessages = append(ch.PendingMessages, msg)
-	ch.PhaseParticipants[phaseKey][client.Name] = true
+	record(ch, models.ChannelEvent{Type: models.Submitted, Client: client.Id, Name: client.Name, Message: &msg})
 	
 	// Send confirmation to this client only (not broadcast)
 	confirmMsg := models.Message{
@@ -195,9 +648,7 @@ func (s *ChannelService) handlePhaseMessage(ch *models.Channel, msg models.Messa
 		Text:       "✅ Your response has been submitted. Waiting for other participants...",
 		Timestamp:  time.Now(),
 	}
-	if client.Conn != nil {
-		client.Conn.WriteJSON(confirmMsg)
-	}
+	s.deliver(ch, client, confirmMsg)
 	
 	// Count active participants
 	activeParticipants := 0
@@ -208,18 +659,17 @@ func (s *ChannelService) handlePhaseMessage(ch *models.Channel, msg models.Messa
 	}
 	
 	// Check if all participants have submitted
-	if len(ch.PhaseParticipants[phaseKey]) >= activeParticipants {
+	if len(ch.PhaseParticipants[key]) >= activeParticipants {
 		// Release all pending messages simultaneously
 		pendingMsgs := make([]models.Message, len(ch.PendingMessages))
 		copy(pendingMsgs, ch.PendingMessages)
-		ch.PendingMessages = []models.Message{}
-		
-		// Unlock before broadcasting and phase completion
-		ch.Mu.Unlock()
+		record(ch, models.ChannelEvent{Type: models.PhaseReleased, Phase: currentPhase})
+		s.emitSubmissionStatus(ch)
 		
 		// Broadcast all pending messages
+		_, span := startSpan(ctx, ch, "channel.broadcast", attribute.Int("debate.messages", len(pendingMsgs)+1))
 		for _, msg := range pendingMsgs {
-			s.BroadcastMessage(ch, msg)
+			s.broadcast(ch, msg)
 		}
 		
 		// Notify that AI analysis is starting
@@ -229,21 +679,36 @@ func (s *ChannelService) handlePhaseMessage(ch *models.Channel, msg models.Messa
 			Text:       "🤖 AI Moderator is analyzing the responses...",
 			Timestamp:  time.Now(),
 		}
-		s.BroadcastMessage(ch, aiStartMsg)
+		s.broadcast(ch, aiStartMsg)
+		span.End()
 		
 		// Handle phase completion
-		s.handlePhaseCompletion(ch, currentPhase)
+		s.handlePhaseCompletion(ctx, ch, currentPhase)
 		return
 	}
 	
-	ch.Mu.Unlock()
+	s.emitSubmissionStatus(ch)
 }
 
 
 // HandleClientEngage marks a client as ready and checks if debate can start
-func (s *ChannelService) HandleClientEngage(ch *models.Channel, client *models.Client) {
-	ch.Mu.Lock()
-	client.Ready = true
+func (s *ChannelService) HandleClientEngage(ctx context.Context, ch *models.Channel, client *models.Client) {
+	if ch.Phase.Id != 0 || client.Ready {
+		// Debate already running or client already counted
+		return
+	}
+
+	// Sides go to debaters in the order they engage, unless already set by a previous round
+	side := ""
+	if ch.Sides[client.Name] == "" {
+		side = models.SideProposition
+		for _, taken := range ch.Sides {
+			if taken == models.SideProposition {
+				side = models.SideOpposition
+			}
+		}
+	}
+	record(ch, models.ChannelEvent{Type: models.Engaged, Client: client.Id, Name: client.Name, Side: side, Identity: playerIdentity(client.Session)})
 
 	// Count how many clients are ready
 	readyCount := 0
@@ -252,7 +717,6 @@ func (s *ChannelService) HandleClientEngage(ch *models.Channel, client *models.C
 			readyCount++
 		}
 	}
-	ch.Mu.Unlock()
 
 	// Announce that this client is ready
 	readyMsg := models.Message{
@@ -261,77 +725,114 @@ func (s *ChannelService) HandleClientEngage(ch *models.Channel, client *models.C
 		Text:       fmt.Sprintf("✅ %s is ready to engage! (%d/2 participants ready)", client.Name, readyCount),
 		Timestamp:  time.Now(),
 	}
-	s.BroadcastMessage(ch, readyMsg)
+	s.broadcast(ch, readyMsg)
+	s.emitReadinessChanged(ch)
 
-	// If both participants are ready, start the debate
+	// If both participants are ready, start the debate, unless it is scheduled for later
 	if readyCount == 2 {
-		battleStartMsg := models.Message{
-			SenderType: "system",
-			SenderName: "system",
-			Text:       "🎯 The debate battle begins! Two participants are now ready to engage. Let the discussion commence!",
-			Timestamp:  time.Now(),
-		}
-		ch.Mu.Lock()
-		ch.Phase = models.Phase{
-			Id:        1,
-			Name:      "Phase 1",
-			StartTime: time.Now(),
-			Duration:  3 * time.Minute,
+		startsAt := ch.StartsAt
+		if time.Now().Before(startsAt) {
+			s.broadcast(ch, models.Message{
+				SenderType: "system",
+				SenderName: "system",
+				Text:       fmt.Sprintf("⏰ Both debaters are ready. The debate begins at its scheduled start, %s.", startsAt.UTC().Format("15:04 MST")),
+				Timestamp:  time.Now(),
+			})
+			return
 		}
-		ch.Mu.Unlock()
-		s.BroadcastMessage(ch, battleStartMsg)
-		
-		// Announce Phase 1
-		phase1Msg := s.getPhaseMessage(1)
-		s.BroadcastMessage(ch, phase1Msg)
-		
-		// Initialize phase participant tracking
-		ch.PhaseParticipants = make(map[string]map[string]bool)
-		ch.PendingMessages = []models.Message{}
+		s.beginDebate(ctx, ch)
+	}
+}
+
+// beginDebate moves a channel out of the lobby into the first debate phase, reporting false if it already left it
+func (s *ChannelService) beginDebate(ctx context.Context, ch *models.Channel) bool {
+	battleStartMsg := models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       "🎯 The debate battle begins! Two participants are now ready to engage. Let the discussion commence!",
+		Timestamp:  time.Now(),
+	}
+	if ch.Phase.Id != 0 || ch.Status != models.ChannelOpen {
+		// Already started by the other path (engage or the scheduler)
+		return false
+	}
+	_, span := startSpan(ctx, ch, "debate.phase_transition", attribute.Int("debate.phase.from", 0), attribute.Int("debate.phase.to", 1))
+	defer span.End()
+	record(ch, models.ChannelEvent{Type: models.PhaseAdvanced, Phase: 1, DurationSeconds: int(s.phaseDuration(1).Seconds())})
+	sidesMsg := models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       s.describeSides(ch),
+		Timestamp:  time.Now(),
 	}
+	s.broadcast(ch, battleStartMsg)
+	s.broadcast(ch, sidesMsg)
+	
+	// Announce Phase 1
+	phase1Msg := s.getPhaseMessage(1)
+	s.broadcast(ch, phase1Msg)
+
+	s.emitPhaseChanged(ch)
+	s.emitSubmissionStatus(ch)
+	return true
 }
 
 
 // handlePhaseCompletion processes AI analysis when a phase is completed
-func (s *ChannelService) handlePhaseCompletion(ch *models.Channel, completedPhase int) {
+func (s *ChannelService) handlePhaseCompletion(ctx context.Context, ch *models.Channel, completedPhase int) {
+	// The span lasts until the next phase is announced, covering the AI request
+	ctx, span := startSpan(ctx, ch, "debate.phase_analysis", attribute.Int("debate.phase", completedPhase))
+
 	// Collect messages from the completed phase
 	phaseMessages := s.getPhaseMessages(ch, completedPhase)
 	
-	if len(phaseMessages) > 0 {
-		// Create context for AI analysis
-		context := s.createPhaseContext(completedPhase, phaseMessages)
-		
+	if len(phaseMessages) == 0 {
+		s.progressToNextPhase(ctx, ch)
+		span.End()
+		return
+	}
+
+	// Create context for AI analysis
+	transcript := s.createPhaseContext(completedPhase, phaseMessages)
+
+	// The request runs off the actor so the channel keeps serving its clients while the AI answers
+	go func() {
 		// Send AI request with phase-specific prompt
-		aiResponse, err := s.sendPhaseSpecificAIRequest(completedPhase, context)
+		aiResponse, usage, err := s.sendPhaseSpecificAIRequest(ctx, completedPhase, transcript)
 		if err != nil {
-			fmt.Printf("Error getting AI analysis: %v\n", err)
+			channelLog(ch).Error("AI phase analysis failed", "phase", completedPhase, "err", err)
 			aiResponse = "Unable to provide analysis at this time."
 		}
-		
-		// Broadcast AI analysis
-		aiMessage := models.Message{
-			SenderType: "ai",
-			SenderName: "AI Moderator",
-			Text:       fmt.Sprintf("📊 **Phase %d Analysis**: %s", completedPhase, aiResponse),
-			Timestamp:  time.Now(),
+
+		if !ch.Actor.Post(func() {
+			defer span.End()
+			record(ch, models.ChannelEvent{Type: models.AIUsageRecorded, Usage: &usage})
+			if ch.Phase.Id != completedPhase || ch.Status != models.ChannelOpen {
+				return
+			}
+			// Broadcast AI analysis
+			aiMessage := models.Message{
+				SenderType: "ai",
+				SenderName: "AI Moderator",
+				Text:       fmt.Sprintf("📊 **Phase %d Analysis**: %s", completedPhase, aiResponse),
+				Timestamp:  time.Now(),
+			}
+			s.broadcast(ch, aiMessage)
+
+			// Progress to next phase after AI analysis
+			s.progressToNextPhase(ctx, ch)
+		}) {
+			span.End()
 		}
-		s.BroadcastMessage(ch, aiMessage)
-	}
-	
-	// Progress to next phase after AI analysis
-	s.progressToNextPhase(ch)
+	}()
 }
 
 // getPhaseMessages collects user messages from the specified phase
 func (s *ChannelService) getPhaseMessages(ch *models.Channel, phaseId int) []models.Message {
-	ch.Mu.Lock()
-	defer ch.Mu.Unlock()
-	
 	var phaseMessages []models.Message
-	phaseKey := fmt.Sprintf("phase_%d", phaseId)
 	
 	// Get participants who contributed in this phase
-	participants := ch.PhaseParticipants[phaseKey]
+	participants := ch.PhaseParticipants[phaseKey(phaseId)]
 	
 	// Collect the most recent messages from each participant in this phase
 	for _, msg := range ch.Messages {
@@ -347,21 +848,7 @@ func (s *ChannelService) getPhaseMessages(ch *models.Channel, phaseId int) []mod
 
 // createPhaseContext creates context string for AI analysis
 func (s *ChannelService) createPhaseContext(phaseId int, messages []models.Message) string {
-	var phaseName string
-	switch phaseId {
-	case 1:
-		phaseName = "Opening Statements"
-	case 2:
-		phaseName = "Rebuttals"
-	case 3:
-		phaseName = "Questions"
-	case 4:
-		phaseName = "Answers"
-	case 5:
-		phaseName = "Closing Statements"
-	default:
-		phaseName = "Discussion"
-	}
+	phaseName := phaseTitle(phaseId)
 	
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("Phase %d - %s:\n\n", phaseId, phaseName))
@@ -397,6 +884,8 @@ func (s *ChannelService) parseJudgeResponse(response string) *models.JudgeReport
 		
 		// Match header to field
 		switch {
+		case strings.EqualFold(header, "winner"):
+			judgeReport.Winner, _, _ = strings.Cut(content, "\n")
 		case strings.Contains(strings.ToLower(header), "winner declaration"):
 			judgeReport.WinnerDeclaration = content
 		case strings.Contains(strings.ToLower(header), "argument analysis"):
@@ -418,7 +907,7 @@ func (s *ChannelService) parseJudgeResponse(response string) *models.JudgeReport
 }
 
 // sendPhaseSpecificAIRequest sends AI request with phase-appropriate prompt
-func (s *ChannelService) sendPhaseSpecificAIRequest(phaseId int, context string) (string, error) {
+func (s *ChannelService) sendPhaseSpecificAIRequest(ctx context.Context, phaseId int, transcript string) (string, models.AIUsage, error) {
 	var prompt string
 	
 	switch phaseId {
@@ -471,11 +960,14 @@ Keep your analysis balanced, constructive, and under 200 words.`
 		prompt = `You are a moderator in this discussion. Please provide a balanced summary of the main points discussed, highlighting different perspectives and any consensus reached. Keep it concise and neutral. And point out any potential out of topic or inappropriate comments.`
 	}
 
-	return SendAIRequestWithCustomPrompt(prompt, context)
+	return s.ai.SendAIRequestWithCustomPrompt(ctx, strconv.Itoa(phaseId), prompt, transcript)
 }
 
 // provideFinalAIJudgment provides final AI verdict after all phases
-func (s *ChannelService) provideFinalAIJudgment(ch *models.Channel) {
+func (s *ChannelService) provideFinalAIJudgment(ctx context.Context, ch *models.Channel) {
+	// The span lasts until the verdict is broadcast, covering the AI request
+	ctx, span := startSpan(ctx, ch, "debate.verdict")
+
 	// Notify that AI Judge is generating verdict
 	judgeStartMsg := models.Message{
 		SenderType: "system",
@@ -483,18 +975,28 @@ func (s *ChannelService) provideFinalAIJudgment(ch *models.Channel) {
 		Text:       "⚖️ AI Judge is evaluating the complete debate and preparing the final verdict...",
 		Timestamp:  time.Now(),
 	}
-	s.BroadcastMessage(ch, judgeStartMsg)
+	s.broadcast(ch, judgeStartMsg)
 	
 	// Collect all debate messages for comprehensive analysis
 	allDebateMessages := s.getAllDebateMessages(ch)
 	
-	if len(allDebateMessages) > 0 {
-		// Create comprehensive context for final judgment
-		context := s.createFinalJudgmentContext(allDebateMessages)
-		
-		// Get AI judgment
+	if len(allDebateMessages) == 0 {
+		s.concludeWithVerdict(ch, nil)
+		span.End()
+		return
+	}
+
+	// Create comprehensive context for final judgment
+	transcript := s.createFinalJudgmentContext(allDebateMessages)
+	debaters := strings.Join(debateParticipants(ch), ", ")
+
+	// Get AI judgment off the actor, as for the phase analyses
+	go func() {
 		judgmentPrompt := `You are an impartial AI judge evaluating this complete debate. Please provide your verdict using EXACTLY this structure with these section headers:
 
+#### Winner
+[Only the winner's name, exactly as written in this list: ` + debaters + `. Write "none" if neither side clearly won]
+
 #### Winner Declaration
 [Clearly state which participant won and provide a brief justification]
 
@@ -518,24 +1020,52 @@ func (s *ChannelService) provideFinalAIJudgment(ch *models.Channel) {
 
 Be decisive in your judgment while explaining your reasoning. Use the exact section headers above with #### formatting.`
 
-		aiJudgment, err := SendAIRequestWithCustomPrompt(judgmentPrompt, context)
+		aiJudgment, usage, err := s.ai.SendAIRequestWithCustomPrompt(ctx, "verdict", judgmentPrompt, transcript)
 		if err != nil {
-			fmt.Printf("Error getting AI judgment: %v\n", err)
+			channelLog(ch).Error("AI verdict failed", "err", err)
 			aiJudgment = "Unable to provide final judgment at this time."
 		}
 
+		if !ch.Actor.Post(func() {
+			defer span.End()
+			record(ch, models.ChannelEvent{Type: models.AIUsageRecorded, Usage: &usage})
+			if ch.Phase.Id != 5 || ch.Status != models.ChannelOpen {
+				return
+			}
+			s.concludeWithVerdict(ch, &aiJudgment)
+		}) {
+			span.End()
+		}
+	}()
+}
+
+// concludeWithVerdict broadcasts the judge's verdict, when there is one, and archives the round
+func (s *ChannelService) concludeWithVerdict(ch *models.Channel, aiJudgment *string) {
+	if aiJudgment != nil {
 		// Parse structured judgment
-		judgeData := s.parseJudgeResponse(aiJudgment)
+		judgeData := s.parseJudgeResponse(*aiJudgment)
+		judgeData.Winner = determineWinner(judgeData, debateParticipants(ch))
+
+		// Compare the audience swing with the judge's pick
+		verdictText := *aiJudgment
+		judgeData.Audience = audienceReport(ch, judgeData.Winner, debateParticipants(ch))
+		judgeData.Engagement = engagementReport(ch)
+		if judgeData.Audience != nil {
+			verdictText += "\n\n" + audienceSummary(judgeData.Audience)
+		}
+		if judgeData.Engagement != nil {
+			verdictText += "\n\n" + engagementSummary(judgeData.Engagement)
+		}
 		
 		// Broadcast final judgment with structured data
 		judgmentMessage := models.Message{
 			SenderType: "judge",
 			SenderName: "AI Judge",
-			Text:       fmt.Sprintf("⚖️ **FINAL VERDICT** ⚖️\n\n%s", aiJudgment),
+			Text:       fmt.Sprintf("⚖️ **FINAL VERDICT** ⚖️\n\n%s", verdictText),
 			Timestamp:  time.Now(),
 			JudgeData:  judgeData,
 		}
-		s.BroadcastMessage(ch, judgmentMessage)
+		s.broadcast(ch, judgmentMessage)
 	}
 
 	// Send conclusion message
@@ -545,14 +1075,13 @@ Be decisive in your judgment while explaining your reasoning. Use the exact sect
 		Text:       "🏁 The debate has concluded. Thank you for participating!",
 		Timestamp:  time.Now(),
 	}
-	s.BroadcastMessage(ch, endMsg)
+	s.broadcast(ch, endMsg)
+
+	s.archiveDebate(ch)
 }
 
 // getAllDebateMessages collects all user messages from the debate
 func (s *ChannelService) getAllDebateMessages(ch *models.Channel) []models.Message {
-	ch.Mu.Lock()
-	defer ch.Mu.Unlock()
-	
 	var debateMessages []models.Message
 	
 	// Collect all user messages (excluding system and ai messages)
@@ -608,43 +1137,38 @@ func (s *ChannelService) createFinalJudgmentContext(messages []models.Message) s
 }
 
 // progressToNextPhase advances the debate to the next phase
-func (s *ChannelService) progressToNextPhase(ch *models.Channel) {
-	ch.Mu.Lock()
-	
+func (s *ChannelService) progressToNextPhase(ctx context.Context, ch *models.Channel) {
 	nextPhaseId := ch.Phase.Id + 1
 	
 	if nextPhaseId > 5 {
 		// Debate concluded, get final AI judgment
-		ch.Mu.Unlock()
-		s.provideFinalAIJudgment(ch)
+		s.provideFinalAIJudgment(ctx, ch)
 		return
 	}
+	_, span := startSpan(ctx, ch, "debate.phase_transition", attribute.Int("debate.phase.from", ch.Phase.Id), attribute.Int("debate.phase.to", nextPhaseId))
+	defer span.End()
 
-	var nextDuration time.Duration
-	switch nextPhaseId {
-	case 2, 3, 4:
-		nextDuration = 2 * time.Minute
-	case 5:
-		nextDuration = 3 * time.Minute
-	default:
-		nextDuration = 0
-	}
-
-	ch.Phase = models.Phase{
-		Id:        nextPhaseId,
-		Name:      fmt.Sprintf("Phase %d", nextPhaseId),
-		StartTime: time.Now(),
-		Duration:  nextDuration,
-	}
-
-	// Clear participant tracking for new phase
-	phaseKey := fmt.Sprintf("phase_%d", nextPhaseId)
-	ch.PhaseParticipants[phaseKey] = make(map[string]bool)
-
+	record(ch, models.ChannelEvent{Type: models.PhaseAdvanced, Phase: nextPhaseId, DurationSeconds: int(s.phaseDuration(nextPhaseId).Seconds())})
+	
 	// Announce new phase
 	phaseMsg := s.getPhaseMessage(nextPhaseId)
-	ch.Mu.Unlock()
-	s.BroadcastMessage(ch, phaseMsg)
+	s.broadcast(ch, phaseMsg)
+	s.emitPhaseChanged(ch)
+	s.emitSubmissionStatus(ch)
+}
+
+// phaseDuration is the configured speaking time of a debate phase, 1 to 5
+func (s *ChannelService) phaseDuration(phaseId int) time.Duration {
+	return s.debate.PhaseDurations[phaseId-1].Duration
+}
+
+// phaseLength describes a phase's speaking time for announcements, e.g. "3 minutes" or "90 seconds"
+func (s *ChannelService) phaseLength(phaseId int) string {
+	d := s.phaseDuration(phaseId)
+	if d%time.Minute != 0 {
+		return fmt.Sprintf("%d seconds", int(d.Seconds()))
+	}
+	return formatCountdown(d)
 }
 
 // getPhaseMessage returns the appropriate message for a phase
@@ -656,35 +1180,35 @@ func (s *ChannelService) getPhaseMessage(phaseId int) models.Message {
 		phaseMsg = models.Message{
 			SenderType: "system",
 			SenderName: "system",
-			Text:       "📢 Phase 1: Opening Statements - Each participant presents their initial arguments. You have 3 minutes each.",
+			Text:       fmt.Sprintf("📢 Phase 1: Opening Statements - Each participant presents their initial arguments. You have %s each.", s.phaseLength(1)),
 			Timestamp:  time.Now(),
 		}
 	case 2:
 		phaseMsg = models.Message{
 			SenderType: "system",
 			SenderName: "system",
-			Text:       "🔄 Phase 2: Rebuttals - Participants respond to each other's opening statements. You have 2 minutes each.",
+			Text:       fmt.Sprintf("🔄 Phase 2: Rebuttals - Participants respond to each other's opening statements. You have %s each.", s.phaseLength(2)),
 			Timestamp:  time.Now(),
 		}
 	case 3:
 		phaseMsg = models.Message{
 			SenderType: "system",
 			SenderName: "system",
-			Text:       "❓ Phase 3: Questions - Participants ask each other questions. You have 2 minutes per question.",
+			Text:       fmt.Sprintf("❓ Phase 3: Questions - Participants ask each other questions. You have %s per question.", s.phaseLength(3)),
 			Timestamp:  time.Now(),
 		}
 	case 4:
 		phaseMsg = models.Message{
 			SenderType: "system",
 			SenderName: "system",
-			Text:       "💬 Phase 4: Answering Questions - Participants answer the questions posed. You have 2 minutes per answer.",
+			Text:       fmt.Sprintf("💬 Phase 4: Answering Questions - Participants answer the questions posed. You have %s per answer.", s.phaseLength(4)),
 			Timestamp:  time.Now(),
 		}
 	case 5:
 		phaseMsg = models.Message{
 			SenderType: "system",
 			SenderName: "system",
-			Text:       "🎯 Phase 5: Closing Statements - Each participant summarizes their key points. You have 3 minutes each.",
+			Text:       fmt.Sprintf("🎯 Phase 5: Closing Statements - Each participant summarizes their key points. You have %s each.", s.phaseLength(5)),
 			Timestamp:  time.Now(),
 		}
 	default:
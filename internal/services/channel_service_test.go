@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/config"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+func newTestService(t *testing.T) *ChannelService {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Limits.MessageRate = config.Rate{} // The clients below send far faster than people do
+	cfg.Limits.MaxChannelClients = 1000
+	s := NewChannelService(&models.ChannelManager{
+		Channels: make(map[string]*models.Channel),
+		Archives: make(map[string][]*models.Archive),
+	})
+	s.Configure(cfg)
+	return s
+}
+
+// inChannel reads the channel's state on its actor
+func inChannel(ch *models.Channel, fn func()) {
+	ch.Actor.Do(fn)
+}
+
+// waitGroup fails the test if wg is not done within timeout, which means a command deadlocked
+func waitGroup(t *testing.T, wg *sync.WaitGroup, timeout time.Duration, stage string) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatalf("%s: clients still running after %s", stage, timeout)
+	}
+}
+
+// TestChannelConcurrentClients drives one channel from many goroutines at once, as its sockets do,
+// while others read it the way the API and metrics do. Run it with -race.
+func TestChannelConcurrentClients(t *testing.T) {
+	const spectators = 50
+
+	s := newTestService(t)
+	ch, err := s.CreateChannel(models.ChannelOptions{Name: "stress", Password: "pw", Motion: "Actors beat locks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	debaters := []*models.Client{
+		{Id: uuid.New(), Name: "ann", CanSend: true},
+		{Id: uuid.New(), Name: "bob", CanSend: true},
+	}
+	audience := make([]*models.Client, spectators)
+	for i := range audience {
+		audience[i] = &models.Client{Id: uuid.New(), Name: fmt.Sprintf("viewer%d", i)}
+	}
+
+	// Readers poll the channel throughout, as the listing, admin pages and metrics scrapes do
+	stopReading := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stopReading:
+					return
+				default:
+				}
+				s.ListChannels(models.ChannelQuery{})
+				s.ChannelState(ch)
+				s.Summarize(ch)
+				s.MessagePage(ch, 0, 20)
+				s.AdminChannels()
+			}
+		}()
+	}
+
+	// Everyone joins at once; spectators vote on the motion before the debate
+	var wg sync.WaitGroup
+	for _, c := range append(append([]*models.Client{}, debaters...), audience...) {
+		wg.Add(1)
+		go func(c *models.Client) {
+			defer wg.Done()
+			if err := s.AddClient(ch, c); err != nil {
+				t.Errorf("adding %s: %v", c.Name, err)
+				return
+			}
+			if !c.CanSend {
+				s.HandleInput(ctx, ch, c, "__VOTE__:pre:for")
+				s.HandleInput(ctx, ch, c, "lobby chatter")
+			}
+		}(c)
+	}
+	waitGroup(t, &wg, 10*time.Second, "joining")
+
+	inChannel(ch, func() {
+		if got := len(ch.Clients); got != spectators+2 {
+			t.Errorf("clients after joining = %d, want %d", got, spectators+2)
+		}
+		if got := len(ch.Votes.Pre); got != spectators {
+			t.Errorf("pre-debate ballots = %d, want %d", got, spectators)
+		}
+	})
+
+	// The debaters engage and submit while the audience votes, reacts and half of it leaves
+	for _, c := range debaters {
+		wg.Add(1)
+		go func(c *models.Client) {
+			defer wg.Done()
+			s.HandleInput(ctx, ch, c, "__ENGAGE__")
+			for i := 0; i < 100; i++ {
+				s.HandleInput(ctx, ch, c, fmt.Sprintf("%s argues point %d", c.Name, i))
+			}
+		}(c)
+	}
+	for i, c := range audience {
+		wg.Add(1)
+		go func(i int, c *models.Client) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				s.HandleInput(ctx, ch, c, "__VOTE__:phase:1:ann")
+				s.HandleInput(ctx, ch, c, "__REACT__:👏")
+				s.HandleInput(ctx, ch, c, "side chat")
+			}
+			if i%2 == 0 {
+				s.RemoveClient(ch, c)
+			}
+		}(i, c)
+	}
+	waitGroup(t, &wg, 30*time.Second, "debating")
+
+	inChannel(ch, func() {
+		if got := len(ch.Clients); got != spectators/2+2 {
+			t.Errorf("clients after half the audience left = %d, want %d", got, spectators/2+2)
+		}
+		if ch.Phase.Id < 1 {
+			t.Errorf("phase = %d, want the debate to have started", ch.Phase.Id)
+		}
+	})
+
+	// The channel is deleted while the rest of the audience is still sending and leaving
+	for i, c := range audience {
+		if i%2 == 0 {
+			continue // Already left
+		}
+		wg.Add(1)
+		go func(c *models.Client) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				s.HandleInput(ctx, ch, c, "__REACT__:🔥")
+			}
+			s.RemoveClient(ch, c)
+		}(c)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if !s.DeleteChannel("stress") {
+			t.Error("deleting the channel reported it missing")
+		}
+	}()
+	waitGroup(t, &wg, 10*time.Second, "deleting")
+
+	close(stopReading)
+	waitGroup(t, &readers, 10*time.Second, "reading")
+
+	if s.GetChannel("stress") != nil {
+		t.Error("channel still listed after being deleted")
+	}
+	select {
+	case <-ch.Actor.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("channel actor still running after the channel was deleted")
+	}
+}
+
+// TestMessagesEncodedWhileReacting encodes message pages and the admin view off the actor, as the API
+// does, while the audience keeps reacting to the same messages. Run it with -race.
+func TestMessagesEncodedWhileReacting(t *testing.T) {
+	s := newTestService(t)
+	ch, err := s.CreateChannel(models.ChannelOptions{Name: "reactions", Password: "pw", Motion: "Copies are cheap"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.DeleteChannel("reactions")
+	ctx := context.Background()
+
+	ann := &models.Client{Id: uuid.New(), Name: "ann", CanSend: true}
+	audience := make([]*models.Client, 10)
+	for i := range audience {
+		audience[i] = &models.Client{Id: uuid.New(), Name: fmt.Sprintf("viewer%d", i)}
+	}
+	for _, c := range append([]*models.Client{ann}, audience...) {
+		if err := s.AddClient(ch, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.HandleInput(ctx, ch, ann, "Applaud this")
+	var messageId string
+	inChannel(ch, func() {
+		for _, msg := range ch.Messages {
+			if msg.SenderType == "user" {
+				messageId = msg.Id
+			}
+		}
+	})
+	if messageId == "" {
+		t.Fatal("the debater's message was not released")
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range audience {
+		wg.Add(1)
+		go func(c *models.Client) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				s.HandleInput(ctx, ch, c, "__REACT__:"+messageId+":👏")
+			}
+		}(c)
+	}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				page, _ := s.MessagePage(ch, 0, 20)
+				if _, err := json.Marshal(page); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := json.Marshal(s.AdminChannel(ch)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	waitGroup(t, &wg, 30*time.Second, "reacting")
+
+	// Every viewer reacted an even number of times, taking each reaction back
+	page, _ := s.MessagePage(ch, 0, 20)
+	for _, msg := range page {
+		if msg.Id == messageId && len(msg.Reactions) != 0 {
+			t.Errorf("reactions = %v, want none left", msg.Reactions)
+		}
+	}
+}
+
+// TestArchivesKeptPerChannel checks that a channel reusing a name does not inherit the rounds of the
+// one that had it before, and that archives are capped per channel and pruned once old enough
+func TestArchivesKeptPerChannel(t *testing.T) {
+	s := newTestService(t)
+	s.maxArchives = 2
+	keep := func(channelId uuid.UUID, round int, concluded time.Time) {
+		s.Manager.Mu.Lock()
+		s.keepArchive(&models.Archive{ChannelId: channelId, ChannelName: "reused", Round: round, ConcludedAt: concluded})
+		s.Manager.Mu.Unlock()
+	}
+	rounds := func() []int {
+		var rounds []int
+		for _, archive := range s.GetArchives("reused") {
+			rounds = append(rounds, archive.Round)
+		}
+		return rounds
+	}
+
+	now, deleted := time.Now(), uuid.New()
+	for round := 1; round <= 3; round++ {
+		keep(deleted, round, now)
+	}
+	if got := rounds(); fmt.Sprint(got) != "[2 3]" {
+		t.Errorf("rounds kept = %v, want the latest two", got)
+	}
+
+	ch, err := s.CreateChannel(models.ChannelOptions{Name: "reused", Password: "pw", Motion: "Names are reused"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.DeleteChannel("reused")
+	if got := rounds(); len(got) != 0 {
+		t.Errorf("new channel starts with rounds %v of the one that had its name", got)
+	}
+
+	keep(deleted, 4, now)
+	keep(ch.ChannelId, 1, now.Add(-2*s.archiveRetention))
+	keep(ch.ChannelId, 2, now)
+	if got := rounds(); fmt.Sprint(got) != "[1 2]" {
+		t.Errorf("rounds = %v, want only the open channel's", got)
+	}
+
+	if pruned := s.PruneArchives(); pruned != 1 {
+		t.Errorf("pruned %d rounds, want the one past retention", pruned)
+	}
+	if got := rounds(); fmt.Sprint(got) != "[2]" {
+		t.Errorf("rounds after pruning = %v, want [2]", got)
+	}
+}
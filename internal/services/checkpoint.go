@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/store"
+)
+
+const (
+	checkpointDocument = "checkpoint"
+
+	restartReason = "server restarting"
+)
+
+// Draining reports whether Shutdown has begun, after which new sockets are refused
+func (s *ChannelService) Draining() bool {
+	return s.draining.Load()
+}
+
+// Shutdown drains the service before a restart: every channel is told about the maintenance and
+// checkpointed to st, and its sockets are closed with CloseServiceRestart so clients know to reconnect
+func (s *ChannelService) Shutdown(st *store.Store) error {
+	s.draining.Store(true)
+
+	s.Manager.Mu.Lock()
+	channels := make([]*models.Channel, 0, len(s.Manager.Channels))
+	for _, ch := range s.Manager.Channels {
+		channels = append(channels, ch)
+	}
+	archives := make(map[string][]*models.Archive, len(s.Manager.Archives))
+	for name, list := range s.Manager.Archives {
+		archives[name] = append([]*models.Archive(nil), list...)
+	}
+	s.Manager.Mu.Unlock()
+
+	checkpoint := models.Checkpoint{
+		Channels: make([]models.ChannelCheckpoint, 0, len(channels)),
+		Archives: archives,
+	}
+	for _, ch := range channels {
+		ch.Actor.Do(func() {
+			s.broadcast(ch, models.Message{
+				SenderType: "system",
+				SenderName: "system",
+				Text:       "🛠️ The server is restarting for maintenance. The debate has been saved and resumes where it left off; you will be reconnected shortly.",
+				Timestamp:  time.Now(),
+			})
+			clients := channelClients(ch)
+			for _, c := range clients {
+				record(ch, models.ChannelEvent{Type: models.ClientLeft, Client: c.Id, Name: c.Name})
+			}
+			clearPauseTimer(ch)
+			checkpoint.Channels = append(checkpoint.Channels, checkpointChannel(ch))
+			s.disconnect(ch, clients, websocket.CloseServiceRestart, restartReason)
+		})
+		ch.Actor.Stop()
+		s.releaseChannel(ch.Name)
+	}
+	s.closeRelays(websocket.CloseServiceRestart, restartReason)
+
+	checkpoint.SavedAt = time.Now()
+	if err := st.Save(checkpointDocument, checkpoint); err != nil {
+		return err
+	}
+	slog.Info("channels checkpointed", "channels", len(checkpoint.Channels))
+	return nil
+}
+
+// checkpointChannel saves the channel's event log, from which the rest of its state is rebuilt; runs on the channel's actor
+func checkpointChannel(ch *models.Channel) models.ChannelCheckpoint {
+	return models.ChannelCheckpoint{
+		Events:   ch.Events,
+		Password: ch.Password,
+		OwnerKey: ch.OwnerKey,
+	}
+}
+
+// clearPauseTimer stops a running timeout's timer without ending the pause; runs on the channel's actor
+func clearPauseTimer(ch *models.Channel) {
+	if ch.Pause != nil && ch.Pause.ResumeTimer != nil {
+		ch.Pause.ResumeTimer.Stop()
+	}
+}
+
+// Restore re-opens the channels checkpointed by the last Shutdown. Phase clocks and timeouts move on by
+// the downtime so nobody loses time, and a phase analysis cut off by the shutdown is requested again.
+func (s *ChannelService) Restore(st *store.Store) (int, error) {
+	var checkpoint models.Checkpoint
+	found, err := st.Load(checkpointDocument, &checkpoint)
+	if err != nil || !found {
+		return 0, err
+	}
+	downtime := time.Since(checkpoint.SavedAt)
+
+	s.Manager.Mu.Lock()
+	for name, archives := range checkpoint.Archives {
+		s.Manager.Archives[name] = append(archives, s.Manager.Archives[name]...)
+	}
+	s.Manager.Mu.Unlock()
+
+	restored := 0
+	for _, saved := range checkpoint.Channels {
+		ch := restoreChannel(saved, downtime)
+		if err := s.openChannel(ch); err != nil {
+			channelLog(ch).Warn("not restoring checkpointed channel", "err", err)
+			continue
+		}
+
+		ch.Actor.Do(func() {
+			if ch.Pause != nil && !ch.Pause.AutoResume.IsZero() {
+				s.scheduleResume(ch, ch.Pause)
+			}
+			s.broadcast(ch, models.Message{
+				SenderType: "system",
+				SenderName: "system",
+				Text:       fmt.Sprintf("🔄 The server is back after %s of maintenance. The clock was stopped meanwhile.", downtime.Round(time.Second)),
+				Timestamp:  time.Now(),
+			})
+			if awaitingAnalysis(ch) {
+				s.handlePhaseCompletion(context.Background(), ch, ch.Phase.Id)
+			}
+		})
+		restored++
+		channelLog(ch).Info("channel restored from checkpoint")
+	}
+
+	// The checkpoint is used once, so a later crash cannot bring back this state
+	if err := st.Delete(checkpointDocument); err != nil {
+		return restored, err
+	}
+	return restored, nil
+}
+
+// restoreChannel rebuilds a checkpointed channel with its clocks moved on by downtime
+func restoreChannel(saved models.ChannelCheckpoint, downtime time.Duration) *models.Channel {
+	ch := RebuildChannel(saved.Events)
+	ch.Password = saved.Password
+	ch.OwnerKey = saved.OwnerKey
+	record(ch, models.ChannelEvent{Type: models.ServerRestarted, Downtime: downtime})
+	return ch
+}
+
+// awaitingAnalysis reports whether the current phase was released but the AI moderator had not
+// moved the debate on yet; runs on the channel's actor
+func awaitingAnalysis(ch *models.Channel) bool {
+	if !debateActive(ch) {
+		return false
+	}
+	for i := len(ch.Events) - 1; i >= 0; i-- {
+		switch ch.Events[i].Type {
+		case models.PhaseReleased:
+			return true
+		case models.PhaseAdvanced:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,597 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/broker"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/socket"
+)
+
+const (
+	// ChannelLeaseTTL is how long an instance keeps a channel after it stops renewing the lease
+	ChannelLeaseTTL = 15 * time.Second
+
+	brokerTimeout = 5 * time.Second
+
+	movedReason = "channel moved"
+
+	instancesTopic = "instances"
+)
+
+// Channels live on the instance holding their lease, which alone drives the debate. Sockets that
+// land on another instance are relayed to the owner over the channel's topic: the owner sees them
+// as clients without a Conn and publishes what it writes to them back to the relaying instance.
+
+// ErrChannelOwnedElsewhere is returned when creating a channel whose lease another instance holds
+var ErrChannelOwnedElsewhere = errors.New("channel is open on another instance")
+
+// ownedChannel is a channel leased by this instance
+type ownedChannel struct {
+	stop    func()    // Ends the subscription to the channel's topic
+	renewed time.Time // When the lease was last taken or extended; it lapses leaseTTL after
+}
+
+// remoteInstance is what another instance last announced; it is forgotten once the announcement is
+// older than a lease, as the instance's channels may have moved or gone since
+type remoteInstance struct {
+	announcement models.InstanceAnnouncement
+	expires      time.Time
+}
+
+// relayedChannel holds the sockets this instance relays for a channel owned elsewhere
+type relayedChannel struct {
+	conns  map[uuid.UUID]*socket.Writer
+	cancel func()
+}
+
+func channelTopic(name string) string {
+	return "channel:" + name
+}
+
+func channelLease(name string) string {
+	return "channel:" + name
+}
+
+// UseBroker replaces the in-process broker; call it before any channel is created
+func (s *ChannelService) UseBroker(b broker.Broker) {
+	s.clusterMu.Lock()
+	s.broker = b
+	s.clusterMu.Unlock()
+}
+
+// Instance identifies this server among the instances sharing the broker
+func (s *ChannelService) Instance() string {
+	return s.broker.Instance()
+}
+
+// StartLeaseRenewal extends the leases of the channels owned here every third of ttl until ctx is
+// cancelled, announcing them to the other instances each time
+func (s *ChannelService) StartLeaseRenewal(ctx context.Context, ttl time.Duration) {
+	s.clusterMu.Lock()
+	s.leaseTTL = ttl
+	s.clusterMu.Unlock()
+
+	if err := s.watchInstances(ctx); err != nil {
+		slog.Error("subscribing to instance announcements, other instances' channels will not be listed", "err", err)
+	}
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		s.announceChannels()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.renewLeases()
+				s.announceChannels()
+			}
+		}
+	}()
+}
+
+// watchInstances keeps what the other instances announce until ctx is cancelled
+func (s *ChannelService) watchInstances(ctx context.Context) error {
+	subscribeCtx, cancel := context.WithTimeout(ctx, brokerTimeout)
+	defer cancel()
+	stop, err := s.broker.Subscribe(subscribeCtx, instancesTopic, s.handleAnnouncement)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return nil
+}
+
+func (s *ChannelService) handleAnnouncement(payload []byte) {
+	var announcement models.InstanceAnnouncement
+	if err := json.Unmarshal(payload, &announcement); err != nil {
+		slog.Warn("dropping malformed instance announcement", "err", err)
+		return
+	}
+	if announcement.Instance == s.broker.Instance() {
+		return
+	}
+	for i := range announcement.Channels {
+		announcement.Channels[i].Instance = announcement.Instance
+	}
+
+	s.clusterMu.Lock()
+	s.instances[announcement.Instance] = &remoteInstance{announcement: announcement, expires: time.Now().Add(s.leaseTTL)}
+	s.clusterMu.Unlock()
+}
+
+// announceChannels publishes the summaries of the channels owned here, and where this instance can be reached
+func (s *ChannelService) announceChannels() {
+	s.clusterMu.Lock()
+	names := make([]string, 0, len(s.owned))
+	for name := range s.owned {
+		names = append(names, name)
+	}
+	s.clusterMu.Unlock()
+
+	announcement := models.InstanceAnnouncement{
+		Instance:     s.broker.Instance(),
+		AdvertiseURL: s.advertise,
+		Channels:     make([]models.ChannelSummary, 0, len(names)),
+	}
+	for _, name := range names {
+		if ch := s.GetChannel(name); ch != nil {
+			announcement.Channels = append(announcement.Channels, s.Summarize(ch))
+		}
+	}
+	payload, err := json.Marshal(announcement)
+	if err != nil {
+		slog.Error("encoding instance announcement", "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), brokerTimeout)
+	defer cancel()
+	if err := s.broker.Publish(ctx, instancesTopic, payload); err != nil {
+		slog.Error("announcing channels", "err", err)
+	}
+}
+
+// remoteSummaries returns the channels other instances last announced, leaving out those open here
+func (s *ChannelService) remoteSummaries() []models.ChannelSummary {
+	now := time.Now()
+	s.clusterMu.Lock()
+	var summaries []models.ChannelSummary
+	for id, instance := range s.instances {
+		if now.After(instance.expires) {
+			delete(s.instances, id)
+			continue
+		}
+		summaries = append(summaries, instance.announcement.Channels...)
+	}
+	s.clusterMu.Unlock()
+
+	s.Manager.Mu.Lock()
+	defer s.Manager.Mu.Unlock()
+	remote := summaries[:0]
+	for _, summary := range summaries {
+		if s.Manager.Channels[summary.Name] == nil {
+			// Announced before it moved here
+			remote = append(remote, summary)
+		}
+	}
+	return remote
+}
+
+// ChannelLocation returns the instance owning a channel that is not open here, and the URL it
+// announced for reaching it directly; both are empty when no instance owns the channel
+func (s *ChannelService) ChannelLocation(name string) (instance, advertiseURL string) {
+	instance = s.RemoteOwner(name)
+	if instance == "" {
+		return "", ""
+	}
+	s.clusterMu.Lock()
+	defer s.clusterMu.Unlock()
+	if remote := s.instances[instance]; remote != nil {
+		advertiseURL = remote.announcement.AdvertiseURL
+	}
+	return instance, advertiseURL
+}
+
+// renewLeases extends the lease of every channel owned here. A channel whose lease went to another
+// instance, or lapsed because the broker could not be reached, is given up, so that only one
+// instance ever drives a debate.
+func (s *ChannelService) renewLeases() {
+	s.clusterMu.Lock()
+	names := make([]string, 0, len(s.owned))
+	for name := range s.owned {
+		names = append(names, name)
+	}
+	ttl := s.leaseTTL
+	s.clusterMu.Unlock()
+
+	for _, name := range names {
+		ctx, cancel := context.WithTimeout(context.Background(), brokerTimeout)
+		held, err := s.broker.Acquire(ctx, channelLease(name), ttl)
+		cancel()
+
+		s.clusterMu.Lock()
+		owned := s.owned[name]
+		lapsed := owned != nil && time.Since(owned.renewed) >= ttl
+		if owned != nil && err == nil && held {
+			owned.renewed = time.Now()
+		}
+		s.clusterMu.Unlock()
+		if owned == nil {
+			continue // Released meanwhile
+		}
+
+		switch {
+		case err == nil && !held:
+			slog.Warn("lease taken by another instance, giving up channel", "channel", name)
+			s.dropChannel(name)
+		case err != nil && lapsed:
+			slog.Error("lease lapsed while the broker was unreachable, giving up channel", "channel", name, "err", err)
+			s.dropChannel(name)
+		case err != nil:
+			slog.Error("renewing lease", "channel", name, "err", err)
+		}
+	}
+}
+
+// claimChannel takes the lease on a channel created here and listens on its topic for relayed
+// sockets. It returns ErrChannelOwnedElsewhere when another instance holds the lease.
+func (s *ChannelService) claimChannel(name string) error {
+	s.clusterMu.Lock()
+	defer s.clusterMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), brokerTimeout)
+	defer cancel()
+	held, err := s.broker.Acquire(ctx, channelLease(name), s.leaseTTL)
+	if err != nil {
+		return fmt.Errorf("acquiring lease: %w", err)
+	}
+	if !held {
+		return ErrChannelOwnedElsewhere
+	}
+
+	if owned, ok := s.owned[name]; ok {
+		owned.stop()
+	}
+	stop, err := s.broker.Subscribe(ctx, channelTopic(name), func(payload []byte) {
+		s.handleRelayed(name, payload)
+	})
+	if err != nil {
+		if err := s.broker.Release(ctx, channelLease(name)); err != nil {
+			slog.Error("releasing lease", "channel", name, "err", err)
+		}
+		return fmt.Errorf("subscribing to channel topic: %w", err)
+	}
+	s.owned[name] = &ownedChannel{stop: stop, renewed: time.Now()}
+	return nil
+}
+
+// dropChannel stops running a channel whose lease this instance lost. Its sockets are closed with
+// CloseServiceRestart, so clients reconnect and are relayed to the instance that owns it now.
+func (s *ChannelService) dropChannel(name string) {
+	s.clusterMu.Lock()
+	owned, ok := s.owned[name]
+	delete(s.owned, name)
+	s.clusterMu.Unlock()
+	if ok {
+		owned.stop()
+	}
+
+	s.Manager.Mu.Lock()
+	ch := s.Manager.Channels[name]
+	delete(s.Manager.Channels, name)
+	s.Manager.Mu.Unlock()
+	if ch == nil {
+		return
+	}
+
+	ch.Actor.Do(func() {
+		s.broadcast(ch, models.Message{
+			SenderType: "system",
+			SenderName: "system",
+			Text:       "🔀 This debate moved to another server. Reconnecting...",
+			Timestamp:  time.Now(),
+		})
+		s.disconnect(ch, channelClients(ch), websocket.CloseServiceRestart, movedReason)
+	})
+	ch.Actor.Stop()
+	channelLog(ch).Warn("channel given up to another instance")
+}
+
+// releaseChannel gives up the lease and topic of a channel removed from this instance
+func (s *ChannelService) releaseChannel(name string) {
+	s.clusterMu.Lock()
+	owned, ok := s.owned[name]
+	delete(s.owned, name)
+	s.clusterMu.Unlock()
+	if !ok {
+		return
+	}
+	owned.stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), brokerTimeout)
+	defer cancel()
+	if err := s.broker.Release(ctx, channelLease(name)); err != nil {
+		slog.Error("releasing lease", "channel", name, "err", err)
+	}
+}
+
+// RemoteOwner returns the instance owning a channel that is not open here, or "" when none does
+func (s *ChannelService) RemoteOwner(name string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), brokerTimeout)
+	defer cancel()
+	owner, err := s.broker.Owner(ctx, channelLease(name))
+	if err != nil {
+		slog.Error("looking up channel owner", "channel", name, "err", err)
+		return ""
+	}
+	if owner == s.broker.Instance() {
+		return ""
+	}
+	return owner
+}
+
+// ChannelExists reports whether a channel is open on this or any other instance
+func (s *ChannelService) ChannelExists(name string) bool {
+	return s.GetChannel(name) != nil || s.RemoteOwner(name) != ""
+}
+
+// handleRelayed applies what relaying instances send about their sockets to a channel owned here
+func (s *ChannelService) handleRelayed(name string, payload []byte) {
+	var env models.RelayEnvelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		slog.Warn("dropping malformed relay envelope", "channel", name, "err", err)
+		return
+	}
+	ch := s.GetChannel(name)
+
+	switch env.Kind {
+	case models.RelayJoin:
+		if s.Draining() {
+			s.publishRelay(name, models.RelayEnvelope{Kind: models.RelayClose, To: []uuid.UUID{env.Client}, Code: websocket.CloseServiceRestart, Reason: restartReason})
+			return
+		}
+		if ch == nil {
+			s.publishRelay(name, models.RelayEnvelope{Kind: models.RelayClose, To: []uuid.UUID{env.Client}})
+			return
+		}
+		client := &models.Client{
+			Id:       env.Client,
+			Name:     env.Name,
+			Instance: env.Origin,
+			IP:       env.IP,
+			Session:  env.Session,
+		}
+		if env.Password != "" {
+			if !s.CheckPassword(ch, env.Password) {
+				s.publishRelay(name, models.RelayEnvelope{Kind: models.RelayClose, To: []uuid.UUID{env.Client}})
+				return
+			}
+			client.CanSend = true
+		}
+		for _, key := range env.OwnerKeys {
+			if s.IsOwner(ch, key) {
+				client.IsModerator = true
+			}
+		}
+		if err := s.AddClient(ch, client); err != nil {
+			frame, _ := json.Marshal(models.Event{Type: models.EventError, Channel: name, Timestamp: time.Now(), Data: s.ChannelFullError()})
+			s.publishRelay(name, models.RelayEnvelope{Kind: models.RelayFrame, To: []uuid.UUID{env.Client}, Frame: frame})
+			s.publishRelay(name, models.RelayEnvelope{Kind: models.RelayClose, To: []uuid.UUID{env.Client}, Code: websocket.CloseTryAgainLater, Reason: models.ErrorChannelFull})
+			return
+		}
+		channelLog(ch).Info("relayed client joined", "client_id", client.Id.String(), "client", client.Name, "instance", env.Origin)
+	case models.RelayInput, models.RelayLeave:
+		if ch == nil {
+			return
+		}
+		ch.Actor.Do(func() {
+			client := ch.Clients[env.Client]
+			if client == nil {
+				return
+			}
+			if env.Kind == models.RelayInput {
+				s.handleInput(context.Background(), ch, client, env.Text)
+			} else {
+				channelLog(ch).Info("relayed client left", "client_id", client.Id.String())
+				s.removeClient(ch, client)
+			}
+		})
+	}
+}
+
+// Relay connects a socket to a channel owned by another instance until the socket closes. The owner
+// checks the password, and ownerKeys for moderation, as if the socket had connected to it directly.
+func (s *ChannelService) Relay(name string, conn *websocket.Conn, clientName, password, ip, session string, ownerKeys []string) {
+	id := uuid.New()
+	out := socket.New(conn)
+	defer out.Stop()
+	if err := s.addRelay(name, id, out); err != nil {
+		slog.Error("relaying socket", "channel", name, "err", err)
+		return
+	}
+	defer s.removeRelay(name, id)
+
+	s.publishRelay(name, models.RelayEnvelope{
+		Kind:      models.RelayJoin,
+		Client:    id,
+		Name:      clientName,
+		IP:        ip,
+		Session:   session,
+		Password:  password,
+		OwnerKeys: ownerKeys,
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		s.publishRelay(name, models.RelayEnvelope{Kind: models.RelayInput, Client: id, Text: string(data)})
+	}
+	s.publishRelay(name, models.RelayEnvelope{Kind: models.RelayLeave, Client: id})
+}
+
+func (s *ChannelService) addRelay(name string, id uuid.UUID, out *socket.Writer) error {
+	s.clusterMu.Lock()
+	defer s.clusterMu.Unlock()
+
+	relayed := s.relayed[name]
+	if relayed == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), brokerTimeout)
+		defer cancel()
+		stop, err := s.broker.Subscribe(ctx, channelTopic(name), func(payload []byte) {
+			s.handleOwnerFrame(name, payload)
+		})
+		if err != nil {
+			return err
+		}
+		relayed = &relayedChannel{conns: make(map[uuid.UUID]*socket.Writer), cancel: stop}
+		s.relayed[name] = relayed
+	}
+	relayed.conns[id] = out
+	return nil
+}
+
+func (s *ChannelService) removeRelay(name string, id uuid.UUID) {
+	s.clusterMu.Lock()
+	relayed := s.relayed[name]
+	if relayed == nil {
+		s.clusterMu.Unlock()
+		return
+	}
+	delete(relayed.conns, id)
+	last := len(relayed.conns) == 0
+	if last {
+		delete(s.relayed, name)
+	}
+	s.clusterMu.Unlock()
+
+	if last {
+		relayed.cancel()
+	}
+}
+
+// handleOwnerFrame writes what the owner of a channel sends to the sockets relayed here
+func (s *ChannelService) handleOwnerFrame(name string, payload []byte) {
+	var env models.RelayEnvelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		slog.Warn("dropping malformed relay envelope", "channel", name, "err", err)
+		return
+	}
+	if env.Kind != models.RelayFrame && env.Kind != models.RelayClose {
+		return
+	}
+
+	s.clusterMu.Lock()
+	var conns []*socket.Writer
+	if relayed := s.relayed[name]; relayed != nil {
+		for _, id := range env.To {
+			if out := relayed.conns[id]; out != nil {
+				conns = append(conns, out)
+			}
+		}
+	}
+	s.clusterMu.Unlock()
+
+	for _, out := range conns {
+		switch {
+		case env.Kind == models.RelayFrame:
+			out.SendRaw(env.Frame)
+		case env.Code != 0:
+			out.Close(env.Code, env.Reason)
+		default:
+			out.Close(websocket.CloseNormalClosure, "")
+		}
+	}
+}
+
+// closeRelays closes every socket relayed from here, whose relay loops then tell the owners they left
+func (s *ChannelService) closeRelays(code int, reason string) {
+	s.clusterMu.Lock()
+	var conns []*socket.Writer
+	for _, relayed := range s.relayed {
+		for _, out := range relayed.conns {
+			conns = append(conns, out)
+		}
+	}
+	s.clusterMu.Unlock()
+
+	for _, out := range conns {
+		out.Close(code, reason)
+	}
+}
+
+func (s *ChannelService) publishRelay(name string, env models.RelayEnvelope) {
+	env.Origin = s.broker.Instance()
+	payload, err := json.Marshal(env)
+	if err != nil {
+		slog.Error("encoding relay envelope", "channel", name, "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), brokerTimeout)
+	defer cancel()
+	if err := s.broker.Publish(ctx, channelTopic(name), payload); err != nil {
+		slog.Error("publishing to channel topic", "channel", name, "err", err)
+	}
+}
+
+// deliver writes v to one client, relaying it when the socket is held by another instance
+func (s *ChannelService) deliver(ch *models.Channel, client *models.Client, v any) {
+	s.deliverAll(ch, []*models.Client{client}, v)
+}
+
+// deliverAll queues v on each client's socket, relaying it in one envelope to the sockets other
+// instances hold. Sockets are written by their own goroutines, so a slow client never holds up
+// the actor; one that falls too far behind is disconnected instead.
+func (s *ChannelService) deliverAll(ch *models.Channel, clients []*models.Client, v any) {
+	frame, err := json.Marshal(v)
+	if err != nil {
+		channelLog(ch).Error("encoding frame", "err", err)
+		return
+	}
+
+	var remote []uuid.UUID
+	for _, client := range clients {
+		if client.Socket != nil {
+			if err := client.Socket.SendRaw(frame); errors.Is(err, socket.ErrTooSlow) {
+				channelLog(ch).Warn("disconnecting slow client", "client_id", client.Id.String(), "client", client.Name)
+			}
+		} else if client.Instance != "" {
+			remote = append(remote, client.Id)
+		}
+	}
+	if len(remote) > 0 {
+		s.publishRelay(ch.Name, models.RelayEnvelope{Kind: models.RelayFrame, To: remote, Frame: frame})
+	}
+}
+
+// disconnect closes the clients' sockets with code, asking other instances to close the ones they relay
+func (s *ChannelService) disconnect(ch *models.Channel, clients []*models.Client, code int, reason string) {
+	var remote []uuid.UUID
+	for _, client := range clients {
+		if client.Socket != nil {
+			client.Socket.Close(code, reason)
+		} else if client.Instance != "" {
+			remote = append(remote, client.Id)
+		}
+	}
+	if len(remote) > 0 {
+		s.publishRelay(ch.Name, models.RelayEnvelope{Kind: models.RelayClose, To: remote, Code: code, Reason: reason})
+	}
+}
+
+// CloseSocket sends a close frame with code and reason, then closes a socket no writer was started for
+func CloseSocket(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	conn.Close()
+}
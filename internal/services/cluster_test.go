@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/broker"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// TestCreateChannelOnce checks that a name is taken by one channel only, whether the other is open on
+// this instance or on another one sharing the broker
+func TestCreateChannelOnce(t *testing.T) {
+	hub := broker.NewMemory("a")
+	a, b := newTestService(t), newTestService(t)
+	a.UseBroker(hub)
+	b.UseBroker(hub.Join("b"))
+
+	opts := models.ChannelOptions{Name: "once", Password: "pw", Motion: "Names are unique"}
+	first, err := a.CreateChannel(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.DeleteChannel("once")
+
+	if _, err := a.CreateChannel(opts); !errors.Is(err, ErrChannelExists) {
+		t.Errorf("creating it again here: err = %v, want %v", err, ErrChannelExists)
+	}
+	if _, err := b.CreateChannel(opts); !errors.Is(err, ErrChannelOwnedElsewhere) {
+		t.Errorf("creating it on another instance: err = %v, want %v", err, ErrChannelOwnedElsewhere)
+	}
+	if a.GetChannel("once") != first {
+		t.Error("the first channel was replaced")
+	}
+	if b.GetChannel("once") != nil {
+		t.Error("the refused channel was left open on the other instance")
+	}
+}
+
+// TestLostLeaseDropsChannel checks that an instance whose lease went elsewhere stops driving the channel
+func TestLostLeaseDropsChannel(t *testing.T) {
+	hub := broker.NewMemory("a")
+	s := newTestService(t)
+	s.UseBroker(hub)
+	s.leaseTTL = 50 * time.Millisecond
+
+	ch, err := s.CreateChannel(models.ChannelOptions{Name: "moved", Password: "pw", Motion: "Leases expire"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lease lapses, say during a network partition, and another instance takes it
+	time.Sleep(2 * s.leaseTTL)
+	if held, err := hub.Join("b").Acquire(context.Background(), channelLease("moved"), time.Minute); err != nil || !held {
+		t.Fatalf("other instance acquiring the lapsed lease: held %v, err %v", held, err)
+	}
+
+	s.renewLeases()
+	if s.GetChannel("moved") != nil {
+		t.Error("channel still open here after its lease went elsewhere")
+	}
+	select {
+	case <-ch.Actor.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("channel actor still running after its lease went elsewhere")
+	}
+	if owner := s.RemoteOwner("moved"); owner != "b" {
+		t.Errorf("remote owner = %q, want the instance holding the lease", owner)
+	}
+}
+
+// TestAnnouncedChannelsListed checks that channels open on another instance are listed here once it
+// announces them, and located on it with the URL it advertises
+func TestAnnouncedChannelsListed(t *testing.T) {
+	hub := broker.NewMemory("a")
+	a, b := newTestService(t), newTestService(t)
+	a.UseBroker(hub)
+	b.UseBroker(hub.Join("b"))
+	a.advertise = "http://a.internal:3000"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := b.watchInstances(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.CreateChannel(models.ChannelOptions{Name: "far", Password: "pw", Motion: "Lists span instances"}); err != nil {
+		t.Fatal(err)
+	}
+	defer a.DeleteChannel("far")
+	a.announceChannels()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var listing models.ChannelListing
+	for listing = b.ListChannels(models.ChannelQuery{}); listing.Total == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		listing = b.ListChannels(models.ChannelQuery{})
+	}
+	if listing.Total != 1 || listing.Channels[0].Name != "far" || listing.Channels[0].Instance != "a" {
+		t.Fatalf("listing on the other instance = %+v, want the announced channel on instance a", listing)
+	}
+	if instance, url := b.ChannelLocation("far"); instance != "a" || url != a.advertise {
+		t.Errorf("location = %q, %q, want a at %q", instance, url, a.advertise)
+	}
+	if listing := a.ListChannels(models.ChannelQuery{}); listing.Total != 1 {
+		t.Errorf("listing on the owner has %d channels, want its own once", listing.Total)
+	}
+}
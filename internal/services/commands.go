@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// parseCommand splits a protocol command of the form __NAME__ or __NAME__:arg
+func parseCommand(text string) (string, string, bool) {
+	if !strings.HasPrefix(text, "__") {
+		return "", "", false
+	}
+	end := strings.Index(text[2:], "__")
+	if end <= 0 {
+		return "", "", false
+	}
+
+	name := text[2 : 2+end]
+	rest := text[2+end+2:]
+	if rest != "" && !strings.HasPrefix(rest, ":") {
+		return "", "", false
+	}
+	if strings.ToUpper(name) != name {
+		return "", "", false
+	}
+	return name, strings.TrimPrefix(rest, ":"), true
+}
+
+// handleCommand dispatches a protocol command sent by a client
+func (s *ChannelService) handleCommand(ctx context.Context, ch *models.Channel, client *models.Client, name, arg string) {
+	archived := ch.Status == models.ChannelArchived
+
+	switch name {
+	case "ENGAGE":
+		if client.CanSend && !archived {
+			s.HandleClientEngage(ctx, ch, client)
+		}
+	case "REMATCH":
+		if client.CanSend {
+			s.HandleRematchRequest(ch, client, arg == "swap")
+		}
+	case "TIMEOUT":
+		s.HandleTimeout(ch, client)
+	case "PAUSE":
+		s.HandlePause(ch, client)
+	case "RESUME":
+		s.HandleResume(ch, client)
+	case "EXTEND":
+		switch arg {
+		case "":
+			s.HandleExtensionRequest(ch, client)
+		case "accept", "decline":
+			s.HandleExtensionAnswer(ch, client, arg == "accept")
+		default:
+			s.notifyClient(ch, client, "❓ Unknown extension answer: "+arg)
+		}
+	case "VOTE":
+		s.HandleVote(ch, client, arg)
+	case "REACT":
+		s.HandleReaction(ch, client, arg)
+	case "MUTE", "UNMUTE":
+		s.HandleMute(ch, client, arg, name == "MUTE")
+	default:
+		s.notifyClient(ch, client, "❓ Unknown command: "+name)
+	}
+}
+
+// notifyClient sends a system message to one client without adding it to the channel history
+func (s *ChannelService) notifyClient(ch *models.Channel, client *models.Client, text string) {
+	s.deliver(ch, client, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       text,
+		Timestamp:  time.Now(),
+	})
+}
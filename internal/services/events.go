@@ -0,0 +1,171 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// RequiredDebaters is the number of ready debaters needed to start
+const RequiredDebaters = 2
+
+// phaseTitle returns the display name of a debate phase
+func phaseTitle(phaseId int) string {
+	switch phaseId {
+	case 0:
+		return "Lobby"
+	case 1:
+		return "Opening Statements"
+	case 2:
+		return "Rebuttals"
+	case 3:
+		return "Questions"
+	case 4:
+		return "Answers"
+	case 5:
+		return "Closing Statements"
+	default:
+		return "Discussion"
+	}
+}
+
+// BroadcastEvent pushes a structured event to every connected client without storing it
+func (s *ChannelService) BroadcastEvent(ch *models.Channel, eventType string, data any) {
+	ch.Actor.Do(func() {
+		s.broadcastEvent(ch, eventType, data)
+	})
+}
+
+func (s *ChannelService) broadcastEvent(ch *models.Channel, eventType string, data any) {
+	s.deliverAll(ch, channelClients(ch), models.Event{
+		Type:      eventType,
+		Channel:   ch.Name,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+}
+
+// sendEvent pushes a structured event to a single client
+func (s *ChannelService) sendEvent(ch *models.Channel, client *models.Client, eventType string, data any) {
+	s.deliver(ch, client, models.Event{
+		Type:      eventType,
+		Channel:   ch.Name,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+}
+
+// debaterNames returns the sorted names of connected debaters; runs on the channel's actor
+func debaterNames(ch *models.Channel) []string {
+	names := []string{}
+	for _, c := range ch.Clients {
+		if c.CanSend {
+			names = append(names, c.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// phaseChangedData snapshots the phase clock; runs on the channel's actor
+func phaseChangedData(ch *models.Channel) models.PhaseChangedData {
+	now := time.Now()
+	data := models.PhaseChangedData{
+		Id:               ch.Phase.Id,
+		Name:             ch.Phase.Name,
+		Title:            phaseTitle(ch.Phase.Id),
+		Status:           ch.Status,
+		Round:            ch.Round,
+		DurationSeconds:  int(ch.Phase.Duration.Seconds()),
+		RemainingSeconds: int(ch.Phase.Remaining(now).Seconds()),
+		Paused:           ch.Phase.Paused(),
+		AllowedSpeakers:  []string{},
+	}
+	if !ch.Phase.StartTime.IsZero() {
+		start := ch.Phase.StartTime
+		data.StartTime = &start
+	}
+	if deadline := ch.Phase.Deadline(); !deadline.IsZero() && !data.Paused {
+		data.Deadline = &deadline
+	}
+	if ch.Status == models.ChannelOpen && !data.Paused {
+		data.AllowedSpeakers = debaterNames(ch)
+	}
+	if ch.Phase.Id == 0 && ch.Status == models.ChannelOpen && !ch.StartsAt.IsZero() {
+		startsAt := ch.StartsAt
+		data.StartsAt = &startsAt
+	}
+	return data
+}
+
+// readinessData snapshots which debaters have engaged; runs on the channel's actor
+func readinessData(ch *models.Channel) models.ReadinessChangedData {
+	data := models.ReadinessChangedData{
+		Debaters: []models.DebaterReadiness{},
+		Required: RequiredDebaters,
+	}
+	for _, c := range ch.Clients {
+		if !c.CanSend {
+			continue
+		}
+		data.Debaters = append(data.Debaters, models.DebaterReadiness{
+			Name:  c.Name,
+			Side:  ch.Sides[c.Name],
+			Ready: c.Ready,
+		})
+		if c.Ready {
+			data.ReadyCount++
+		}
+	}
+	sort.Slice(data.Debaters, func(i, j int) bool {
+		return data.Debaters[i].Name < data.Debaters[j].Name
+	})
+	return data
+}
+
+// submissionData snapshots who has submitted in the current phase; runs on the channel's actor
+func submissionData(ch *models.Channel) models.SubmissionStatusData {
+	data := models.SubmissionStatusData{
+		Phase:     ch.Phase.Id,
+		Submitted: []string{},
+		Waiting:   []string{},
+	}
+	submitted := ch.PhaseParticipants[phaseKey(ch.Phase.Id)]
+	for _, name := range debaterNames(ch) {
+		if submitted[name] {
+			data.Submitted = append(data.Submitted, name)
+		} else {
+			data.Waiting = append(data.Waiting, name)
+		}
+	}
+	return data
+}
+
+func (s *ChannelService) emitPhaseChanged(ch *models.Channel) {
+	data := phaseChangedData(ch)
+	s.broadcastEvent(ch, models.EventPhaseChanged, data)
+}
+
+func (s *ChannelService) emitReadinessChanged(ch *models.Channel) {
+	data := readinessData(ch)
+	s.broadcastEvent(ch, models.EventReadinessChanged, data)
+}
+
+func (s *ChannelService) emitSubmissionStatus(ch *models.Channel) {
+	data := submissionData(ch)
+	s.broadcastEvent(ch, models.EventSubmissionStatus, data)
+}
+
+// sendStateEvents brings a newly connected client up to date with the current state
+func (s *ChannelService) sendStateEvents(ch *models.Channel, client *models.Client) {
+	phase := phaseChangedData(ch)
+	readiness := readinessData(ch)
+	submissions := submissionData(ch)
+	votes := voteTally(ch)
+
+	s.sendEvent(ch, client, models.EventPhaseChanged, phase)
+	s.sendEvent(ch, client, models.EventReadinessChanged, readiness)
+	s.sendEvent(ch, client, models.EventSubmissionStatus, submissions)
+	s.sendEvent(ch, client, models.EventVoteTally, votes)
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"math"
+	"time"
+)
+
+// Glicko-2 constants, following Glickman's "Example of the Glicko-2 system"
+const (
+	DefaultRating     = 1500.0
+	DefaultRD         = 350.0
+	DefaultVolatility = 0.06
+	ProvisionalRD     = 110.0          // Ratings with a higher deviation are marked provisional
+	RatingPeriod      = 24 * time.Hour // Inactivity per period grows the deviation back toward DefaultRD
+
+	glickoScale   = 173.7178
+	glickoTau     = 0.5 // Constrains how fast volatility can change
+	glickoEpsilon = 0.000001
+)
+
+// glickoPlayer is a rating on the internal Glicko-2 scale
+type glickoPlayer struct {
+	mu    float64
+	phi   float64
+	sigma float64
+}
+
+func toGlicko(rating, rd, volatility float64) glickoPlayer {
+	return glickoPlayer{
+		mu:    (rating - DefaultRating) / glickoScale,
+		phi:   rd / glickoScale,
+		sigma: volatility,
+	}
+}
+
+func (p glickoPlayer) rating() float64 { return p.mu*glickoScale + DefaultRating }
+func (p glickoPlayer) rd() float64     { return p.phi * glickoScale }
+
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func glickoE(mu, muOpponent, phiOpponent float64) float64 {
+	return 1 / (1 + math.Exp(-glickoG(phiOpponent)*(mu-muOpponent)))
+}
+
+// inflate grows the deviation for rating periods without games, capped at DefaultRD
+func (p glickoPlayer) inflate(periods float64) glickoPlayer {
+	if periods <= 0 {
+		return p
+	}
+	p.phi = math.Min(math.Sqrt(p.phi*p.phi+periods*p.sigma*p.sigma), DefaultRD/glickoScale)
+	return p
+}
+
+// glickoGame is one result in a rating period; score is 1 for a win, 0 for a loss
+type glickoGame struct {
+	opponent glickoPlayer
+	score    float64
+}
+
+// glickoUpdate rates p after the games of one rating period
+func glickoUpdate(p glickoPlayer, games ...glickoGame) glickoPlayer {
+	if len(games) == 0 {
+		return p.inflate(1)
+	}
+	var vInv, improvement float64
+	for _, game := range games {
+		g := glickoG(game.opponent.phi)
+		e := glickoE(p.mu, game.opponent.mu, game.opponent.phi)
+		vInv += g * g * e * (1 - e)
+		improvement += g * (game.score - e)
+	}
+	v := 1 / vInv
+	delta := v * improvement
+
+	// Solve for the new volatility with the Illinois algorithm
+	a := math.Log(p.sigma * p.sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := p.phi*p.phi + v + ex
+		return ex*(delta*delta-d)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+	lo := a
+	var hi float64
+	if delta*delta > p.phi*p.phi+v {
+		hi = math.Log(delta*delta - p.phi*p.phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		hi = a - k*glickoTau
+	}
+	fLo, fHi := f(lo), f(hi)
+	for math.Abs(hi-lo) > glickoEpsilon {
+		c := lo + (lo-hi)*fLo/(fHi-fLo)
+		fC := f(c)
+		if fC*fHi <= 0 {
+			lo, fLo = hi, fHi
+		} else {
+			fLo /= 2
+		}
+		hi, fHi = c, fC
+	}
+	sigma := math.Exp(lo / 2)
+
+	phiStar := math.Sqrt(p.phi*p.phi + sigma*sigma)
+	phi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	return glickoPlayer{
+		mu:    p.mu + phi*phi*improvement,
+		phi:   phi,
+		sigma: sigma,
+	}
+}
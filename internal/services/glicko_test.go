@@ -0,0 +1,150 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/store"
+)
+
+// TestGlickoUpdate checks the worked example in Glickman's "Example of the Glicko-2 system"
+func TestGlickoUpdate(t *testing.T) {
+	player := toGlicko(1500, 200, 0.06)
+	rated := glickoUpdate(player,
+		glickoGame{opponent: toGlicko(1400, 30, 0.06), score: 1},
+		glickoGame{opponent: toGlicko(1550, 100, 0.06), score: 0},
+		glickoGame{opponent: toGlicko(1700, 300, 0.06), score: 0},
+	)
+
+	for _, check := range []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"rating", rated.rating(), 1464.06, 0.01},
+		{"deviation", rated.rd(), 151.52, 0.01},
+		{"volatility", rated.sigma, 0.05999, 0.00001},
+	} {
+		if math.Abs(check.got-check.want) > check.tolerance {
+			t.Errorf("%s = %.5f, want %.5f", check.name, check.got, check.want)
+		}
+	}
+}
+
+func TestGlickoInflate(t *testing.T) {
+	p := toGlicko(1500, 50, 0.06)
+	if got := p.inflate(0).rd(); got != 50 {
+		t.Errorf("deviation after no time away = %.2f, want it unchanged", got)
+	}
+	once, twice := p.inflate(1).rd(), p.inflate(2).rd()
+	if !(50 < once && once < twice) {
+		t.Errorf("deviation after one and two periods away = %.2f, %.2f, want it growing", once, twice)
+	}
+	if got := p.inflate(1e6).rd(); math.Abs(got-DefaultRD) > 1e-9 {
+		t.Errorf("deviation after years away = %.2f, want it capped at %.0f", got, DefaultRD)
+	}
+	if got := glickoUpdate(p).rd(); got != once {
+		t.Errorf("deviation after a period without games = %.2f, want %.2f", got, once)
+	}
+}
+
+func newTestRatings(t *testing.T) *RatingService {
+	t.Helper()
+	st, err := store.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := NewRatingService(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+// TestRatingProvisional checks that players stay provisional until their games bring the deviation
+// down, and become provisional again after long enough away
+func TestRatingProvisional(t *testing.T) {
+	rs := newTestRatings(t)
+	if r := rs.GetRating("ann"); !r.Provisional || r.Rating != DefaultRating {
+		t.Fatalf("unrated player = %+v, want the provisional default", r)
+	}
+
+	now := time.Now()
+	games := 0
+	for ; rs.GetRating("ann").Provisional; games++ {
+		if games == 50 {
+			t.Fatal("still provisional after 50 games")
+		}
+		rs.RecordResult(&models.Archive{
+			ChannelName:  "c",
+			Participants: []string{"ann", "bob"},
+			Winner:       []string{"ann", "bob"}[games%2],
+			Identities:   map[string]string{"ann": "ann's browser", "bob": "bob's browser"},
+			ConcludedAt:  now,
+		})
+	}
+	if games < 2 {
+		t.Errorf("established after %d games, want a provisional period", games)
+	}
+	if r := rs.GetRating("ann"); r.Games != games || r.Wins+r.Losses != games || r.RD > ProvisionalRD {
+		t.Errorf("after %d games: %+v", games, r)
+	}
+
+	// A year away grows the deviation back past the threshold
+	rs.mu.Lock()
+	rs.book.Players["ann"].LastPlayed = now.Add(-365 * 24 * time.Hour)
+	rs.mu.Unlock()
+	if r := rs.GetRating("ann"); !r.Provisional {
+		t.Errorf("after a year away: %+v, want provisional", r)
+	}
+
+	// Forfeits and debates without a winner are not rated
+	rs.RecordResult(&models.Archive{Participants: []string{"cat", "dan"}, Winner: "cat", Forfeit: true, ConcludedAt: now})
+	rs.RecordResult(&models.Archive{Participants: []string{"cat", "dan"}, ConcludedAt: now})
+	if r := rs.GetRating("cat"); r.Games != 0 {
+		t.Errorf("cat = %+v after a forfeit and a draw, want unrated", r)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	rs := newTestRatings(t)
+	now := time.Now()
+	for _, r := range []models.Rating{
+		{Player: "low", Rating: 1400, RD: 60},
+		{Player: "high", Rating: 1700, RD: 60},
+		{Player: "newcomer", Rating: 1900, RD: 300},
+		{Player: "alpha", Rating: 1550, RD: 60},
+		{Player: "beta", Rating: 1550, RD: 60},
+	} {
+		r.Volatility, r.LastPlayed, r.Provisional = DefaultVolatility, now, r.RD > ProvisionalRD
+		rs.book.Players[r.Player] = &r
+	}
+
+	board := rs.Leaderboard(0, 0, true)
+	var order []string
+	for _, r := range board.Ratings {
+		order = append(order, r.Player)
+	}
+	want := []string{"high", "alpha", "beta", "low", "newcomer"}
+	if len(order) != len(want) || board.Total != len(want) {
+		t.Fatalf("leaderboard = %v (total %d), want %v", order, board.Total, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("leaderboard = %v, want established players by rating, ties by name, then provisional ones", order)
+		}
+	}
+	if board.Ratings[0].Rank != 1 || board.Ratings[3].Rank != 4 || board.Ratings[4].Rank != 0 {
+		t.Errorf("ranks = %d, %d, %d, want 1 and 4 for established players and none for provisional ones",
+			board.Ratings[0].Rank, board.Ratings[3].Rank, board.Ratings[4].Rank)
+	}
+
+	if established := rs.Leaderboard(0, 0, false); established.Total != 4 {
+		t.Errorf("leaderboard without provisional players has %d, want 4", established.Total)
+	}
+	if page := rs.Leaderboard(1, 2, true); len(page.Ratings) != 2 || page.Ratings[0].Player != "alpha" || page.Total != 5 {
+		t.Errorf("second page = %+v, want alpha and beta of 5", page)
+	}
+}
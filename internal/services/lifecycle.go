@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// archiveDebate records the concluded debate and switches the channel to read-only
+func (s *ChannelService) archiveDebate(ch *models.Channel) {
+	archive := &models.Archive{
+		ChannelId:   ch.ChannelId,
+		ChannelName: ch.Name,
+		Round:       ch.Round,
+		Motion:      ch.Motion,
+		Sides:       make(map[string]string, len(ch.Sides)),
+		Messages:    make([]models.Message, len(ch.Messages)),
+		CreatedAt:   ch.CreatedAt,
+		ConcludedAt: time.Now(),
+	}
+	copy(archive.Messages, ch.Messages)
+
+	for i := len(ch.Messages) - 1; i >= 0; i-- {
+		if ch.Messages[i].JudgeData != nil {
+			archive.Verdict = ch.Messages[i].JudgeData
+			break
+		}
+	}
+
+	archive.Participants = debateParticipants(ch)
+	for name, side := range ch.Sides {
+		archive.Sides[name] = side
+	}
+	archive.Identities = make(map[string]string, len(archive.Participants))
+	for _, name := range archive.Participants {
+		archive.Identities[name] = ch.Identities[name]
+	}
+	archive.Winner = determineWinner(archive.Verdict, archive.Participants)
+	record(ch, models.ChannelEvent{Type: models.Verdict, Winner: archive.Winner})
+
+	s.concludeDebate(ch, archive)
+}
+
+// forfeitDebate concludes a scheduled debate that could not start because debaters were missing or
+// not ready; winner is the one debater who was, or empty when neither was
+func (s *ChannelService) forfeitDebate(ch *models.Channel, winner string, noShows []string) *models.Archive {
+	if ch.Phase.Id != 0 || ch.Status != models.ChannelOpen {
+		return nil
+	}
+	record(ch, models.ChannelEvent{Type: models.Verdict, Winner: winner, Forfeit: true})
+
+	text := "🚫 Nobody was ready at the scheduled start. The debate is forfeited without a winner."
+	if winner != "" {
+		text = fmt.Sprintf("🚫 %s wins by forfeit: the opponent did not show up ready at the scheduled start.", winner)
+	}
+	s.broadcast(ch, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       text,
+		Timestamp:  time.Now(),
+	})
+
+	archive := &models.Archive{
+		ChannelId:   ch.ChannelId,
+		ChannelName: ch.Name,
+		Round:       ch.Round,
+		Motion:      ch.Motion,
+		Sides:       make(map[string]string, len(ch.Sides)),
+		Messages:    make([]models.Message, len(ch.Messages)),
+		Winner:      winner,
+		Forfeit:     true,
+		CreatedAt:   ch.CreatedAt,
+		ConcludedAt: time.Now(),
+	}
+	copy(archive.Messages, ch.Messages)
+
+	debaters := make(map[string]bool)
+	for name, side := range ch.Sides {
+		archive.Sides[name] = side
+		debaters[name] = true
+	}
+	for _, name := range debaterNames(ch) {
+		debaters[name] = true
+	}
+	for _, name := range noShows {
+		debaters[name] = true
+	}
+	for name := range debaters {
+		archive.Participants = append(archive.Participants, name)
+	}
+	sort.Strings(archive.Participants)
+
+	s.concludeDebate(ch, archive)
+	return archive
+}
+
+// concludeDebate stores the archive of a round whose verdict has been recorded and runs the concluded hooks
+func (s *ChannelService) concludeDebate(ch *models.Channel, archive *models.Archive) {
+	s.Manager.Mu.Lock()
+	s.keepArchive(archive)
+	s.Manager.Mu.Unlock()
+
+	channelLog(ch).Info("channel archived", "round", archive.Round)
+
+	s.recordRoundResult(ch, archive)
+	s.emitPhaseChanged(ch)
+
+	// Hooks take their services' locks and may read channels back, so they run off the actor
+	go func() {
+		for _, hook := range s.concludedHooks {
+			hook(archive)
+		}
+	}()
+}
+
+// keepArchive files a concluded round under its channel's name, dropping the oldest rounds beyond the
+// cap; the caller holds the manager lock. The rounds of only one channel are kept under a name: those
+// of an earlier channel that had it give way.
+func (s *ChannelService) keepArchive(archive *models.Archive) {
+	name := archive.ChannelName
+	if ch := s.Manager.Channels[name]; ch != nil && ch.ChannelId != archive.ChannelId {
+		// Deleted while concluding, and the name already taken again
+		return
+	}
+	archives := s.Manager.Archives[name]
+	if len(archives) > 0 && archives[0].ChannelId != archive.ChannelId {
+		archives = nil
+	}
+	archives = append(archives, archive)
+	if len(archives) > s.maxArchives {
+		archives = slices.Clone(archives[len(archives)-s.maxArchives:])
+	}
+	s.Manager.Archives[name] = archives
+}
+
+// PruneArchives drops the rounds concluded longer than the retention ago, returning how many
+func (s *ChannelService) PruneArchives() int {
+	cutoff := time.Now().Add(-s.archiveRetention)
+
+	s.Manager.Mu.Lock()
+	defer s.Manager.Mu.Unlock()
+	pruned := 0
+	for name, archives := range s.Manager.Archives {
+		kept := slices.DeleteFunc(slices.Clone(archives), func(a *models.Archive) bool {
+			return a.ConcludedAt.Before(cutoff)
+		})
+		pruned += len(archives) - len(kept)
+		if len(kept) == 0 {
+			delete(s.Manager.Archives, name)
+		} else if len(kept) < len(archives) {
+			s.Manager.Archives[name] = kept
+		}
+	}
+	if pruned > 0 {
+		slog.Info("archived rounds pruned", "rounds", pruned, "retention", s.archiveRetention)
+	}
+	return pruned
+}
+
+// OnDebateConcluded registers fn to be called with every archived debate; register hooks before serving
+func (s *ChannelService) OnDebateConcluded(fn func(*models.Archive)) {
+	s.concludedHooks = append(s.concludedHooks, fn)
+}
+
+// debateParticipants returns the sorted names of everyone who submitted in any phase; runs on the channel's actor
+func debateParticipants(ch *models.Channel) []string {
+	debaters := make(map[string]bool)
+	for _, participants := range ch.PhaseParticipants {
+		for name := range participants {
+			debaters[name] = true
+		}
+	}
+	names := make([]string, 0, len(debaters))
+	for name := range debaters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetArchives returns the concluded debates of the channel last open under a name, oldest first
+func (s *ChannelService) GetArchives(name string) []*models.Archive {
+	s.Manager.Mu.Lock()
+	defer s.Manager.Mu.Unlock()
+
+	archives := make([]*models.Archive, len(s.Manager.Archives[name]))
+	copy(archives, s.Manager.Archives[name])
+	return archives
+}
+
+// ExpireIdleChannels removes channels nobody is connected to that have been idle longer than ttl
+func (s *ChannelService) ExpireIdleChannels(ttl time.Duration) []string {
+	now := time.Now()
+
+	s.Manager.Mu.Lock()
+	channels := make(map[string]*models.Channel, len(s.Manager.Channels))
+	for name, ch := range s.Manager.Channels {
+		channels[name] = ch
+	}
+	s.Manager.Mu.Unlock()
+
+	// Ask each actor outside the manager lock, which commands take to record archives
+	idle := make(map[string]*models.Channel)
+	for name, ch := range channels {
+		ch.Actor.Do(func() {
+			// Scheduled channels wait empty until their start time
+			if len(ch.Clients) == 0 && now.Sub(ch.LastActivity) > ttl && !now.Before(ch.StartsAt) {
+				idle[name] = ch
+			}
+		})
+	}
+
+	var expired []string
+	s.Manager.Mu.Lock()
+	for name, ch := range idle {
+		if s.Manager.Channels[name] == ch {
+			delete(s.Manager.Channels, name)
+			expired = append(expired, name)
+		}
+	}
+	s.Manager.Mu.Unlock()
+
+	for _, name := range expired {
+		idle[name].Actor.Stop()
+		s.releaseChannel(name)
+		slog.Info("channel expired", "channel", name, "idle", ttl)
+	}
+	return expired
+}
+
+// StartJanitor expires idle channels and prunes old archives every interval until ctx is cancelled
+func (s *ChannelService) StartJanitor(ctx context.Context, interval, ttl time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.ExpireIdleChannels(ttl)
+				s.PruneArchives()
+			}
+		}
+	}()
+}
+
+// CreateMatchChannel opens a password-protected channel named after prefix with the sides already
+// assigned, for debates arranged by the server rather than by the players; it returns the password
+func (s *ChannelService) CreateMatchChannel(prefix, motion, owner string, bestOf int, proposition, opposition string) (*models.Channel, string, error) {
+	password := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	var ch *models.Channel
+	for ch == nil {
+		var err error
+		ch, err = s.CreateChannel(models.ChannelOptions{
+			Name:     prefix + "-" + strings.SplitN(uuid.NewString(), "-", 2)[0],
+			Password: password,
+			Motion:   motion,
+			Owner:    owner,
+			BestOf:   bestOf,
+		})
+		if err != nil && !errors.Is(err, ErrChannelExists) && !errors.Is(err, ErrChannelOwnedElsewhere) {
+			return nil, "", err
+		}
+	}
+
+	ch.Actor.Do(func() {
+		record(ch, models.ChannelEvent{
+			Type:  models.SidesAssigned,
+			Sides: map[string]string{proposition: models.SideProposition, opposition: models.SideOpposition},
+		})
+	})
+	return ch, password, nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/latestcomment/go-websocket-chat/internal/config"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/ratelimit"
+	"github.com/latestcomment/go-websocket-chat/internal/socket"
+)
+
+// rateLimitNoticeInterval spaces out the error events sent to a client that keeps sending too fast
+const rateLimitNoticeInterval = time.Second
+
+var ErrChannelFull = errors.New("channel is full")
+
+func newLimiter(rate config.Rate) *ratelimit.Limiter {
+	return ratelimit.New(rate.Count, rate.Per)
+}
+
+// allowInput takes a token for a frame from the client's IP and session buckets, telling the client
+// when it is sending too fast; runs on the channel's actor
+func (s *ChannelService) allowInput(ch *models.Channel, client *models.Client) bool {
+	ok, wait := s.messageLimit.Allow(ratelimit.Keys(client.IP, client.Session)...)
+	if ok {
+		return true
+	}
+
+	now := time.Now()
+	if now.Sub(client.LimitedAt) >= rateLimitNoticeInterval {
+		client.LimitedAt = now
+		s.sendEvent(ch, client, models.EventError, models.ErrorData{
+			Code:              models.ErrorRateLimited,
+			Message:           "You are sending messages too quickly. Your last message was dropped; please wait a moment.",
+			RetryAfterSeconds: ratelimit.RetryAfter(wait),
+		})
+		channelLog(ch).Warn("client rate limited", "client_id", client.Id.String(), "client", client.Name, "ip", client.IP)
+	}
+	return false
+}
+
+// RejectSocket sends an error event on a socket that was refused before joining a channel, then
+// closes it with code
+func RejectSocket(conn *websocket.Conn, channel string, code int, data models.ErrorData) {
+	conn.WriteJSON(models.Event{
+		Type:      models.EventError,
+		Channel:   channel,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+	CloseSocket(conn, code, data.Code)
+}
+
+// RejectClient is RejectSocket for a socket its writer has been started for; it returns once the
+// socket is closed
+func RejectClient(out *socket.Writer, channel string, code int, data models.ErrorData) {
+	out.Send(models.Event{
+		Type:      models.EventError,
+		Channel:   channel,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+	out.Close(code, data.Code)
+	<-out.Done()
+}
+
+// ChannelFullError is the error event for a client turned away from a full channel
+func (s *ChannelService) ChannelFullError() models.ErrorData {
+	return models.ErrorData{
+		Code:    models.ErrorChannelFull,
+		Message: fmt.Sprintf("This channel already has %d people connected. Try again later.", s.maxClients),
+	}
+}
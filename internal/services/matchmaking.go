@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/socket"
+)
+
+const (
+	MatchBaseWindow   = 100.0            // Rating gap accepted as soon as a player queues
+	MatchWindowGrowth = 50.0             // Extra gap accepted per MatchWindowStep of waiting
+	MatchWindowStep   = 10 * time.Second // Wait that earns another MatchWindowGrowth
+	MatchMaxWindow    = 500.0            // Widest gap ever accepted
+	MatchTicketTTL    = 10 * time.Minute // How long matched and cancelled tickets stay readable
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrUnknownTopic  = errors.New("unknown topic")
+)
+
+// MatchmakingService pairs queued players of similar rating into private channels
+type MatchmakingService struct {
+	mu       sync.Mutex
+	tickets  map[string]*models.MatchTicket
+	lobbies  map[string]*socket.Writer // Ticket id -> lobby socket waiting for the match
+	channels *ChannelService
+	ratings  *RatingService
+}
+
+func NewMatchmakingService(channels *ChannelService, ratings *RatingService) *MatchmakingService {
+	return &MatchmakingService{
+		tickets:  make(map[string]*models.MatchTicket),
+		lobbies:  make(map[string]*socket.Writer),
+		channels: channels,
+		ratings:  ratings,
+	}
+}
+
+// Enqueue puts a player in the queue for the browser with the session key, replacing any ticket it
+// already has waiting. A name rated for another browser is refused with ErrNameTaken.
+func (ms *MatchmakingService) Enqueue(player, session string, formats, topics []string) (models.MatchTicket, error) {
+	for _, format := range formats {
+		if _, ok := models.MatchFormats[format]; !ok {
+			return models.MatchTicket{}, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+		}
+	}
+	for _, topic := range topics {
+		if _, ok := motionBank[topic]; !ok {
+			return models.MatchTicket{}, fmt.Errorf("%w: %s", ErrUnknownTopic, topic)
+		}
+	}
+	if err := ms.ratings.CheckName(player, session); err != nil {
+		return models.MatchTicket{}, err
+	}
+
+	ticket := &models.MatchTicket{
+		Id:       uuid.NewString(),
+		Player:   player,
+		Identity: playerIdentity(session),
+		Rating:   ms.ratings.GetRating(player).Rating,
+		Formats:  append([]string{}, formats...),
+		Topics:   append([]string{}, topics...),
+		Status:   models.TicketQueued,
+		JoinedAt: time.Now(),
+	}
+
+	ms.mu.Lock()
+	for _, t := range ms.tickets {
+		if t.Identity == ticket.Identity && t.Status == models.TicketQueued {
+			t.Status = models.TicketCancelled
+		}
+	}
+	ms.tickets[ticket.Id] = ticket
+	copied := *ticket
+	ms.mu.Unlock()
+
+	slog.Info("player queued", "player", player, "rating", ticket.Rating)
+	return copied, nil
+}
+
+// Ticket returns a copy of the ticket with the given id
+func (ms *MatchmakingService) Ticket(id string) (models.MatchTicket, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ticket, ok := ms.tickets[id]
+	if !ok {
+		return models.MatchTicket{}, false
+	}
+	return *ticket, true
+}
+
+// Cancel takes a waiting ticket out of the queue, reporting false if it was not waiting
+func (ms *MatchmakingService) Cancel(id string) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ticket, ok := ms.tickets[id]
+	if !ok || ticket.Status != models.TicketQueued {
+		return false
+	}
+	ticket.Status = models.TicketCancelled
+	return true
+}
+
+// AttachLobby registers the socket to notify when the ticket is matched, sending the current status straight
+// away. The status is queued under ms.mu, which never blocks, so that it cannot overtake a match found meanwhile.
+func (ms *MatchmakingService) AttachLobby(id string, out *socket.Writer) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ticket, ok := ms.tickets[id]
+	if !ok || ticket.Status == models.TicketCancelled {
+		return false
+	}
+	ms.lobbies[id] = out
+	if ticket.Status == models.TicketMatched {
+		lobbyEvent{out, models.EventMatchFound, *ticket.Match}.send()
+	} else {
+		lobbyEvent{out, models.EventQueueStatus, ms.queueStatus(ticket, time.Now())}.send()
+	}
+	return true
+}
+
+// DetachLobby forgets the socket; a player who leaves the lobby before being matched leaves the queue
+func (ms *MatchmakingService) DetachLobby(id string, out *socket.Writer) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.lobbies[id] != out {
+		return
+	}
+	delete(ms.lobbies, id)
+	if ticket, ok := ms.tickets[id]; ok && ticket.Status == models.TicketQueued {
+		ticket.Status = models.TicketCancelled
+	}
+}
+
+// Start runs the matcher every interval until ctx is cancelled
+func (ms *MatchmakingService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ms.runMatcher(time.Now())
+			}
+		}
+	}()
+}
+
+// runMatcher pairs waiting players, longest waiting first, each with the closest-rated compatible opponent.
+// Match channels are opened without ms.mu, which would otherwise hold up the queue on broker and actor calls.
+func (ms *MatchmakingService) runMatcher(now time.Time) {
+	ms.mu.Lock()
+	pairs := ms.pair(now)
+	ms.mu.Unlock()
+
+	for _, pair := range pairs {
+		ms.createMatch(pair[0], pair[1])
+	}
+
+	ms.mu.Lock()
+	var events []lobbyEvent
+	for _, ticket := range ms.queued() {
+		if out := ms.lobbies[ticket.Id]; out != nil {
+			events = append(events, lobbyEvent{out, models.EventQueueStatus, ms.queueStatus(ticket, now)})
+		}
+	}
+	for id, ticket := range ms.tickets {
+		if ticket.Status != models.TicketQueued && now.Sub(ticket.JoinedAt) > MatchTicketTTL {
+			delete(ms.tickets, id)
+		}
+	}
+	ms.mu.Unlock()
+
+	for _, event := range events {
+		event.send()
+	}
+}
+
+// pair picks the tickets to match on this run; caller must hold ms.mu
+func (ms *MatchmakingService) pair(now time.Time) [][2]*models.MatchTicket {
+	queued := ms.queued()
+	paired := make(map[string]bool)
+	var pairs [][2]*models.MatchTicket
+	for i, a := range queued {
+		if paired[a.Id] {
+			continue
+		}
+		var best *models.MatchTicket
+		bestGap := math.Inf(1)
+		for _, b := range queued[i+1:] {
+			// One channel cannot seat two debaters of the same name
+			if paired[b.Id] || b.Player == a.Player {
+				continue
+			}
+			gap := math.Abs(a.Rating - b.Rating)
+			window := math.Max(ratingWindow(a, now), ratingWindow(b, now))
+			if gap > window || gap >= bestGap {
+				continue
+			}
+			if _, ok := commonChoice(a.Formats, b.Formats); !ok {
+				continue
+			}
+			if _, ok := commonChoice(a.Topics, b.Topics); !ok {
+				continue
+			}
+			best, bestGap = b, gap
+		}
+		if best != nil {
+			paired[a.Id] = true
+			paired[best.Id] = true
+			pairs = append(pairs, [2]*models.MatchTicket{a, best})
+		}
+	}
+	return pairs
+}
+
+// createMatch opens a private channel for the pair and tells both players. The caller must not hold ms.mu;
+// a player who leaves the queue while the channel is opened calls the match off.
+func (ms *MatchmakingService) createMatch(a, b *models.MatchTicket) {
+	// Preferences never change once queued, so they are read without the lock
+	format, _ := commonChoice(a.Formats, b.Formats)
+	if format == "" {
+		format = models.FormatSingle
+	}
+	topic, _ := commonChoice(a.Topics, b.Topics)
+	motion, topic := randomMotion(topic)
+
+	proposition, opposition := a, b
+	if rand.IntN(2) == 0 {
+		proposition, opposition = b, a
+	}
+	ch, password, err := ms.channels.CreateMatchChannel("match", motion, "matchmaker", models.MatchFormats[format],
+		proposition.Player, opposition.Player)
+	if err != nil {
+		// Both stay queued and are paired again on a later run
+		slog.Error("creating match channel", "proposition", proposition.Player, "opposition", opposition.Player, "err", err)
+		return
+	}
+
+	ms.mu.Lock()
+	if a.Status != models.TicketQueued || b.Status != models.TicketQueued {
+		ms.mu.Unlock()
+		ms.channels.DeleteChannel(ch.Name)
+		channelLog(ch).Info("match called off, a player left the queue", "proposition", proposition.Player, "opposition", opposition.Player)
+		return
+	}
+	var events []lobbyEvent
+	for _, pair := range [][2]*models.MatchTicket{{proposition, opposition}, {opposition, proposition}} {
+		ticket, opponent := pair[0], pair[1]
+		ticket.Status = models.TicketMatched
+		side := models.SideOpposition
+		if ticket == proposition {
+			side = models.SideProposition
+		}
+		ticket.Match = &models.MatchFound{
+			Channel:        ch.Name,
+			Password:       password,
+			Motion:         motion,
+			Topic:          topic,
+			Format:         format,
+			BestOf:         models.MatchFormats[format],
+			Side:           side,
+			Opponent:       opponent.Player,
+			OpponentRating: opponent.Rating,
+		}
+		if out := ms.lobbies[ticket.Id]; out != nil {
+			events = append(events, lobbyEvent{out, models.EventMatchFound, *ticket.Match})
+		}
+	}
+	ms.mu.Unlock()
+
+	for _, event := range events {
+		event.send()
+	}
+	channelLog(ch).Info("players matched", "proposition", proposition.Player, "opposition", opposition.Player, "format", format, "topic", topic)
+}
+
+// queued returns the waiting tickets, longest waiting first; caller must hold ms.mu
+func (ms *MatchmakingService) queued() []*models.MatchTicket {
+	queued := []*models.MatchTicket{}
+	for _, ticket := range ms.tickets {
+		if ticket.Status == models.TicketQueued {
+			queued = append(queued, ticket)
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool {
+		return queued[i].JoinedAt.Before(queued[j].JoinedAt)
+	})
+	return queued
+}
+
+// queueStatus describes a waiting ticket's place in the queue; caller must hold ms.mu
+func (ms *MatchmakingService) queueStatus(ticket *models.MatchTicket, now time.Time) models.QueueStatusData {
+	queued := ms.queued()
+	position := 0
+	for i, t := range queued {
+		if t == ticket {
+			position = i + 1
+		}
+	}
+	return models.QueueStatusData{
+		Ticket:       ticket.Id,
+		Position:     position,
+		QueueSize:    len(queued),
+		WaitSeconds:  int(now.Sub(ticket.JoinedAt).Seconds()),
+		RatingWindow: ratingWindow(ticket, now),
+	}
+}
+
+// ratingWindow is the rating gap a ticket accepts, widening the longer it waits
+func ratingWindow(ticket *models.MatchTicket, now time.Time) float64 {
+	steps := float64(now.Sub(ticket.JoinedAt) / MatchWindowStep)
+	return math.Min(MatchBaseWindow+steps*MatchWindowGrowth, MatchMaxWindow)
+}
+
+// commonChoice picks the first of a's preferences b also accepts; an empty list accepts anything
+func commonChoice(a, b []string) (string, bool) {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return "", true
+	case len(a) == 0:
+		return b[0], true
+	case len(b) == 0:
+		return a[0], true
+	}
+	for _, choice := range a {
+		if slices.Contains(b, choice) {
+			return choice, true
+		}
+	}
+	return "", false
+}
+
+// lobbyEvent is an event for a lobby socket, gathered under ms.mu and sent once it is released
+type lobbyEvent struct {
+	out       *socket.Writer
+	eventType string
+	data      any
+}
+
+// send queues the event on the lobby socket; it never blocks
+func (e lobbyEvent) send() {
+	e.out.Send(models.Event{
+		Type:      e.eventType,
+		Timestamp: time.Now(),
+		Data:      e.data,
+	})
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// TestRatedNamesBelongToABrowser checks that a debate played under someone else's rated name does not
+// move their rating, and that the impostor cannot queue under it either
+func TestRatedNamesBelongToABrowser(t *testing.T) {
+	rs := newTestRatings(t)
+	ms := NewMatchmakingService(newTestService(t), rs)
+	ann, bob, impostor := "ann-session", "bob-session", "impostor-session"
+	debate := func(winner string, identities map[string]string) {
+		rs.RecordResult(&models.Archive{
+			ChannelName:  "c",
+			Participants: []string{"ann", "bob"},
+			Winner:       winner,
+			Identities:   identities,
+			ConcludedAt:  time.Now(),
+		})
+	}
+
+	debate("ann", map[string]string{"ann": playerIdentity(ann), "bob": playerIdentity(bob)})
+	rated := rs.GetRating("ann")
+	if rated.Games != 1 {
+		t.Fatalf("ann = %+v, want one rated game", rated)
+	}
+
+	debate("bob", map[string]string{"ann": playerIdentity(impostor), "bob": playerIdentity(bob)})
+	debate("bob", map[string]string{"ann": playerIdentity(bob), "bob": playerIdentity(bob)})
+	debate("bob", map[string]string{"bob": playerIdentity(bob)})
+	if got := rs.GetRating("ann"); got.Games != rated.Games || got.Rating != rated.Rating {
+		t.Errorf("ann = %+v after debates others played as her, want %+v", got, rated)
+	}
+
+	if _, err := ms.Enqueue("ann", impostor, nil, nil); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("queueing under another's rated name: err = %v, want %v", err, ErrNameTaken)
+	}
+	if _, err := ms.Enqueue("ann", ann, nil, nil); err != nil {
+		t.Errorf("queueing under one's own name: %v", err)
+	}
+}
+
+// TestEnqueueReplacesOwnTicket checks that queueing again replaces the browser's own waiting ticket and
+// nobody else's, even under the same name
+func TestEnqueueReplacesOwnTicket(t *testing.T) {
+	ms := NewMatchmakingService(newTestService(t), newTestRatings(t))
+
+	first, err := ms.Enqueue("guest", "one", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ms.Enqueue("guest", "two", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ms.Enqueue("guest", "one", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, check := range []struct {
+		ticket models.MatchTicket
+		want   string
+	}{
+		{first, models.TicketCancelled},
+		{other, models.TicketQueued},
+		{second, models.TicketQueued},
+	} {
+		if got, _ := ms.Ticket(check.ticket.Id); got.Status != check.want {
+			t.Errorf("ticket %s is %s, want %s", check.ticket.Id, got.Status, check.want)
+		}
+	}
+
+	// The two browsers share a name, so they are not paired with each other
+	ms.mu.Lock()
+	pairs := ms.pair(time.Now())
+	ms.mu.Unlock()
+	if len(pairs) != 0 {
+		t.Errorf("paired %d matches, want none between two players of the same name", len(pairs))
+	}
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"github.com/latestcomment/go-websocket-chat/internal/metrics"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// channelCollector reports the channel gauges when scraped, asking each channel's actor for its clients
+type channelCollector struct {
+	s *ChannelService
+}
+
+// MetricsCollector reports the channels owned here, their clients and their actors' backlogs
+func (s *ChannelService) MetricsCollector() prometheus.Collector {
+	return channelCollector{s}
+}
+
+func (c channelCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- metrics.ActiveChannels
+	descs <- metrics.ConnectedClients
+	descs <- metrics.QueueDepth
+	descs <- metrics.QueueDepthMax
+}
+
+func (c channelCollector) Collect(out chan<- prometheus.Metric) {
+	c.s.Manager.Mu.Lock()
+	channels := make([]*models.Channel, 0, len(c.s.Manager.Channels))
+	for _, ch := range c.s.Manager.Channels {
+		channels = append(channels, ch)
+	}
+	c.s.Manager.Mu.Unlock()
+
+	var debaters, spectators, queued, busiest int
+	for _, ch := range channels {
+		pending := ch.Actor.Pending()
+		queued += pending
+		busiest = max(busiest, pending)
+		ch.Actor.Do(func() {
+			for _, client := range ch.Clients {
+				if client.CanSend {
+					debaters++
+				} else {
+					spectators++
+				}
+			}
+		})
+	}
+
+	out <- prometheus.MustNewConstMetric(metrics.ActiveChannels, prometheus.GaugeValue, float64(len(channels)))
+	out <- prometheus.MustNewConstMetric(metrics.ConnectedClients, prometheus.GaugeValue, float64(debaters), "debater")
+	out <- prometheus.MustNewConstMetric(metrics.ConnectedClients, prometheus.GaugeValue, float64(spectators), "spectator")
+	out <- prometheus.MustNewConstMetric(metrics.QueueDepth, prometheus.GaugeValue, float64(queued))
+	out <- prometheus.MustNewConstMetric(metrics.QueueDepthMax, prometheus.GaugeValue, float64(busiest))
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"math/rand/v2"
+	"sort"
+)
+
+// motionBank holds the motions matchmaking draws from, by topic
+var motionBank = map[string][]string{
+	"technology": {
+		"This house believes social media has done more harm than good",
+		"This house would ban facial recognition in public spaces",
+		"This house believes artificial intelligence will create more jobs than it destroys",
+		"This house would make all software open source",
+	},
+	"politics": {
+		"This house would lower the voting age to 16",
+		"This house would make voting compulsory",
+		"This house believes term limits should apply to all elected offices",
+		"This house would abolish the monarchy",
+	},
+	"education": {
+		"This house would abolish homework",
+		"This house would make university education free",
+		"This house believes standardized testing does more harm than good",
+		"This house would teach coding instead of a second language",
+	},
+	"environment": {
+		"This house would ban single-use plastics",
+		"This house believes nuclear power is essential to fight climate change",
+		"This house would tax meat to reduce emissions",
+		"This house would ban private cars from city centres",
+	},
+	"philosophy": {
+		"This house believes free will is an illusion",
+		"This house believes it is never right to lie",
+		"This house would choose a guaranteed happy life in a simulation",
+		"This house believes morality is objective",
+	},
+}
+
+// MotionTopics lists the topics players may ask for, sorted
+func MotionTopics() []string {
+	topics := make([]string, 0, len(motionBank))
+	for topic := range motionBank {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// randomMotion draws a motion for the topic, or from a random topic if it is empty
+func randomMotion(topic string) (string, string) {
+	if topic == "" {
+		topics := MotionTopics()
+		topic = topics[rand.IntN(len(topics))]
+	}
+	motions := motionBank[topic]
+	return motions[rand.IntN(len(motions))], topic
+}
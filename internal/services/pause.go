@@ -0,0 +1,196 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+const (
+	MaxTimeoutsPerDebater = 2                // Timeouts each debater may call per round
+	TimeoutDuration       = 60 * time.Second // Length of a debater timeout before the clock restarts
+	ExtensionStep         = 60 * time.Second // Time added by an accepted extension request
+)
+
+// debateActive reports whether a timed debate phase is running; runs on the channel's actor
+func debateActive(ch *models.Channel) bool {
+	return ch.Status == models.ChannelOpen && ch.Phase.Id >= 1 && ch.Phase.Id <= 5
+}
+
+// HandleTimeout lets a debater stop the clock for TimeoutDuration, a limited number of times per round
+func (s *ChannelService) HandleTimeout(ch *models.Channel, client *models.Client) {
+	if !client.CanSend {
+		s.notifyClient(ch, client, "Only debaters can call a timeout.")
+		return
+	}
+
+	switch {
+	case !debateActive(ch):
+		s.notifyClient(ch, client, "⏸️ Timeouts can only be called during the debate.")
+		return
+	case ch.Pause != nil:
+		s.notifyClient(ch, client, "⏸️ The debate is already paused.")
+		return
+	case ch.TimeoutsUsed[client.Name] >= MaxTimeoutsPerDebater:
+		s.notifyClient(ch, client, fmt.Sprintf("⏸️ You have used all %d of your timeouts.", MaxTimeoutsPerDebater))
+		return
+	}
+
+	pause := s.pauseClock(ch, client.Name, models.PauseTimeout, TimeoutDuration)
+	used := ch.TimeoutsUsed[client.Name]
+
+	s.broadcast(ch, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text: fmt.Sprintf("⏸️ %s called a timeout (%d/%d). The clock is paused until %s.",
+			client.Name, used, MaxTimeoutsPerDebater, pause.AutoResume.Format("15:04:05")),
+		Timestamp: time.Now(),
+	})
+	s.emitPhaseChanged(ch)
+}
+
+// HandlePause lets a moderator stop the clock indefinitely
+func (s *ChannelService) HandlePause(ch *models.Channel, client *models.Client) {
+	if !client.IsModerator {
+		s.notifyClient(ch, client, "Only the channel moderator can pause the debate.")
+		return
+	}
+
+	if !debateActive(ch) {
+		s.notifyClient(ch, client, "⏸️ There is no debate running to pause.")
+		return
+	}
+	if ch.Pause != nil && ch.Pause.Reason == models.PauseModerator {
+		s.notifyClient(ch, client, "⏸️ The debate is already paused.")
+		return
+	}
+	// A running timeout is taken over and no longer ends on its own
+	s.pauseClock(ch, client.Name, models.PauseModerator, 0)
+
+	s.broadcast(ch, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       fmt.Sprintf("⏸️ The moderator %s has paused the debate.", client.Name),
+		Timestamp:  time.Now(),
+	})
+	s.emitPhaseChanged(ch)
+}
+
+// HandleResume restarts the clock; moderators may end any pause, debaters only their own timeout
+func (s *ChannelService) HandleResume(ch *models.Channel, client *models.Client) {
+	pause := ch.Pause
+
+	if pause == nil {
+		s.notifyClient(ch, client, "▶️ The debate is not paused.")
+		return
+	}
+	if !client.IsModerator && !(pause.Reason == models.PauseTimeout && pause.By == client.Name) {
+		s.notifyClient(ch, client, "Only the moderator or the debater who called the timeout can resume.")
+		return
+	}
+
+	s.resumeClock(ch, pause)
+}
+
+// pauseClock stops the phase clock, scheduling an automatic resume when autoResume is set; runs on the channel's actor
+func (s *ChannelService) pauseClock(ch *models.Channel, by, reason string, autoResume time.Duration) *models.Pause {
+	record(ch, models.ChannelEvent{Type: models.Paused, Name: by, Reason: reason, DurationSeconds: int(autoResume.Seconds())})
+	if autoResume > 0 {
+		s.scheduleResume(ch, ch.Pause)
+	}
+	return ch.Pause
+}
+
+// scheduleResume starts the timer ending pause at its AutoResume time; runs on the channel's actor
+func (s *ChannelService) scheduleResume(ch *models.Channel, pause *models.Pause) {
+	pause.ResumeTimer = time.AfterFunc(time.Until(pause.AutoResume), func() {
+		ch.Actor.Post(func() {
+			s.resumeClock(ch, pause)
+		})
+	})
+}
+
+// resumeClock ends the given pause, doing nothing if it was already ended or replaced; runs on the channel's actor
+func (s *ChannelService) resumeClock(ch *models.Channel, pause *models.Pause) {
+	if ch.Pause != pause {
+		return
+	}
+	record(ch, models.ChannelEvent{Type: models.Resumed})
+	remaining := ch.Phase.Remaining(time.Now()).Round(time.Second)
+
+	s.broadcast(ch, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       fmt.Sprintf("▶️ The debate has resumed. %s left in this phase.", remaining),
+		Timestamp:  time.Now(),
+	})
+	s.emitPhaseChanged(ch)
+}
+
+// clearPause drops any pause and pending extension without announcing it; runs on the channel's actor
+func clearPause(ch *models.Channel) {
+	if ch.Pause != nil && ch.Pause.ResumeTimer != nil {
+		ch.Pause.ResumeTimer.Stop()
+	}
+	ch.Pause = nil
+	ch.PendingExtension = nil
+}
+
+// HandleExtensionRequest asks the opponent to grant ExtensionStep more time in the current phase
+func (s *ChannelService) HandleExtensionRequest(ch *models.Channel, client *models.Client) {
+	if !client.CanSend {
+		s.notifyClient(ch, client, "Only debaters can request an extension.")
+		return
+	}
+
+	if !debateActive(ch) {
+		s.notifyClient(ch, client, "⏱️ Extensions can only be requested during the debate.")
+		return
+	}
+	if ch.PendingExtension != nil && ch.PendingExtension.Phase == ch.Phase.Id {
+		s.notifyClient(ch, client, "⏱️ An extension request is already waiting for an answer.")
+		return
+	}
+	record(ch, models.ChannelEvent{Type: models.ExtensionRequested, Name: client.Name, Phase: ch.Phase.Id, DurationSeconds: int(ExtensionStep.Seconds())})
+
+	s.broadcast(ch, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       fmt.Sprintf("⏱️ %s requests a %s extension for this phase. Opponent, do you accept?", client.Name, ExtensionStep),
+		Timestamp:  time.Now(),
+	})
+}
+
+// HandleExtensionAnswer applies the opponent's answer to a pending extension request
+func (s *ChannelService) HandleExtensionAnswer(ch *models.Channel, client *models.Client, accept bool) {
+	request := ch.PendingExtension
+	if request == nil || request.Phase != ch.Phase.Id || !debateActive(ch) {
+		s.notifyClient(ch, client, "⏱️ There is no extension request to answer.")
+		return
+	}
+	if !client.CanSend || client.Name == request.By {
+		s.notifyClient(ch, client, "⏱️ Only the opponent can answer this extension request.")
+		return
+	}
+
+	var text string
+	if accept {
+		record(ch, models.ChannelEvent{Type: models.ExtensionGranted, Name: client.Name})
+		remaining := ch.Phase.Remaining(time.Now()).Round(time.Second)
+		text = fmt.Sprintf("✅ %s accepted the extension. %s left in this phase.", client.Name, remaining)
+	} else {
+		record(ch, models.ChannelEvent{Type: models.ExtensionDeclined, Name: client.Name})
+		text = fmt.Sprintf("❌ %s declined %s's extension request.", client.Name, request.By)
+	}
+
+	s.broadcast(ch, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       text,
+		Timestamp:  time.Now(),
+	})
+	if accept {
+		s.emitPhaseChanged(ch)
+	}
+}
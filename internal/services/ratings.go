@@ -0,0 +1,279 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/store"
+)
+
+const ratingsDocument = "ratings"
+
+var ErrNameTaken = errors.New("this name is rated for another player")
+
+// playerIdentity turns a browser's session key into the identity players are told apart by. Only a
+// digest is kept, in ratings and channel logs alike, as the key itself would let anyone play as them.
+func playerIdentity(session string) string {
+	if session == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(session))
+	return hex.EncodeToString(sum[:16])
+}
+
+// RatingService keeps a Glicko-2 rating per player name, updated from judged debates. A name belongs
+// to the browser whose debates first rated it; debates played under it from elsewhere are not rated.
+type RatingService struct {
+	mu     sync.Mutex
+	saving sync.Mutex // Held from snapshot to write, so a slow save cannot overwrite a newer one
+	book   models.RatingBook
+	store  *store.Store
+}
+
+// NewRatingService loads the saved ratings, starting empty if there are none
+func NewRatingService(st *store.Store) (*RatingService, error) {
+	rs := &RatingService{
+		book: models.RatingBook{
+			Players: make(map[string]*models.Rating),
+			History: make(map[string][]models.RatingChange),
+			Owners:  make(map[string]string),
+		},
+		store: st,
+	}
+	if _, err := st.Load(ratingsDocument, &rs.book); err != nil {
+		return nil, err
+	}
+	if rs.book.Players == nil {
+		rs.book.Players = make(map[string]*models.Rating)
+	}
+	if rs.book.History == nil {
+		rs.book.History = make(map[string][]models.RatingChange)
+	}
+	if rs.book.Owners == nil {
+		// Saved before names had owners; each is claimed by the next browser to play under it
+		rs.book.Owners = make(map[string]string)
+	}
+	return rs, nil
+}
+
+// RecordResult rates a concluded one-on-one debate. Debates without a clear winner leave ratings untouched.
+func (rs *RatingService) RecordResult(archive *models.Archive) {
+	if archive.Winner == "" || len(archive.Participants) != 2 || archive.Forfeit {
+		// Forfeits are not rated: no debate was played
+		return
+	}
+	winner := archive.Winner
+	loser := archive.Participants[0]
+	if loser == winner {
+		loser = archive.Participants[1]
+	}
+	winnerId, loserId := archive.Identities[winner], archive.Identities[loser]
+	if winnerId == loserId {
+		// Unknown browsers, or one browser playing both sides
+		slog.Warn("debate not rated, the debaters are not known apart", "channel", archive.ChannelName, "winner", winner, "loser", loser)
+		return
+	}
+
+	rs.mu.Lock()
+	if !rs.mayPlayAs(winner, winnerId) || !rs.mayPlayAs(loser, loserId) {
+		rs.mu.Unlock()
+		slog.Warn("debate not rated, a debater played under a name rated for someone else",
+			"channel", archive.ChannelName, "winner", winner, "loser", loser)
+		return
+	}
+	rs.book.Owners[winner] = winnerId
+	rs.book.Owners[loser] = loserId
+	now := archive.ConcludedAt
+	w := rs.current(winner, now)
+	l := rs.current(loser, now)
+	newW := glickoUpdate(w, glickoGame{opponent: l, score: 1})
+	newL := glickoUpdate(l, glickoGame{opponent: w, score: 0})
+	rs.apply(winner, loser, true, w, newW, archive)
+	rs.apply(loser, winner, false, l, newL, archive)
+	rs.mu.Unlock()
+
+	slog.Info("ratings updated",
+		"winner", winner, "winner_from", w.rating(), "winner_to", newW.rating(),
+		"loser", loser, "loser_from", l.rating(), "loser_to", newL.rating())
+	rs.save()
+}
+
+// save writes the rating book to the store. Debates conclude concurrently, so saves are taken one
+// at a time, each snapshotting the book when its turn comes.
+func (rs *RatingService) save() {
+	rs.saving.Lock()
+	defer rs.saving.Unlock()
+
+	rs.mu.Lock()
+	book := rs.snapshot()
+	rs.mu.Unlock()
+
+	if err := rs.store.Save(ratingsDocument, book); err != nil {
+		slog.Error("saving ratings", "err", err)
+	}
+}
+
+// mayPlayAs reports whether the browser with identity may be rated under the player's name; caller must hold rs.mu
+func (rs *RatingService) mayPlayAs(player, identity string) bool {
+	owner := rs.book.Owners[player]
+	return identity != "" && (owner == "" || owner == identity)
+}
+
+// CheckName returns ErrNameTaken if the name's rating belongs to a browser other than the one with
+// the session key
+func (rs *RatingService) CheckName(player, session string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if !rs.mayPlayAs(player, playerIdentity(session)) {
+		return ErrNameTaken
+	}
+	return nil
+}
+
+// current returns a player's rating as of now, with the deviation grown for time away; caller must hold rs.mu
+func (rs *RatingService) current(player string, now time.Time) glickoPlayer {
+	r, ok := rs.book.Players[player]
+	if !ok {
+		return toGlicko(DefaultRating, DefaultRD, DefaultVolatility)
+	}
+	p := toGlicko(r.Rating, r.RD, r.Volatility)
+	return p.inflate(float64(now.Sub(r.LastPlayed) / RatingPeriod))
+}
+
+// apply stores a player's new rating and history entry; caller must hold rs.mu
+func (rs *RatingService) apply(player, opponent string, won bool, before, after glickoPlayer, archive *models.Archive) {
+	r, ok := rs.book.Players[player]
+	if !ok {
+		r = &models.Rating{Player: player}
+		rs.book.Players[player] = r
+	}
+	r.Rating = after.rating()
+	r.RD = after.rd()
+	r.Volatility = after.sigma
+	r.Games++
+	if won {
+		r.Wins++
+	} else {
+		r.Losses++
+	}
+	r.Provisional = r.RD > ProvisionalRD
+	r.LastPlayed = archive.ConcludedAt
+
+	rs.book.History[player] = append(rs.book.History[player], models.RatingChange{
+		At:           archive.ConcludedAt,
+		Channel:      archive.ChannelName,
+		Round:        archive.Round,
+		Opponent:     opponent,
+		Won:          won,
+		RatingBefore: before.rating(),
+		RatingAfter:  r.Rating,
+		RDBefore:     before.rd(),
+		RDAfter:      r.RD,
+	})
+}
+
+// snapshot deep-copies the rating book for saving outside the lock; caller must hold rs.mu
+func (rs *RatingService) snapshot() models.RatingBook {
+	book := models.RatingBook{
+		Players: make(map[string]*models.Rating, len(rs.book.Players)),
+		History: make(map[string][]models.RatingChange, len(rs.book.History)),
+		Owners:  make(map[string]string, len(rs.book.Owners)),
+	}
+	for name, owner := range rs.book.Owners {
+		book.Owners[name] = owner
+	}
+	for name, r := range rs.book.Players {
+		copied := *r
+		book.Players[name] = &copied
+	}
+	for name, changes := range rs.book.History {
+		book.History[name] = append([]models.RatingChange(nil), changes...)
+	}
+	return book
+}
+
+// ratingAt returns a player's rating with the deviation grown for time away; caller must hold rs.mu
+func (rs *RatingService) ratingAt(player string, now time.Time) models.Rating {
+	r, ok := rs.book.Players[player]
+	if !ok {
+		return models.Rating{
+			Player:      player,
+			Rating:      DefaultRating,
+			RD:          DefaultRD,
+			Volatility:  DefaultVolatility,
+			Provisional: true,
+		}
+	}
+	rating := *r
+	rating.RD = rs.current(player, now).rd()
+	rating.Provisional = rating.RD > ProvisionalRD
+	return rating
+}
+
+// GetRating returns a player's current rating; players who have never been rated get the defaults
+func (rs *RatingService) GetRating(player string) models.Rating {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.ratingAt(player, time.Now())
+}
+
+// History returns a player's rating changes, newest first
+func (rs *RatingService) History(player string) []models.RatingChange {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	changes := rs.book.History[player]
+	history := make([]models.RatingChange, len(changes))
+	for i, change := range changes {
+		history[len(changes)-1-i] = change
+	}
+	return history
+}
+
+// Leaderboard returns rated players by rating, established players ahead of provisional ones
+func (rs *RatingService) Leaderboard(offset, limit int, includeProvisional bool) models.Leaderboard {
+	rs.mu.Lock()
+	now := time.Now()
+	ratings := make([]models.Rating, 0, len(rs.book.Players))
+	for name := range rs.book.Players {
+		r := rs.ratingAt(name, now)
+		if r.Provisional && !includeProvisional {
+			continue
+		}
+		ratings = append(ratings, r)
+	}
+	rs.mu.Unlock()
+
+	sort.Slice(ratings, func(i, j int) bool {
+		if ratings[i].Provisional != ratings[j].Provisional {
+			return !ratings[i].Provisional
+		}
+		if ratings[i].Rating != ratings[j].Rating {
+			return ratings[i].Rating > ratings[j].Rating
+		}
+		return ratings[i].Player < ratings[j].Player
+	})
+
+	for i := range ratings {
+		if !ratings[i].Provisional {
+			ratings[i].Rank = i + 1
+		}
+	}
+
+	board := models.Leaderboard{Total: len(ratings), Offset: offset, Limit: limit}
+	if offset > len(ratings) {
+		offset = len(ratings)
+	}
+	end := len(ratings)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	board.Ratings = ratings[offset:end]
+	return board
+}
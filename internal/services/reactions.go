@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// HandleReaction toggles a reaction on a released debate message. The argument has the
+// form <messageId>:<reaction>; sending the same reaction again takes it back.
+func (s *ChannelService) HandleReaction(ch *models.Channel, client *models.Client, arg string) {
+	messageId, reaction, ok := strings.Cut(arg, ":")
+	if !ok || !slices.Contains(models.AllowedReactions, reaction) {
+		s.notifyClient(ch, client, "❓ React with one of "+strings.Join(models.AllowedReactions, " "))
+		return
+	}
+
+	if ch.Status != models.ChannelOpen {
+		s.notifyClient(ch, client, "🏁 This debate has concluded; reactions are closed.")
+		return
+	}
+	idx := -1
+	for i := range ch.Messages {
+		if ch.Messages[i].Id == messageId && ch.Messages[i].SenderType == "user" {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		s.notifyClient(ch, client, "❓ You can only react to debate messages that have been released.")
+		return
+	}
+
+	record(ch, models.ChannelEvent{Type: models.ReactionToggled, Name: client.Name, MessageId: messageId, Reaction: reaction})
+	data := models.ReactionUpdatedData{
+		MessageId: messageId,
+		Counts:    ch.Messages[idx].ReactionCounts(),
+	}
+
+	s.broadcastEvent(ch, models.EventReactionUpdated, data)
+}
+
+// engagementReport totals the reactions to each debater's messages, nil if nobody reacted; runs on the channel's actor
+func engagementReport(ch *models.Channel) *models.EngagementReport {
+	report := &models.EngagementReport{
+		ByDebater:  make(map[string]int),
+		ByReaction: make(map[string]map[string]int),
+	}
+	for _, msg := range ch.Messages {
+		if msg.SenderType != "user" || len(msg.Reactions) == 0 {
+			continue
+		}
+		total := 0
+		for reaction, count := range msg.ReactionCounts() {
+			if report.ByReaction[msg.SenderName] == nil {
+				report.ByReaction[msg.SenderName] = make(map[string]int)
+			}
+			report.ByReaction[msg.SenderName][reaction] += count
+			total += count
+		}
+		report.ByDebater[msg.SenderName] += total
+		report.TotalReactions += total
+		if total > report.TopCount {
+			top := msg
+			top.Reactions = nil
+			report.TopMessage = &top
+			report.TopCount = total
+		}
+	}
+	if report.TotalReactions == 0 {
+		return nil
+	}
+	return report
+}
+
+// engagementSummary renders the engagement report as text appended to the verdict
+func engagementSummary(report *models.EngagementReport) string {
+	debaters := make([]string, 0, len(report.ByDebater))
+	for name := range report.ByDebater {
+		debaters = append(debaters, name)
+	}
+	sort.Strings(debaters)
+
+	var builder strings.Builder
+	builder.WriteString("#### Audience Engagement\n")
+	parts := make([]string, 0, len(debaters))
+	for _, name := range debaters {
+		parts = append(parts, fmt.Sprintf("%s %d", name, report.ByDebater[name]))
+	}
+	builder.WriteString(fmt.Sprintf("%d reactions in total: %s.", report.TotalReactions, strings.Join(parts, ", ")))
+	if report.TopMessage != nil {
+		builder.WriteString(fmt.Sprintf(" The most reacted-to message was by %s (%d reactions).", report.TopMessage.SenderName, report.TopCount))
+	}
+	return builder.String()
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// newChannel allocates an empty channel for ChannelCreated to fill in
+func newChannel() *models.Channel {
+	return &models.Channel{
+		Sides:             make(map[string]string),
+		Identities:        make(map[string]string),
+		RematchRequests:   make(map[string]bool),
+		TimeoutsUsed:      make(map[string]int),
+		Votes:             models.NewAudienceVotes(),
+		SpectatorChat:     []models.Message{},
+		Muted:             make(map[string]bool),
+		Clients:           make(map[uuid.UUID]*models.Client),
+		Messages:          []models.Message{},
+		PendingMessages:   []models.Message{},
+		PhaseParticipants: make(map[string]map[string]bool),
+		Events:            []models.ChannelEvent{},
+	}
+}
+
+// RebuildChannel replays an event log into a new channel. The result has no sockets or secrets, and
+// replaying the same log always gives the same state.
+func RebuildChannel(events []models.ChannelEvent) *models.Channel {
+	ch := newChannel()
+	for _, ev := range events {
+		ev.Live = nil
+		ch.Events = append(ch.Events, ev)
+		applyEvent(ch, ev)
+	}
+	return ch
+}
+
+// record stamps an event, appends it to the channel's log and applies it; runs on the channel's actor
+func record(ch *models.Channel, ev models.ChannelEvent) {
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now()
+	}
+	ev.Seq = len(ch.Events) + 1
+
+	logged := ev
+	logged.Live = nil
+	ch.Events = append(ch.Events, logged)
+	applyEvent(ch, ev)
+}
+
+// applyEvent is the reducer: the one place the debate state changes
+func applyEvent(ch *models.Channel, ev models.ChannelEvent) {
+	switch ev.Type {
+	case models.ChannelCreated:
+		ch.ChannelId = ev.ChannelId
+		ch.Name = ev.Name
+		ch.Motion = ev.Motion
+		ch.Owner = ev.Owner
+		ch.Status = models.ChannelOpen
+		ch.Round = 1
+		ch.Series = models.Series{BestOf: ev.BestOf, Scores: make(map[string]int)}
+		ch.StartsAt = ev.StartsAt
+		ch.CreatedAt = ev.Timestamp
+		ch.LastActivity = ev.Timestamp
+		ch.Phase = models.Phase{Id: 0, Name: "Phase 0"} // No time limit for the lobby
+
+	case models.ClientJoined:
+		client := ev.Live
+		if client == nil {
+			client = &models.Client{Id: ev.Client, Name: ev.Name, CanSend: ev.CanSend, IsModerator: ev.Moderator}
+		}
+		ch.Clients[ev.Client] = client
+		ch.ClientCount++
+		ch.LastActivity = ev.Timestamp
+
+	case models.ClientLeft:
+		if _, ok := ch.Clients[ev.Client]; ok {
+			delete(ch.Clients, ev.Client)
+			ch.ClientCount--
+		}
+		ch.LastActivity = ev.Timestamp
+
+	case models.SidesAssigned:
+		for name, side := range ev.Sides {
+			ch.Sides[name] = side
+		}
+
+	case models.Engaged:
+		if client := ch.Clients[ev.Client]; client != nil {
+			client.Ready = true
+		}
+		if ev.Side != "" {
+			ch.Sides[ev.Name] = ev.Side
+		}
+		if ev.Identity != "" {
+			ch.Identities[ev.Name] = ev.Identity
+		}
+
+	case models.MessagePosted:
+		ch.Messages = append(ch.Messages, *ev.Message)
+		ch.LastActivity = ev.Timestamp
+
+	case models.Submitted:
+		key := phaseKey(ch.Phase.Id)
+		if ch.PhaseParticipants[key] == nil {
+			ch.PhaseParticipants[key] = make(map[string]bool)
+		}
+		ch.PhaseParticipants[key][ev.Name] = true
+		ch.PendingMessages = append(ch.PendingMessages, *ev.Message)
+
+	case models.PhaseReleased:
+		ch.PendingMessages = []models.Message{}
+
+	case models.PhaseAdvanced:
+		ch.Phase = models.Phase{
+			Id:        ev.Phase,
+			Name:      fmt.Sprintf("Phase %d", ev.Phase),
+			StartTime: ev.Timestamp,
+			Duration:  time.Duration(ev.DurationSeconds) * time.Second,
+		}
+		if ch.Pause != nil {
+			// Paused during the analysis: the new phase starts with its clock stopped
+			ch.Phase.PauseAt(ev.Timestamp)
+		}
+		ch.PendingExtension = nil
+		if ev.Phase == 1 {
+			ch.PhaseParticipants = make(map[string]map[string]bool)
+			ch.PendingMessages = []models.Message{}
+		}
+		ch.PhaseParticipants[phaseKey(ev.Phase)] = make(map[string]bool)
+
+	case models.Verdict:
+		// Free live debate state; the transcript stays in Messages for connected viewers
+		ch.Status = models.ChannelArchived
+		ch.PendingMessages = []models.Message{}
+		ch.PhaseParticipants = make(map[string]map[string]bool)
+		clearPause(ch)
+		if ev.Winner != "" {
+			ch.Series.Scores[ev.Winner]++
+			if ch.Series.Scores[ev.Winner] >= ch.Series.WinsNeeded() {
+				ch.Series.Winner = ev.Winner
+			}
+		}
+
+	case models.RematchStarted:
+		if ch.Series.Winner != "" {
+			ch.Series = models.Series{BestOf: ch.Series.BestOf, Scores: make(map[string]int)}
+		}
+		ch.Round++
+		if ev.Swap {
+			for name, side := range ch.Sides {
+				if side == models.SideProposition {
+					ch.Sides[name] = models.SideOpposition
+				} else {
+					ch.Sides[name] = models.SideProposition
+				}
+			}
+		}
+		ch.Status = models.ChannelOpen
+		ch.Phase = models.Phase{Id: 0, Name: "Phase 0"}
+		ch.Messages = []models.Message{} // The previous round's transcript lives in its archive
+		ch.PendingMessages = []models.Message{}
+		ch.PhaseParticipants = make(map[string]map[string]bool)
+		ch.RematchRequests = make(map[string]bool)
+		ch.RematchSwap = false
+		ch.TimeoutsUsed = make(map[string]int)
+		ch.Votes = models.NewAudienceVotes()
+		clearPause(ch)
+		for _, c := range ch.Clients {
+			c.Ready = false
+		}
+
+	case models.Paused:
+		if ch.Pause != nil && ch.Pause.ResumeTimer != nil {
+			// A moderator taking over a running timeout, which no longer ends on its own
+			ch.Pause.ResumeTimer.Stop()
+		}
+		if ev.Reason == models.PauseTimeout {
+			ch.TimeoutsUsed[ev.Name]++
+		}
+		ch.Phase.PauseAt(ev.Timestamp)
+		ch.Pause = &models.Pause{By: ev.Name, Reason: ev.Reason, Since: ev.Timestamp}
+		if ev.DurationSeconds > 0 {
+			ch.Pause.AutoResume = ev.Timestamp.Add(time.Duration(ev.DurationSeconds) * time.Second)
+		}
+
+	case models.Resumed:
+		ch.Phase.ResumeAt(ev.Timestamp)
+		if ch.Pause != nil && ch.Pause.ResumeTimer != nil {
+			ch.Pause.ResumeTimer.Stop()
+		}
+		ch.Pause = nil
+
+	case models.ExtensionRequested:
+		ch.PendingExtension = &models.ExtensionRequest{
+			By:     ev.Name,
+			Phase:  ev.Phase,
+			Amount: time.Duration(ev.DurationSeconds) * time.Second,
+		}
+
+	case models.ExtensionGranted:
+		if ch.PendingExtension != nil {
+			ch.Phase.Extension += ch.PendingExtension.Amount
+		}
+		ch.PendingExtension = nil
+
+	case models.ExtensionDeclined:
+		ch.PendingExtension = nil
+
+	case models.RematchRequested:
+		ch.RematchRequests[ev.Name] = true
+		if ev.Swap {
+			ch.RematchSwap = true
+		}
+
+	case models.VoteCast:
+		switch ev.Ballot {
+		case models.BallotPre:
+			ch.Votes.Pre[ev.Name] = ev.Choice
+		case models.BallotPost:
+			ch.Votes.Post[ev.Name] = ev.Choice
+		case models.BallotPhase:
+			if ch.Votes.Phases[ev.Phase] == nil {
+				ch.Votes.Phases[ev.Phase] = make(map[string]string)
+			}
+			ch.Votes.Phases[ev.Phase][ev.Name] = ev.Choice
+		}
+
+	case models.ReactionToggled:
+		toggleReaction(ch, ev.MessageId, ev.Reaction, ev.Name)
+
+	case models.SpectatorChatted:
+		ch.SpectatorChat = append(ch.SpectatorChat, *ev.Message)
+		if len(ch.SpectatorChat) > SpectatorChatHistory {
+			ch.SpectatorChat = ch.SpectatorChat[len(ch.SpectatorChat)-SpectatorChatHistory:]
+		}
+		ch.LastActivity = ev.Timestamp
+
+	case models.SpectatorMuted:
+		ch.Muted[ev.Name] = true
+
+	case models.SpectatorUnmuted:
+		delete(ch.Muted, ev.Name)
+
+	case models.AIUsageRecorded:
+		ch.AIUsage.Add(*ev.Usage)
+
+	case models.ServerRestarted:
+		// The clock was stopped while the server was down
+		if !ch.Phase.StartTime.IsZero() {
+			ch.Phase.StartTime = ch.Phase.StartTime.Add(ev.Downtime)
+		}
+		if ch.Phase.Paused() {
+			ch.Phase.PausedAt = ch.Phase.PausedAt.Add(ev.Downtime)
+		}
+		if ch.Pause != nil {
+			ch.Pause.Since = ch.Pause.Since.Add(ev.Downtime)
+			if !ch.Pause.AutoResume.IsZero() {
+				ch.Pause.AutoResume = ch.Pause.AutoResume.Add(ev.Downtime)
+			}
+		}
+		ch.LastActivity = ev.Timestamp
+	}
+}
+
+// toggleReaction adds name's reaction to a debate message, or takes it back if it was already there.
+// The message gets a new reactions map rather than an updated one, as copies of it handed out by
+// MessagePage and the admin view are encoded off the actor.
+func toggleReaction(ch *models.Channel, messageId, reaction, name string) {
+	for i := range ch.Messages {
+		msg := &ch.Messages[i]
+		if msg.Id != messageId {
+			continue
+		}
+		reactions := make(map[string][]string, len(msg.Reactions)+1)
+		for r, names := range msg.Reactions {
+			reactions[r] = names
+		}
+		names := msg.Reactions[reaction]
+		if j := slices.Index(names, name); j >= 0 {
+			names = slices.Concat(names[:j], names[j+1:])
+		} else {
+			names = slices.Concat(names, []string{name})
+		}
+		if len(names) == 0 {
+			delete(reactions, reaction)
+		} else {
+			reactions[reaction] = names
+		}
+		msg.Reactions = reactions
+		return
+	}
+}
+
+// phaseKey names a phase in PhaseParticipants
+func phaseKey(phaseId int) string {
+	return fmt.Sprintf("phase_%d", phaseId)
+}
+
+// EventLog returns the channel's events after sequence number since, oldest first. Submissions are
+// sealed until their phase is released, so the text of those still pending is withheld, as are the
+// debaters' browser identities.
+func (s *ChannelService) EventLog(ch *models.Channel, since int) []models.ChannelEvent {
+	events := []models.ChannelEvent{}
+	ch.Actor.Do(func() {
+		if since < 0 {
+			since = 0
+		}
+		if since > len(ch.Events) {
+			since = len(ch.Events)
+		}
+		events = make([]models.ChannelEvent, len(ch.Events)-since)
+		copy(events, ch.Events[since:])
+		for i := range events {
+			events[i].Identity = ""
+		}
+
+		for _, i := range sealedSubmissions(ch.Events) {
+			if i < since {
+				continue
+			}
+			sealed := *events[i-since].Message
+			sealed.Text = ""
+			events[i-since].Message = &sealed
+		}
+	})
+	return events
+}
+
+// sealedSubmissions returns the indexes of the Submitted events in events that were never released,
+// because their phase is still running or the debate ended before it was
+func sealedSubmissions(events []models.ChannelEvent) []int {
+	var sealed, pending []int
+	for i, ev := range events {
+		switch {
+		case ev.Type == models.Submitted:
+			pending = append(pending, i)
+		case ev.Type == models.PhaseReleased:
+			pending = nil
+		case ev.Type == models.Verdict, ev.Type == models.RematchStarted, ev.Type == models.PhaseAdvanced && ev.Phase == 1:
+			// The reducer discards pending submissions here without revealing them
+			sealed = append(sealed, pending...)
+			pending = nil
+		}
+	}
+	return append(sealed, pending...)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// TestRebuildChannelMatchesLiveState plays a debate through the commands clients send, then checks
+// that replaying the channel's event log gives back the state the commands left behind
+func TestRebuildChannelMatchesLiveState(t *testing.T) {
+	s := newTestService(t)
+	ch, err := s.CreateChannel(models.ChannelOptions{Name: "replay", Password: "pw", Motion: "Logs are the truth"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.DeleteChannel("replay")
+	ctx := context.Background()
+
+	ann := &models.Client{Id: uuid.New(), Name: "ann", CanSend: true, IsModerator: true}
+	bob := &models.Client{Id: uuid.New(), Name: "bob", CanSend: true}
+	viewer := &models.Client{Id: uuid.New(), Name: "viewer"}
+	heckler := &models.Client{Id: uuid.New(), Name: "heckler"}
+	for _, c := range []*models.Client{ann, bob, viewer, heckler} {
+		if err := s.AddClient(ch, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send := func(c *models.Client, text string) {
+		t.Helper()
+		s.HandleInput(ctx, ch, c, text)
+	}
+	send(ann, "Good luck, bob")
+	var lobbyMessage string
+	inChannel(ch, func() {
+		for _, msg := range ch.Messages {
+			if msg.SenderType == "user" {
+				lobbyMessage = msg.Id
+			}
+		}
+	})
+	send(viewer, "__REACT__:"+lobbyMessage+":👏")
+	send(heckler, "__REACT__:"+lobbyMessage+":👏")
+	send(heckler, "__REACT__:"+lobbyMessage+":👏") // Taken back
+	send(viewer, "__VOTE__:pre:for")
+	send(heckler, "__VOTE__:pre:against")
+	send(viewer, "Exciting!")
+	send(ann, "__MUTE__:heckler")
+
+	send(ann, "__ENGAGE__")
+	send(bob, "__ENGAGE__")
+	send(bob, "__TIMEOUT__")
+	send(ann, "__PAUSE__") // Takes over bob's timeout
+	send(ann, "__RESUME__")
+	send(bob, "__EXTEND__")
+	send(ann, "__EXTEND__:accept")
+	send(viewer, "__VOTE__:phase:1:bob")
+	send(ann, "My opening statement")
+
+	type snapshot struct {
+		Status           string
+		Round            int
+		Series           models.Series
+		Sides            map[string]string
+		Phase            models.Phase
+		Pause            *models.Pause
+		PendingExtension *models.ExtensionRequest
+		TimeoutsUsed     map[string]int
+		RematchRequests  map[string]bool
+		Votes            models.AudienceVotes
+		SpectatorChat    []models.Message
+		Muted            map[string]bool
+		AIUsage          models.AIUsage
+		Messages         []models.Message
+		PendingMessages  []models.Message
+		Participants     map[string]map[string]bool
+	}
+	take := func(ch *models.Channel) snapshot {
+		return snapshot{
+			Status: ch.Status, Round: ch.Round, Series: ch.Series, Sides: ch.Sides,
+			Phase: ch.Phase, Pause: ch.Pause, PendingExtension: ch.PendingExtension,
+			TimeoutsUsed: ch.TimeoutsUsed, RematchRequests: ch.RematchRequests,
+			Votes: ch.Votes, SpectatorChat: ch.SpectatorChat, Muted: ch.Muted, AIUsage: ch.AIUsage,
+			Messages: ch.Messages, PendingMessages: ch.PendingMessages, Participants: ch.PhaseParticipants,
+		}
+	}
+
+	var live, rebuilt snapshot
+	inChannel(ch, func() {
+		live = take(ch)
+		rebuilt = take(RebuildChannel(ch.Events))
+	})
+
+	if live.Phase.Id != 1 || live.Phase.Extension != ExtensionStep || live.Phase.PausedTotal == 0 {
+		t.Fatalf("live phase = %+v, want phase 1 with an extension and a finished pause", live.Phase)
+	}
+	if live.TimeoutsUsed["bob"] != 1 || !live.Muted["heckler"] || len(live.Votes.Phases[1]) != 1 {
+		t.Fatalf("live state missed commands: timeouts %v, muted %v, votes %+v", live.TimeoutsUsed, live.Muted, live.Votes)
+	}
+	if !reflect.DeepEqual(live, rebuilt) {
+		t.Errorf("rebuilt state differs from the live one\nlive:    %+v\nrebuilt: %+v", live, rebuilt)
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+const (
+	ReplayMinSpeed = 0.25
+	ReplayMaxSpeed = 16
+
+	// ReplayMaxGap caps the wait between two replayed messages so long AI analyses and idle
+	// stretches do not stall playback
+	ReplayMaxGap = 10 * time.Second
+)
+
+var ErrInvalidSpeed = errors.New("speed must be between 0.25 and 16")
+
+// Replay plays an archived round back over a WebSocket in the live message format, paced by the
+// messages' original timestamps. Messages before the start phase are sent at once, like the
+// history a late joiner gets.
+type Replay struct {
+	archive  *models.Archive
+	start    int // Index of the first message played in real time
+	speed    float64
+	paused   bool
+	position int // Index of the next message to send
+	phase    int // Phase announced to the viewer
+	commands chan string
+}
+
+// NewReplay prepares a replay of an archived round from startPhase; round 0 picks the latest
+func (s *ChannelService) NewReplay(name string, round, startPhase int, speed float64) (*Replay, error) {
+	if speed == 0 {
+		speed = 1
+	}
+	if speed < ReplayMinSpeed || speed > ReplayMaxSpeed {
+		return nil, ErrInvalidSpeed
+	}
+	archive, err := s.findArchive(name, round)
+	if err != nil {
+		return nil, err
+	}
+
+	start := len(archive.Messages)
+	for i, msg := range archive.Messages {
+		if msg.Phase >= startPhase {
+			start = i
+			break
+		}
+	}
+	return &Replay{
+		archive:  archive,
+		start:    start,
+		speed:    speed,
+		phase:    -1,
+		commands: make(chan string, 8),
+	}, nil
+}
+
+// Command queues a control command from the viewer: __PAUSE__, __RESUME__, __SPEED__:<factor> or
+// __SEEK__:<phase>
+func (r *Replay) Command(text string) {
+	select {
+	case r.commands <- text:
+	default:
+		// Viewer is flooding; drop rather than block its read loop
+	}
+}
+
+// Play sends the round to conn until done is closed, waiting for the viewer to seek back once it has
+// all been sent; it is the only writer on conn
+func (r *Replay) Play(conn *websocket.Conn, done <-chan struct{}) {
+	messages := r.archive.Messages
+	for r.position < r.start {
+		if !r.emit(conn) {
+			return
+		}
+	}
+	if r.position < len(messages) {
+		r.sendPhase(conn, messages[r.position].Phase)
+		r.sendStatus(conn)
+	} else {
+		r.finish(conn)
+	}
+
+	remaining := r.gap()
+	for {
+		var timer *time.Timer
+		var tick <-chan time.Time
+		waitStart := time.Now()
+		if !r.paused && r.position < len(messages) {
+			timer = time.NewTimer(remaining)
+			tick = timer.C
+		}
+
+		select {
+		case <-done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-tick:
+			if !r.emit(conn) {
+				return
+			}
+			remaining = r.gap()
+			if r.position == len(messages) {
+				r.finish(conn)
+			}
+		case cmd := <-r.commands:
+			if timer != nil {
+				timer.Stop()
+				remaining -= time.Since(waitStart)
+				if remaining < 0 {
+					remaining = 0
+				}
+			}
+			remaining = r.apply(conn, cmd, remaining)
+			r.sendStatus(conn)
+		}
+	}
+}
+
+// finish closes the replay the way a live debate ends
+func (r *Replay) finish(conn *websocket.Conn) {
+	conn.WriteJSON(models.Event{
+		Type:      models.EventPhaseChanged,
+		Channel:   r.archive.ChannelName,
+		Timestamp: time.Now(),
+		Data:      r.phaseData(r.phase, models.ChannelArchived),
+	})
+	r.sendStatus(conn)
+}
+
+// apply handles a viewer command and returns the wait left before the next message
+func (r *Replay) apply(conn *websocket.Conn, text string, remaining time.Duration) time.Duration {
+	name, arg, ok := parseCommand(text)
+	switch {
+	case ok && name == "PAUSE":
+		r.paused = true
+	case ok && name == "RESUME":
+		r.paused = false
+	case ok && name == "SPEED":
+		speed, err := strconv.ParseFloat(arg, 64)
+		if err != nil || speed < ReplayMinSpeed || speed > ReplayMaxSpeed {
+			r.notify(conn, "❓ "+ErrInvalidSpeed.Error())
+			break
+		}
+		remaining = time.Duration(float64(remaining) * r.speed / speed)
+		r.speed = speed
+	case ok && name == "SEEK":
+		phase, err := strconv.Atoi(arg)
+		if err != nil || !r.seek(conn, phase) {
+			r.notify(conn, fmt.Sprintf("❓ Round %d has no phase %s", r.archive.Round, arg))
+			break
+		}
+		remaining = 0
+	default:
+		r.notify(conn, "❓ Replays only accept __PAUSE__, __RESUME__, __SPEED__:<factor> and __SEEK__:<phase>")
+	}
+	return remaining
+}
+
+// seek moves playback to the first message of a phase, forwards or back, and announces the phase
+// again; it reports false when the round has no message in that phase
+func (r *Replay) seek(conn *websocket.Conn, phase int) bool {
+	for i, msg := range r.archive.Messages {
+		if msg.Phase == phase {
+			r.position = i
+			r.phase = -1
+			r.sendPhase(conn, phase)
+			return true
+		}
+	}
+	return false
+}
+
+// gap returns how long to wait before sending the next message at the current speed
+func (r *Replay) gap() time.Duration {
+	if r.position <= r.start || r.position >= len(r.archive.Messages) {
+		return 0
+	}
+	d := r.archive.Messages[r.position].Timestamp.Sub(r.archive.Messages[r.position-1].Timestamp)
+	if d < 0 {
+		d = 0
+	}
+	if d > ReplayMaxGap {
+		d = ReplayMaxGap
+	}
+	return time.Duration(float64(d) / r.speed)
+}
+
+// emit sends the next message, announcing a phase change first, and reports whether the write succeeded
+func (r *Replay) emit(conn *websocket.Conn) bool {
+	msg := r.archive.Messages[r.position]
+	if msg.Phase != r.phase && !r.sendPhase(conn, msg.Phase) {
+		return false
+	}
+	r.position++
+	return conn.WriteJSON(msg) == nil
+}
+
+func (r *Replay) sendPhase(conn *websocket.Conn, phase int) bool {
+	if phase == r.phase {
+		return true
+	}
+	r.phase = phase
+	return conn.WriteJSON(models.Event{
+		Type:      models.EventPhaseChanged,
+		Channel:   r.archive.ChannelName,
+		Timestamp: time.Now(),
+		Data:      r.phaseData(phase, models.ChannelOpen),
+	}) == nil
+}
+
+// phaseData describes a replayed phase; replays have no clock, so the phase is untimed
+func (r *Replay) phaseData(phase int, status string) models.PhaseChangedData {
+	return models.PhaseChangedData{
+		Id:              phase,
+		Name:            fmt.Sprintf("Phase %d", phase),
+		Title:           phaseTitle(phase),
+		Status:          status,
+		Round:           r.archive.Round,
+		AllowedSpeakers: []string{},
+	}
+}
+
+func (r *Replay) sendStatus(conn *websocket.Conn) {
+	status := models.ReplayStatusData{
+		Playing:  !r.paused && r.position < len(r.archive.Messages),
+		Speed:    r.speed,
+		Phase:    r.phase,
+		Position: r.position,
+		Total:    len(r.archive.Messages),
+		Phases:   []int{},
+		Finished: r.position >= len(r.archive.Messages),
+	}
+	for _, msg := range r.archive.Messages {
+		if n := len(status.Phases); n == 0 || status.Phases[n-1] != msg.Phase {
+			status.Phases = append(status.Phases, msg.Phase)
+		}
+	}
+	conn.WriteJSON(models.Event{
+		Type:      models.EventReplayStatus,
+		Channel:   r.archive.ChannelName,
+		Timestamp: time.Now(),
+		Data:      status,
+	})
+}
+
+func (r *Replay) notify(conn *websocket.Conn, text string) {
+	conn.WriteJSON(models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       text,
+		Timestamp:  time.Now(),
+	})
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/config"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/store"
+)
+
+const (
+	schedulesDocument = "schedules"
+
+	// ScheduleReminderLead is how long before the start the debaters are reminded
+	ScheduleReminderLead = 5 * time.Minute
+
+	// ScheduledDebateLength is the expected length of a debate, used for calendar entries
+	ScheduledDebateLength = 30 * time.Minute
+)
+
+var ErrStartInPast = errors.New("scheduled start must be in the future")
+
+// ScheduleService runs debates booked for a fixed start time: it reminds the debaters shortly
+// before, starts the debate on time if both are ready and records a no-show forfeit otherwise.
+// Schedules are persisted so their channels come back after a restart, and forgotten a retention
+// period after they are resolved.
+type ScheduleService struct {
+	mu        sync.Mutex
+	saving    sync.Mutex                         // Held from snapshot to write, so a slow save cannot overwrite a newer one
+	schedules map[string]*models.ScheduledDebate // By channel name
+	retention time.Duration                      // How long resolved schedules stay listed
+	channels  *ChannelService
+	store     *store.Store
+}
+
+// NewScheduleService loads persisted schedules and re-creates the channels of those still upcoming.
+// Starts missed while the server was down are settled as no-shows on the first run.
+func NewScheduleService(channels *ChannelService, st *store.Store) (*ScheduleService, error) {
+	ss := &ScheduleService{
+		schedules: make(map[string]*models.ScheduledDebate),
+		channels:  channels,
+		store:     st,
+		retention: config.Default().Limits.ScheduleRetention.Duration,
+	}
+
+	var saved models.ScheduleStore
+	if _, err := st.Load(schedulesDocument, &saved); err != nil {
+		return nil, err
+	}
+	for _, stored := range saved.Schedules {
+		sd := stored.ScheduledDebate
+		sd.Password = stored.Password
+		sd.OwnerKey = stored.OwnerKey
+		if sd.Status != models.ScheduleUpcoming && sd.ResolvedAt.IsZero() {
+			sd.ResolvedAt = sd.StartsAt // Saved before resolutions were timed
+		}
+		ss.schedules[sd.Channel] = &sd
+
+		if sd.Status == models.ScheduleUpcoming && channels.GetChannel(sd.Channel) == nil {
+			_, err := channels.CreateChannel(models.ChannelOptions{
+				Name:     sd.Channel,
+				Password: sd.Password,
+				Motion:   sd.Motion,
+				Owner:    sd.Owner,
+				BestOf:   sd.BestOf,
+				StartsAt: sd.StartsAt,
+				Id:       sd.ChannelId,
+				OwnerKey: sd.OwnerKey,
+			})
+			if err != nil {
+				slog.Error("restoring scheduled channel", "channel", sd.Channel, "err", err)
+				continue
+			}
+			slog.Info("scheduled channel restored", "channel", sd.Channel, "starts_at", sd.StartsAt)
+		}
+	}
+	return ss, nil
+}
+
+// Schedule creates a channel whose debate starts at opts.StartsAt
+func (ss *ScheduleService) Schedule(opts models.ChannelOptions) (*models.Channel, error) {
+	if !opts.StartsAt.After(time.Now()) {
+		return nil, ErrStartInPast
+	}
+	if ss.channels.ChannelExists(opts.Name) {
+		return nil, ErrChannelExists
+	}
+
+	opts.StartsAt = opts.StartsAt.UTC().Truncate(time.Second)
+	ch, err := ss.channels.CreateChannel(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bestOf := opts.BestOf
+	if bestOf < 1 {
+		bestOf = 1
+	}
+	ss.mu.Lock()
+	ss.schedules[ch.Name] = &models.ScheduledDebate{
+		ChannelId: ch.ChannelId,
+		Channel:   ch.Name,
+		Motion:    ch.Motion,
+		Owner:     ch.Owner,
+		BestOf:    bestOf,
+		StartsAt:  opts.StartsAt,
+		Status:    models.ScheduleUpcoming,
+		CreatedAt: time.Now(),
+		Password:  ch.Password,
+		OwnerKey:  ch.OwnerKey,
+	}
+	ss.mu.Unlock()
+	ss.save()
+
+	channelLog(ch).Info("channel scheduled", "starts_at", opts.StartsAt)
+	return ch, nil
+}
+
+// Get returns the schedule of a channel
+func (ss *ScheduleService) Get(channel string) (models.ScheduledDebate, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	sd, ok := ss.schedules[channel]
+	if !ok {
+		return models.ScheduledDebate{}, false
+	}
+	return copySchedule(sd), true
+}
+
+// List returns the schedules in start order, only the upcoming ones unless all is set
+func (ss *ScheduleService) List(all bool) []models.ScheduledDebate {
+	ss.mu.Lock()
+	list := make([]models.ScheduledDebate, 0, len(ss.schedules))
+	for _, sd := range ss.schedules {
+		if all || sd.Status == models.ScheduleUpcoming {
+			list = append(list, copySchedule(sd))
+		}
+	}
+	ss.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].StartsAt.Equal(list[j].StartsAt) {
+			return list[i].StartsAt.Before(list[j].StartsAt)
+		}
+		return list[i].Channel < list[j].Channel
+	})
+	return list
+}
+
+// Start checks the schedules every interval until ctx is cancelled, forgetting those resolved longer
+// than retention ago
+func (ss *ScheduleService) Start(ctx context.Context, interval, retention time.Duration) {
+	ss.mu.Lock()
+	ss.retention = retention
+	ss.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ss.runSchedules(time.Now())
+			}
+		}
+	}()
+}
+
+// runSchedules cancels schedules whose channel is gone, sends due reminders, settles schedules
+// whose start time has come and forgets those resolved longer than the retention ago
+func (ss *ScheduleService) runSchedules(now time.Time) {
+	changed := false
+	ss.mu.Lock()
+	var upcoming []*models.ScheduledDebate
+	for name, sd := range ss.schedules {
+		switch {
+		case sd.Status == models.ScheduleUpcoming:
+			upcoming = append(upcoming, sd)
+		case now.Sub(sd.ResolvedAt) > ss.retention:
+			delete(ss.schedules, name)
+			changed = true
+		}
+	}
+	ss.mu.Unlock()
+
+	for _, sd := range upcoming {
+		if ss.advance(sd, now) {
+			changed = true
+		}
+	}
+	if changed {
+		ss.save()
+	}
+}
+
+// advance moves an upcoming schedule along, reporting whether it changed
+func (ss *ScheduleService) advance(sd *models.ScheduledDebate, now time.Time) bool {
+	ss.mu.Lock()
+	name, id, startsAt, reminded := sd.Channel, sd.ChannelId, sd.StartsAt, sd.ReminderSent
+	ss.mu.Unlock()
+
+	ch := ss.channels.GetChannel(name)
+	if ch == nil || ch.ChannelId != id {
+		ss.mu.Lock()
+		sd.Status = models.ScheduleCancelled
+		sd.ResolvedAt = now
+		ss.mu.Unlock()
+		slog.Info("schedule cancelled, channel gone", "channel", name)
+		return true
+	}
+
+	if now.Before(startsAt.Add(-ScheduleReminderLead)) {
+		return false
+	}
+
+	if now.Before(startsAt) {
+		if reminded {
+			return false
+		}
+		if !ch.Actor.Do(func() {
+			ready, waiting := scheduleReadiness(ch)
+			ss.channels.broadcast(ch, models.Message{
+				SenderType: "system",
+				SenderName: "system",
+				Text:       fmt.Sprintf("⏰ The debate starts in %s. Debaters, engage before the start or forfeit.", formatCountdown(startsAt.Sub(now))),
+				Timestamp:  now,
+			})
+			ss.channels.broadcastEvent(ch, models.EventDebateReminder, models.DebateReminderData{
+				StartsAt:    startsAt,
+				SecondsLeft: int(startsAt.Sub(now).Seconds()),
+				Ready:       ready,
+				Waiting:     waiting,
+			})
+		}) {
+			return false // Deleted meanwhile; the next run cancels the schedule
+		}
+		ss.mu.Lock()
+		sd.ReminderSent = true
+		ss.mu.Unlock()
+		return true
+	}
+
+	status := models.ScheduleStarted
+	winner := ""
+	var noShows []string
+	if !ch.Actor.Do(func() {
+		ready, waiting := scheduleReadiness(ch)
+		if len(ready) >= RequiredDebaters {
+			ss.channels.beginDebate(context.Background(), ch)
+			return
+		}
+		if len(ready) == 1 {
+			winner = ready[0]
+		}
+		if ss.channels.forfeitDebate(ch, winner, waiting) != nil {
+			status = models.ScheduleForfeited
+			noShows = waiting
+		} else {
+			// The debaters engaged and started it in the meantime
+			winner = ""
+		}
+	}) {
+		return false
+	}
+
+	ss.mu.Lock()
+	sd.Status = status
+	sd.Winner = winner
+	sd.NoShows = noShows
+	sd.ResolvedAt = now
+	ss.mu.Unlock()
+	slog.Info("scheduled debate resolved", "channel", name, "status", status)
+	return true
+}
+
+// scheduleReadiness splits the expected debaters of a channel into those present and ready and the rest;
+// runs on the channel's actor
+func scheduleReadiness(ch *models.Channel) (ready, waiting []string) {
+	expected := make(map[string]bool)
+	for name := range ch.Sides {
+		expected[name] = true
+	}
+	isReady := make(map[string]bool)
+	for _, c := range ch.Clients {
+		if c.CanSend {
+			expected[c.Name] = true
+			if c.Ready {
+				isReady[c.Name] = true
+			}
+		}
+	}
+
+	ready, waiting = []string{}, []string{}
+	for name := range expected {
+		if isReady[name] {
+			ready = append(ready, name)
+		} else {
+			waiting = append(waiting, name)
+		}
+	}
+	sort.Strings(ready)
+	sort.Strings(waiting)
+	return ready, waiting
+}
+
+// formatCountdown renders a duration as whole minutes, or seconds under a minute
+func formatCountdown(d time.Duration) string {
+	if d < time.Minute {
+		if seconds := int(d.Seconds()); seconds != 1 {
+			return fmt.Sprintf("%d seconds", seconds)
+		}
+		return "1 second"
+	}
+	minutes := int(d.Round(time.Minute).Minutes())
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
+
+func copySchedule(sd *models.ScheduledDebate) models.ScheduledDebate {
+	c := *sd
+	c.NoShows = append([]string(nil), sd.NoShows...)
+	return c
+}
+
+func (ss *ScheduleService) save() {
+	ss.saving.Lock()
+	defer ss.saving.Unlock()
+
+	ss.mu.Lock()
+	saved := models.ScheduleStore{Schedules: make([]models.StoredSchedule, 0, len(ss.schedules))}
+	for _, sd := range ss.schedules {
+		saved.Schedules = append(saved.Schedules, models.StoredSchedule{
+			ScheduledDebate: copySchedule(sd),
+			Password:        sd.Password,
+			OwnerKey:        sd.OwnerKey,
+		})
+	}
+	ss.mu.Unlock()
+
+	if err := ss.store.Save(schedulesDocument, saved); err != nil {
+		slog.Error("saving schedules", "err", err)
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/store"
+)
+
+// TestResolvedSchedulesForgotten checks that a resolved schedule stays listed for the retention
+// period and is forgotten after, while upcoming ones are kept however long they wait
+func TestResolvedSchedulesForgotten(t *testing.T) {
+	st, err := store.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels := newTestService(t)
+	ss, err := NewScheduleService(channels, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.retention = time.Hour
+
+	startsAt := time.Now().Add(24 * time.Hour)
+	for _, name := range []string{"kept", "dropped"} {
+		if _, err := ss.Schedule(models.ChannelOptions{Name: name, Password: "pw", Motion: "Schedules end", StartsAt: startsAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer channels.DeleteChannel("kept")
+	channels.DeleteChannel("dropped")
+
+	now := time.Now()
+	ss.runSchedules(now)
+	if sd, _ := ss.Get("dropped"); sd.Status != models.ScheduleCancelled {
+		t.Fatalf("schedule of a deleted channel is %s, want cancelled", sd.Status)
+	}
+
+	ss.runSchedules(now.Add(ss.retention / 2))
+	if _, ok := ss.Get("dropped"); !ok {
+		t.Error("cancelled schedule forgotten within the retention period")
+	}
+
+	ss.runSchedules(now.Add(2 * ss.retention))
+	if _, ok := ss.Get("dropped"); ok {
+		t.Error("cancelled schedule still listed after the retention period")
+	}
+	if sd, ok := ss.Get("kept"); !ok || sd.Status != models.ScheduleUpcoming {
+		t.Errorf("upcoming schedule = %+v, %v, want it kept", sd, ok)
+	}
+}
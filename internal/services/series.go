@@ -0,0 +1,174 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// determineWinner returns the debater the judge's Winner section names, or "" if it names none of
+// them exactly; the free-text declaration is never searched, as it mentions both debaters
+func determineWinner(report *models.JudgeReport, debaters []string) string {
+	if report == nil {
+		return ""
+	}
+	// Models dress up a bare answer in emphasis or quotes now and then
+	name := strings.Trim(report.Winner, " \t\r*_`\"'")
+	for _, debater := range debaters {
+		if name == debater {
+			return debater
+		}
+	}
+	return ""
+}
+
+// describeSides announces the motion and who argues which side; runs on the channel's actor
+func (s *ChannelService) describeSides(ch *models.Channel) string {
+	var proposition, opposition []string
+	for name, side := range ch.Sides {
+		if side == models.SideProposition {
+			proposition = append(proposition, name)
+		} else {
+			opposition = append(opposition, name)
+		}
+	}
+	sort.Strings(proposition)
+	sort.Strings(opposition)
+
+	var builder strings.Builder
+	if ch.Series.BestOf > 1 {
+		builder.WriteString(fmt.Sprintf("🔢 Round %d (best of %d). ", ch.Round, ch.Series.BestOf))
+	}
+	if ch.Motion != "" {
+		builder.WriteString(fmt.Sprintf("📜 Motion: \"%s\". ", ch.Motion))
+	}
+	builder.WriteString(fmt.Sprintf("🟢 Proposition: %s · 🔴 Opposition: %s",
+		strings.Join(proposition, ", "), strings.Join(opposition, ", ")))
+	return builder.String()
+}
+
+// seriesScore formats the running series score; runs on the channel's actor
+func seriesScore(ch *models.Channel, debaters []string) string {
+	parts := make([]string, 0, len(debaters))
+	for _, name := range debaters {
+		parts = append(parts, fmt.Sprintf("%s %d", name, ch.Series.Scores[name]))
+	}
+	return strings.Join(parts, " – ")
+}
+
+// recordRoundResult announces the series score after a round is archived and offers a rematch
+func (s *ChannelService) recordRoundResult(ch *models.Channel, archive *models.Archive) {
+	score := seriesScore(ch, archive.Participants)
+	series := ch.Series
+
+	var text string
+	switch {
+	case archive.Winner == "":
+		text = fmt.Sprintf("🤝 Round %d ended without a clear winner. Series: %s.", archive.Round, score)
+	case series.Winner != "" && series.BestOf > 1:
+		text = fmt.Sprintf("🏆 %s wins the best-of-%d series! Final score: %s.", series.Winner, series.BestOf, score)
+	default:
+		text = fmt.Sprintf("🏆 %s wins round %d. Series: %s.", archive.Winner, archive.Round, score)
+	}
+	if series.Winner == "" && series.BestOf > 1 {
+		text += " Send a rematch request to play the next round."
+	} else {
+		text += " Send a rematch request to start a new series."
+	}
+
+	s.broadcast(ch, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       text,
+		Timestamp:  time.Now(),
+	})
+}
+
+// HandleRematchRequest records a debater's rematch request and starts the next round once both agree
+func (s *ChannelService) HandleRematchRequest(ch *models.Channel, client *models.Client, swap bool) {
+	if ch.Status != models.ChannelArchived {
+		s.notifyClient(ch, client, "🔁 A rematch can only be requested once the verdict is in.")
+		return
+	}
+
+	record(ch, models.ChannelEvent{Type: models.RematchRequested, Name: client.Name, Swap: swap})
+
+	debaters := 0
+	agreed := 0
+	for _, c := range ch.Clients {
+		if c.CanSend {
+			debaters++
+			if ch.RematchRequests[c.Name] {
+				agreed++
+			}
+		}
+	}
+
+	if debaters < 2 || agreed < debaters {
+		text := fmt.Sprintf("🔁 %s wants a rematch", client.Name)
+		if swap {
+			text += " with sides swapped"
+		}
+		s.broadcast(ch, models.Message{
+			SenderType: "system",
+			SenderName: "system",
+			Text:       text + ". Waiting for the opponent to accept...",
+			Timestamp:  time.Now(),
+		})
+		return
+	}
+
+	s.startRematch(ch)
+}
+
+// ReplayRound starts the next round of an archived channel without waiting for rematch requests, for
+// debates arranged by the server that must produce a winner. It reports false if the channel is not
+// awaiting a rematch.
+func (s *ChannelService) ReplayRound(ch *models.Channel, reason string) bool {
+	replayed := false
+	ch.Actor.Do(func() {
+		if ch.Status != models.ChannelArchived {
+			return
+		}
+		s.broadcast(ch, models.Message{
+			SenderType: "system",
+			SenderName: "system",
+			Text:       reason,
+			Timestamp:  time.Now(),
+		})
+		s.startRematch(ch)
+		replayed = true
+	})
+	return replayed
+}
+
+// startRematch resets the channel to the lobby for the next round, swapping sides if requested
+func (s *ChannelService) startRematch(ch *models.Channel) {
+	newSeries := ch.Series.Winner != ""
+	record(ch, models.ChannelEvent{Type: models.RematchStarted, Swap: ch.RematchSwap})
+
+	text := fmt.Sprintf("🔁 Rematch accepted! Round %d is about to begin.", ch.Round)
+	if newSeries {
+		text = fmt.Sprintf("🔁 Rematch accepted! A new best-of-%d series begins with round %d.", ch.Series.BestOf, ch.Round)
+	}
+	sidesText := s.describeSides(ch)
+
+	s.broadcast(ch, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       text + " " + sidesText,
+		Timestamp:  time.Now(),
+	})
+	s.broadcast(ch, models.Message{
+		SenderType: "system",
+		SenderName: "system",
+		Text:       "🤔 Are you ready to engage in the debate? Click 'Engage' when you're ready to begin.",
+		Timestamp:  time.Now(),
+	})
+	s.emitPhaseChanged(ch)
+	s.emitReadinessChanged(ch)
+	s.emitSubmissionStatus(ch)
+}
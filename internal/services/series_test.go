@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+func TestDetermineWinner(t *testing.T) {
+	s := newTestService(t)
+	debaters := []string{"ann", "annabel"}
+	verdict := func(winner string) string {
+		return "#### Winner\n" + winner + "\n\n#### Winner Declaration\nannabel argued well, but ann won on rebuttals.\n"
+	}
+
+	for _, tc := range []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"named", verdict("ann"), "ann"},
+		{"name containing another", verdict("annabel"), "annabel"},
+		{"emphasized", verdict("**annabel**"), "annabel"},
+		{"none", verdict("none"), ""},
+		{"not a debater", verdict("Ann"), ""},
+		{"sentence", verdict("ann wins"), ""},
+		{"no winner section", "#### Winner Declaration\nann wins.\n", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			report := s.parseJudgeResponse(tc.response)
+			if got := determineWinner(report, debaters); got != tc.want {
+				t.Errorf("winner = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	if got := determineWinner(nil, debaters); got != "" {
+		t.Errorf("winner without a verdict = %q, want none", got)
+	}
+	if got := determineWinner(&models.JudgeReport{WinnerDeclaration: "ann wins"}, debaters); got != "" {
+		t.Errorf("winner from the declaration alone = %q, want none", got)
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+const (
+	SpectatorChatMaxLength = 300              // Longest side-chat message accepted
+	SpectatorChatBurst     = 5                // Side-chat messages a spectator may send per window
+	SpectatorChatWindow    = 10 * time.Second // Window the burst limit applies to
+	SpectatorChatHistory   = 200              // Side-chat messages kept for late joiners
+)
+
+// HandleSpectatorChat posts a spectator's message to the side-chat. Side-chat only reaches
+// other spectators so the audience cannot coach the debaters, and it is kept out of
+// ch.Messages so the AI moderator and judge never see it.
+func (s *ChannelService) HandleSpectatorChat(ch *models.Channel, client *models.Client, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if len([]rune(text)) > SpectatorChatMaxLength {
+		s.notifyClient(ch, client, fmt.Sprintf("💬 Side-chat messages are limited to %d characters.", SpectatorChatMaxLength))
+		return
+	}
+
+	now := time.Now()
+	recent := client.ChatSent[:0]
+	for _, sent := range client.ChatSent {
+		if now.Sub(sent) < SpectatorChatWindow {
+			recent = append(recent, sent)
+		}
+	}
+	client.ChatSent = recent
+	if len(recent) >= SpectatorChatBurst {
+		s.notifyClient(ch, client, "💬 You are sending messages too quickly. Please wait a moment.")
+		return
+	}
+
+	if ch.Muted[client.Name] {
+		s.notifyClient(ch, client, "🔇 The moderator has muted you in the side-chat.")
+		return
+	}
+
+	client.ChatSent = append(client.ChatSent, now)
+	s.broadcastSpectatorChat(ch, models.Message{
+		SenderType: "spectator",
+		SenderName: client.Name,
+		Text:       text,
+		Timestamp:  now,
+	})
+}
+
+// HandleMute lets the moderator mute or unmute a spectator in the side-chat
+func (s *ChannelService) HandleMute(ch *models.Channel, client *models.Client, target string, mute bool) {
+	if !client.IsModerator {
+		s.notifyClient(ch, client, "Only the channel moderator can mute spectators.")
+		return
+	}
+	target = strings.TrimSpace(target)
+	if target == "" {
+		s.notifyClient(ch, client, "🔇 Name the spectator to mute, e.g. __MUTE__:name.")
+		return
+	}
+
+	if mute {
+		record(ch, models.ChannelEvent{Type: models.SpectatorMuted, Name: target})
+	} else {
+		record(ch, models.ChannelEvent{Type: models.SpectatorUnmuted, Name: target})
+	}
+
+	text := fmt.Sprintf("🔇 %s has been muted by the moderator.", target)
+	if !mute {
+		text = fmt.Sprintf("🔊 %s may chat again.", target)
+	}
+	s.broadcastSpectatorChat(ch, models.Message{
+		SenderType: "spectator",
+		SenderName: "system",
+		Text:       text,
+		Timestamp:  time.Now(),
+	})
+}
+
+// broadcastSpectatorChat stores a side-chat message and sends it to every spectator
+func (s *ChannelService) broadcastSpectatorChat(ch *models.Channel, msg models.Message) {
+	if msg.Id == "" {
+		msg.Id = uuid.NewString()
+	}
+	record(ch, models.ChannelEvent{Type: models.SpectatorChatted, Message: &msg})
+	spectators := make([]*models.Client, 0, len(ch.Clients))
+	for _, c := range ch.Clients {
+		if !c.CanSend {
+			spectators = append(spectators, c)
+		}
+	}
+
+	s.deliverAll(ch, spectators, msg)
+	channelLog(ch).Debug("side chat message", "sender", msg.SenderName, "text", msg.Text)
+}
+
+// sendSpectatorHistory replays the side-chat to a spectator who just joined
+func (s *ChannelService) sendSpectatorHistory(ch *models.Channel, client *models.Client) {
+	history := append([]models.Message(nil), ch.SpectatorChat...)
+
+	for _, msg := range history {
+		s.deliver(ch, client, msg)
+	}
+}
@@ -0,0 +1,791 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/store"
+)
+
+const (
+	tournamentsDocument  = "tournaments"
+	MinTournamentPlayers = 2
+)
+
+var (
+	ErrTournamentNotFound      = errors.New("tournament not found")
+	ErrUnknownTournamentFormat = errors.New("format must be single_elimination or swiss")
+	ErrTournamentStarted       = errors.New("tournament has already started")
+	ErrAlreadyRegistered       = errors.New("player is already registered")
+	ErrNotEnoughPlayers        = errors.New("at least two players must register")
+	ErrNotOrganizer            = errors.New("only the organizer can run the tournament")
+	ErrNoLiveMatch             = errors.New("no live match for this player")
+	ErrMatchNotFound           = errors.New("match not found")
+	ErrMatchDecided            = errors.New("match has already been decided")
+	ErrNotInMatch              = errors.New("winner must be one of the match's players")
+)
+
+// TournamentService runs single-elimination and Swiss tournaments, opening a channel per match
+// and advancing players as the judge's verdicts come in
+type TournamentService struct {
+	mu          sync.Mutex
+	saving      sync.Mutex // Held from snapshot to write, so a slow save cannot overwrite a newer one
+	tournaments map[string]*models.Tournament
+	opening     map[matchRef]bool // Matches whose channel is being created
+	channels    *ChannelService
+	ratings     *RatingService
+	store       *store.Store
+}
+
+// NewTournamentService loads the saved tournaments, starting empty if there are none
+func NewTournamentService(channels *ChannelService, ratings *RatingService, st *store.Store) (*TournamentService, error) {
+	ts := &TournamentService{
+		tournaments: make(map[string]*models.Tournament),
+		opening:     make(map[matchRef]bool),
+		channels:    channels,
+		ratings:     ratings,
+		store:       st,
+	}
+
+	var saved models.TournamentStore
+	if _, err := st.Load(tournamentsDocument, &saved); err != nil {
+		return nil, err
+	}
+	for _, stored := range saved.Tournaments {
+		t := stored.Tournament
+		t.OrganizerKey = stored.OrganizerKey
+		t.PlayerKeys = stored.PlayerKeys
+		if t.PlayerKeys == nil {
+			t.PlayerKeys = make(map[string]string)
+		}
+		for r := range t.Rounds {
+			for m := range t.Rounds[r].Matches {
+				match := &t.Rounds[r].Matches[m]
+				match.Password = stored.Passwords[match.Id]
+			}
+		}
+		ts.tournaments[t.Id] = &t
+	}
+	return ts, nil
+}
+
+// Create opens a tournament for registration and returns it with the organizer key
+func (ts *TournamentService) Create(name, format, motion, organizer string, swissRounds int) (models.Tournament, error) {
+	if format != models.TournamentSingleElimination && format != models.TournamentSwiss {
+		return models.Tournament{}, ErrUnknownTournamentFormat
+	}
+
+	t := &models.Tournament{
+		Id:           uuid.NewString(),
+		Name:         name,
+		Format:       format,
+		Motion:       strings.TrimSpace(motion),
+		Organizer:    organizer,
+		OrganizerKey: uuid.NewString(),
+		Status:       models.TournamentRegistering,
+		Players:      []string{},
+		PlayerKeys:   make(map[string]string),
+		SwissRounds:  swissRounds,
+		Rounds:       []models.TournamentRound{},
+		CreatedAt:    time.Now(),
+	}
+
+	ts.mu.Lock()
+	ts.tournaments[t.Id] = t
+	copied := ts.copyTournament(t)
+	copied.OrganizerKey = t.OrganizerKey
+	ts.mu.Unlock()
+
+	slog.Info("tournament created", "tournament", name, "format", format)
+	ts.save()
+	return copied, nil
+}
+
+// Register adds a player before the tournament starts and returns the key that reveals their match passwords
+func (ts *TournamentService) Register(id, player string) (string, error) {
+	ts.mu.Lock()
+	t, ok := ts.tournaments[id]
+	switch {
+	case !ok:
+		ts.mu.Unlock()
+		return "", ErrTournamentNotFound
+	case t.Status != models.TournamentRegistering:
+		ts.mu.Unlock()
+		return "", ErrTournamentStarted
+	case t.PlayerKeys[player] != "":
+		ts.mu.Unlock()
+		return "", ErrAlreadyRegistered
+	}
+	key := uuid.NewString()
+	t.Players = append(t.Players, player)
+	t.PlayerKeys[player] = key
+	ts.mu.Unlock()
+
+	ts.save()
+	return key, nil
+}
+
+// IsOrganizer reports whether key is the organizer key of the tournament
+func (ts *TournamentService) IsOrganizer(id, key string) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	t, ok := ts.tournaments[id]
+	return ok && key != "" && key == t.OrganizerKey
+}
+
+// PlayerFor returns the registered player a key belongs to
+func (ts *TournamentService) PlayerFor(id, key string) (string, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	t, ok := ts.tournaments[id]
+	if !ok || key == "" {
+		return "", false
+	}
+	for player, playerKey := range t.PlayerKeys {
+		if playerKey == key {
+			return player, true
+		}
+	}
+	return "", false
+}
+
+// Start seeds the players by rating and opens the first round
+func (ts *TournamentService) Start(id, key string) (models.Tournament, error) {
+	ts.mu.Lock()
+	t, ok := ts.tournaments[id]
+	switch {
+	case !ok:
+		ts.mu.Unlock()
+		return models.Tournament{}, ErrTournamentNotFound
+	case key == "" || key != t.OrganizerKey:
+		ts.mu.Unlock()
+		return models.Tournament{}, ErrNotOrganizer
+	case t.Status != models.TournamentRegistering:
+		ts.mu.Unlock()
+		return models.Tournament{}, ErrTournamentStarted
+	case len(t.Players) < MinTournamentPlayers:
+		ts.mu.Unlock()
+		return models.Tournament{}, ErrNotEnoughPlayers
+	}
+
+	// Seed by rating, registration order breaking ties
+	seeded := append([]string{}, t.Players...)
+	ratings := make(map[string]float64, len(seeded))
+	for _, player := range seeded {
+		ratings[player] = ts.ratings.GetRating(player).Rating
+	}
+	sort.SliceStable(seeded, func(i, j int) bool {
+		return ratings[seeded[i]] > ratings[seeded[j]]
+	})
+	t.Seeds = make(map[string]int, len(seeded))
+	for i, player := range seeded {
+		t.Seeds[player] = i + 1
+	}
+	if t.Format == models.TournamentSwiss && t.SwissRounds < 1 {
+		t.SwissRounds = int(math.Ceil(math.Log2(float64(len(seeded)))))
+	}
+
+	now := time.Now()
+	t.Status = models.TournamentRunning
+	t.StartedAt = &now
+	if t.Format == models.TournamentSwiss {
+		t.Standings = swissStandings(t)
+	}
+	opening := ts.startRound(t)
+	ts.mu.Unlock()
+
+	slog.Info("tournament started", "tournament", t.Name, "players", len(seeded))
+	ts.openMatches(opening)
+	ts.save()
+	copied, _ := ts.Get(id)
+	return copied, nil
+}
+
+// Get returns a copy of the tournament with the phase of each live match filled in
+func (ts *TournamentService) Get(id string) (models.Tournament, bool) {
+	ts.mu.Lock()
+	t, ok := ts.tournaments[id]
+	if !ok {
+		ts.mu.Unlock()
+		return models.Tournament{}, false
+	}
+	copied := ts.copyTournament(t)
+	ts.mu.Unlock()
+
+	ts.fillLivePhases(&copied)
+	return copied, true
+}
+
+// List returns every tournament without its rounds, newest first
+func (ts *TournamentService) List() []models.Tournament {
+	ts.mu.Lock()
+	list := make([]models.Tournament, 0, len(ts.tournaments))
+	for _, t := range ts.tournaments {
+		summary := *t
+		summary.Players = append([]string{}, t.Players...)
+		summary.Rounds = nil
+		summary.Standings = nil
+		summary.Seeds = nil
+		summary.PlayerKeys = nil
+		list = append(list, summary)
+	}
+	ts.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// PlayerMatch returns the live match of the player holding key, with its channel password.
+// A match whose channel expired while nobody was in it, or could not be opened, is opened again.
+func (ts *TournamentService) PlayerMatch(id, key string) (models.TournamentMatch, error) {
+	player, ok := ts.PlayerFor(id, key)
+	if !ok {
+		return models.TournamentMatch{}, ErrNoLiveMatch
+	}
+
+	ts.mu.Lock()
+	t := ts.tournaments[id]
+	var match models.TournamentMatch
+	found := false
+	for r := range t.Rounds {
+		for _, candidate := range t.Rounds[r].Matches {
+			current := candidate.Status == models.MatchLive || (candidate.Status == models.MatchPending && !candidate.Bye)
+			if current && (candidate.Proposition == player || candidate.Opposition == player) {
+				match, found = candidate, true
+			}
+		}
+	}
+	ts.mu.Unlock()
+	if !found {
+		return models.TournamentMatch{}, ErrNoLiveMatch
+	}
+	if match.Status == models.MatchLive && ts.channels.GetChannel(match.Channel) != nil {
+		return match, nil
+	}
+
+	ref := matchRef{tournament: id, match: match.Id}
+	if ts.openMatch(ref, match.Channel) {
+		ts.save()
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if _, opened := ts.findMatch(ref); opened != nil && opened.Status == models.MatchLive {
+		return *opened, nil
+	}
+	return models.TournamentMatch{}, ErrNoLiveMatch
+}
+
+// RecordResult advances the tournament whose match was played in the archived channel. A round
+// without a clear winner is replayed in the same channel straight away; the organizer can award a
+// match that never produces one with AwardMatch.
+func (ts *TournamentService) RecordResult(archive *models.Archive) {
+	ts.mu.Lock()
+	var t *models.Tournament
+	var match *models.TournamentMatch
+	for _, candidate := range ts.tournaments {
+		for r := range candidate.Rounds {
+			for m := range candidate.Rounds[r].Matches {
+				if mm := &candidate.Rounds[r].Matches[m]; mm.Channel == archive.ChannelName && mm.Status == models.MatchLive {
+					t, match = candidate, mm
+				}
+			}
+		}
+	}
+	if match == nil {
+		ts.mu.Unlock()
+		return
+	}
+	if archive.Winner != match.Proposition && archive.Winner != match.Opposition {
+		name, id := t.Name, match.Id
+		ts.mu.Unlock()
+
+		ch := ts.channels.GetChannel(archive.ChannelName)
+		if ch == nil || !ts.channels.ReplayRound(ch, "⚖️ A tournament match needs a winner, so this round is replayed.") {
+			slog.Warn("tournament match undecided and its channel cannot replay it", "tournament", name, "match", id)
+			return
+		}
+		slog.Info("tournament match undecided, replaying", "tournament", name, "match", id)
+		return
+	}
+
+	concluded := archive.ConcludedAt
+	match.Status = models.MatchCompleted
+	match.Winner = archive.Winner
+	match.ConcludedAt = &concluded
+	slog.Info("tournament match won", "tournament", t.Name, "match", match.Id, "winner", match.Winner)
+	opening := ts.advance(t)
+	ts.mu.Unlock()
+
+	ts.openMatches(opening)
+	ts.save()
+}
+
+// AwardMatch settles a match the organizer holding key decides, such as one whose debate never
+// produced a winner or a player failed to turn up to, and advances the tournament
+func (ts *TournamentService) AwardMatch(id, key, matchId, winner string) (models.Tournament, error) {
+	ts.mu.Lock()
+	t, match := ts.findMatch(matchRef{tournament: id, match: matchId})
+	switch {
+	case ts.tournaments[id] == nil:
+		ts.mu.Unlock()
+		return models.Tournament{}, ErrTournamentNotFound
+	case key == "" || key != ts.tournaments[id].OrganizerKey:
+		ts.mu.Unlock()
+		return models.Tournament{}, ErrNotOrganizer
+	case match == nil:
+		ts.mu.Unlock()
+		return models.Tournament{}, ErrMatchNotFound
+	case match.Status == models.MatchCompleted:
+		ts.mu.Unlock()
+		return models.Tournament{}, ErrMatchDecided
+	case winner == "" || (winner != match.Proposition && winner != match.Opposition):
+		ts.mu.Unlock()
+		return models.Tournament{}, ErrNotInMatch
+	}
+
+	now := time.Now()
+	match.Status = models.MatchCompleted
+	match.Winner = winner
+	match.Awarded = true
+	match.ConcludedAt = &now
+	slog.Info("tournament match awarded", "tournament", t.Name, "match", match.Id, "winner", winner)
+	opening := ts.advance(t)
+	ts.mu.Unlock()
+
+	ts.openMatches(opening)
+	ts.save()
+	copied, _ := ts.Get(id)
+	return copied, nil
+}
+
+// advance pairs the next round once every match of the current one is decided, returning the matches
+// whose channels the caller opens after releasing ts.mu; caller must hold ts.mu
+func (ts *TournamentService) advance(t *models.Tournament) []matchRef {
+	current := t.Rounds[len(t.Rounds)-1]
+	for _, match := range current.Matches {
+		if match.Status != models.MatchCompleted {
+			return nil
+		}
+	}
+
+	if t.Format == models.TournamentSwiss {
+		t.Standings = swissStandings(t)
+		if len(t.Rounds) >= t.SwissRounds {
+			ts.finish(t, t.Standings[0].Player)
+			return nil
+		}
+	} else if len(current.Matches) == 1 {
+		ts.finish(t, current.Matches[0].Winner)
+		return nil
+	}
+	return ts.startRound(t)
+}
+
+// finish records the champion; caller must hold ts.mu
+func (ts *TournamentService) finish(t *models.Tournament, winner string) {
+	now := time.Now()
+	t.Status = models.TournamentFinished
+	t.Winner = winner
+	t.FinishedAt = &now
+	slog.Info("tournament won", "tournament", t.Name, "winner", winner)
+}
+
+// startRound pairs the next round and settles its byes, returning the matches whose channels the
+// caller opens after releasing ts.mu; caller must hold ts.mu
+func (ts *TournamentService) startRound(t *models.Tournament) []matchRef {
+	number := len(t.Rounds) + 1
+	var pairs [][2]string
+	if t.Format == models.TournamentSwiss {
+		pairs = swissPairings(t)
+	} else {
+		pairs = eliminationPairings(t)
+	}
+
+	round := models.TournamentRound{Number: number}
+	var opening []matchRef
+	now := time.Now()
+	for i, pair := range pairs {
+		match := models.TournamentMatch{
+			Id:          fmt.Sprintf("r%d-m%d", number, i+1),
+			Round:       number,
+			Proposition: pair[0],
+			Opposition:  pair[1],
+			Bye:         pair[1] == "",
+			Status:      models.MatchPending,
+		}
+		if match.Bye {
+			match.Status = models.MatchCompleted
+			match.Winner = match.Proposition
+			match.ConcludedAt = &now
+		} else {
+			opening = append(opening, matchRef{tournament: t.Id, match: match.Id})
+		}
+		round.Matches = append(round.Matches, match)
+	}
+	t.Rounds = append(t.Rounds, round)
+
+	// A round made only of byes is already over
+	return append(opening, ts.advance(t)...)
+}
+
+// matchRef names a match across releases of ts.mu, during which its tournament's rounds may grow
+type matchRef struct {
+	tournament, match string
+}
+
+// findMatch returns the match ref names, or nil; caller must hold ts.mu
+func (ts *TournamentService) findMatch(ref matchRef) (*models.Tournament, *models.TournamentMatch) {
+	t, ok := ts.tournaments[ref.tournament]
+	if !ok {
+		return nil, nil
+	}
+	for r := range t.Rounds {
+		for m := range t.Rounds[r].Matches {
+			if match := &t.Rounds[r].Matches[m]; match.Id == ref.match {
+				return t, match
+			}
+		}
+	}
+	return nil, nil
+}
+
+// openMatches opens the channels of new matches; caller must not hold ts.mu
+func (ts *TournamentService) openMatches(refs []matchRef) {
+	for _, ref := range refs {
+		ts.openMatch(ref, "")
+	}
+}
+
+// openMatch creates the channel of a pending match, or replaces expired, the channel of a live match
+// that went away. It reports whether a channel was opened. The caller must not hold ts.mu, as creating
+// a channel waits on the broker and the channel's actor.
+func (ts *TournamentService) openMatch(ref matchRef, expired string) bool {
+	ts.mu.Lock()
+	t, match := ts.findMatch(ref)
+	if match == nil || ts.opening[ref] || !needsChannel(match, expired) {
+		ts.mu.Unlock()
+		return false
+	}
+	ts.opening[ref] = true
+	name, motion := t.Name, t.Motion
+	proposition, opposition := match.Proposition, match.Opposition
+	ts.mu.Unlock()
+
+	if motion == "" {
+		motion, _ = randomMotion("")
+	}
+	ch, password, err := ts.channels.CreateMatchChannel(channelPrefix(name), motion, "tournament:"+name, 1,
+		proposition, opposition)
+
+	ts.mu.Lock()
+	delete(ts.opening, ref)
+	_, match = ts.findMatch(ref)
+	opened := err == nil && match != nil && needsChannel(match, expired)
+	if opened {
+		match.Channel = ch.Name
+		match.Password = password
+		match.Motion = motion
+		match.Status = models.MatchLive
+	}
+	ts.mu.Unlock()
+
+	switch {
+	case err != nil:
+		// The match stays pending and is opened when one of its players next asks for it
+		slog.Error("creating tournament match channel", "tournament", name, "match", ref.match, "err", err)
+	case !opened:
+		ts.channels.DeleteChannel(ch.Name) // Decided meanwhile
+	}
+	return opened
+}
+
+// needsChannel reports whether a match is waiting for its channel to be opened; caller must hold ts.mu
+func needsChannel(match *models.TournamentMatch, expired string) bool {
+	if match.Bye {
+		return false
+	}
+	return match.Status == models.MatchPending || (match.Status == models.MatchLive && expired != "" && match.Channel == expired)
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// channelPrefix turns a tournament name into a channel name prefix
+func channelPrefix(name string) string {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 24 {
+		slug = strings.TrimRight(slug[:24], "-")
+	}
+	if slug == "" {
+		slug = "tournament"
+	}
+	return slug
+}
+
+// eliminationPairings seeds the first round so the top seeds meet last, with byes for the top seeds
+// when the field is not a power of two; later rounds pair the winners of neighbouring matches
+func eliminationPairings(t *models.Tournament) [][2]string {
+	if len(t.Rounds) > 0 {
+		previous := t.Rounds[len(t.Rounds)-1].Matches
+		pairs := make([][2]string, 0, len(previous)/2)
+		for i := 0; i+1 < len(previous); i += 2 {
+			pairs = append(pairs, [2]string{previous[i].Winner, previous[i+1].Winner})
+		}
+		return pairs
+	}
+
+	bySeed := make([]string, len(t.Seeds))
+	for player, seed := range t.Seeds {
+		bySeed[seed-1] = player
+	}
+	size := 1
+	for size < len(bySeed) {
+		size *= 2
+	}
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, len(order)*2+1-seed)
+		}
+		order = next
+	}
+
+	pairs := make([][2]string, 0, size/2)
+	for i := 0; i < size; i += 2 {
+		// The lower seed number is always present, so byes land on the opposition slot
+		pairs = append(pairs, [2]string{bySeed[order[i]-1], seedOrEmpty(bySeed, order[i+1])})
+	}
+	return pairs
+}
+
+func seedOrEmpty(bySeed []string, seed int) string {
+	if seed > len(bySeed) {
+		return ""
+	}
+	return bySeed[seed-1]
+}
+
+// swissPairings pairs players with equal or close scores who have not met, giving the bye
+// to the lowest-ranked player who has not had one
+func swissPairings(t *models.Tournament) [][2]string {
+	ranked := make([]string, 0, len(t.Standings))
+	byes := make(map[string]int)
+	for _, standing := range t.Standings {
+		ranked = append(ranked, standing.Player)
+		byes[standing.Player] = standing.Byes
+	}
+
+	played := make(map[string]map[string]bool)
+	propositions := make(map[string]int)
+	for _, round := range t.Rounds {
+		for _, match := range round.Matches {
+			if match.Bye {
+				continue
+			}
+			if played[match.Proposition] == nil {
+				played[match.Proposition] = make(map[string]bool)
+			}
+			if played[match.Opposition] == nil {
+				played[match.Opposition] = make(map[string]bool)
+			}
+			played[match.Proposition][match.Opposition] = true
+			played[match.Opposition][match.Proposition] = true
+			propositions[match.Proposition]++
+		}
+	}
+
+	var pairs [][2]string
+	if len(ranked)%2 == 1 {
+		bye := len(ranked) - 1
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if byes[ranked[i]] == 0 {
+				bye = i
+				break
+			}
+		}
+		pairs = append(pairs, [2]string{ranked[bye], ""})
+		ranked = append(ranked[:bye:bye], ranked[bye+1:]...)
+	}
+
+	paired := make(map[string]bool)
+	for i, player := range ranked {
+		if paired[player] {
+			continue
+		}
+		opponent := ""
+		for _, candidate := range ranked[i+1:] {
+			if paired[candidate] {
+				continue
+			}
+			if opponent == "" {
+				opponent = candidate // Fall back to a rematch if everyone left has been played
+			}
+			if !played[player][candidate] {
+				opponent = candidate
+				break
+			}
+		}
+		paired[player] = true
+		paired[opponent] = true
+		// Whoever has argued the proposition less often takes it
+		if propositions[opponent] < propositions[player] {
+			pairs = append(pairs, [2]string{opponent, player})
+		} else {
+			pairs = append(pairs, [2]string{player, opponent})
+		}
+	}
+
+	// Byes are listed last so the real matches read first
+	if len(pairs) > 0 && pairs[0][1] == "" {
+		pairs = append(pairs[1:], pairs[0])
+	}
+	return pairs
+}
+
+// swissStandings scores completed matches: a win or bye is a point, ties broken by Buchholz then seed
+func swissStandings(t *models.Tournament) []models.Standing {
+	standings := make(map[string]*models.Standing, len(t.Players))
+	for _, player := range t.Players {
+		standings[player] = &models.Standing{Player: player}
+	}
+	opponents := make(map[string][]string)
+	for _, round := range t.Rounds {
+		for _, match := range round.Matches {
+			if match.Status != models.MatchCompleted {
+				continue
+			}
+			if match.Bye {
+				standings[match.Proposition].Points++
+				standings[match.Proposition].Byes++
+				continue
+			}
+			loser := match.Proposition
+			if loser == match.Winner {
+				loser = match.Opposition
+			}
+			standings[match.Winner].Points++
+			standings[match.Winner].Wins++
+			standings[loser].Losses++
+			opponents[match.Proposition] = append(opponents[match.Proposition], match.Opposition)
+			opponents[match.Opposition] = append(opponents[match.Opposition], match.Proposition)
+		}
+	}
+	for player, standing := range standings {
+		for _, opponent := range opponents[player] {
+			standing.Buchholz += standings[opponent].Points
+		}
+	}
+
+	list := make([]models.Standing, 0, len(standings))
+	for _, standing := range standings {
+		list = append(list, *standing)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Points != list[j].Points {
+			return list[i].Points > list[j].Points
+		}
+		if list[i].Buchholz != list[j].Buchholz {
+			return list[i].Buchholz > list[j].Buchholz
+		}
+		return t.Seeds[list[i].Player] < t.Seeds[list[j].Player]
+	})
+	for i := range list {
+		list[i].Rank = i + 1
+	}
+	return list
+}
+
+// copyTournament deep-copies a tournament for use outside the lock, without keys or passwords; caller must hold ts.mu
+func (ts *TournamentService) copyTournament(t *models.Tournament) models.Tournament {
+	copied := *t
+	copied.OrganizerKey = ""
+	copied.PlayerKeys = nil
+	copied.Players = append([]string{}, t.Players...)
+	copied.Standings = append([]models.Standing(nil), t.Standings...)
+	copied.Seeds = make(map[string]int, len(t.Seeds))
+	for player, seed := range t.Seeds {
+		copied.Seeds[player] = seed
+	}
+	copied.Rounds = make([]models.TournamentRound, len(t.Rounds))
+	for r, round := range t.Rounds {
+		copied.Rounds[r] = models.TournamentRound{
+			Number:  round.Number,
+			Matches: append([]models.TournamentMatch(nil), round.Matches...),
+		}
+		for m := range copied.Rounds[r].Matches {
+			copied.Rounds[r].Matches[m].Password = ""
+		}
+	}
+	return copied
+}
+
+// fillLivePhases sets the phase of each live match of a copied tournament from its channel; caller must
+// not hold ts.mu, as reading a channel waits on its actor
+func (ts *TournamentService) fillLivePhases(t *models.Tournament) {
+	for r := range t.Rounds {
+		for m := range t.Rounds[r].Matches {
+			match := &t.Rounds[r].Matches[m]
+			if match.Status != models.MatchLive {
+				continue
+			}
+			if ch := ts.channels.GetChannel(match.Channel); ch != nil {
+				summary := ts.channels.Summarize(ch)
+				match.LivePhase = phaseTitle(summary.PhaseId)
+				if summary.Status == models.ChannelArchived {
+					match.LivePhase = "Awaiting rematch"
+				}
+			}
+		}
+	}
+}
+
+// save writes every tournament, keys and passwords included
+func (ts *TournamentService) save() {
+	ts.saving.Lock()
+	defer ts.saving.Unlock()
+
+	ts.mu.Lock()
+	saved := models.TournamentStore{Tournaments: make([]models.StoredTournament, 0, len(ts.tournaments))}
+	for _, t := range ts.tournaments {
+		stored := models.StoredTournament{
+			Tournament:   *t,
+			OrganizerKey: t.OrganizerKey,
+			PlayerKeys:   make(map[string]string, len(t.PlayerKeys)),
+			Passwords:    make(map[string]string),
+		}
+		for player, key := range t.PlayerKeys {
+			stored.PlayerKeys[player] = key
+		}
+		stored.Rounds = make([]models.TournamentRound, len(t.Rounds))
+		for r, round := range t.Rounds {
+			stored.Rounds[r] = models.TournamentRound{
+				Number:  round.Number,
+				Matches: append([]models.TournamentMatch(nil), round.Matches...),
+			}
+			for _, match := range round.Matches {
+				if match.Password != "" {
+					stored.Passwords[match.Id] = match.Password
+				}
+			}
+		}
+		stored.Players = append([]string{}, t.Players...)
+		stored.Standings = append([]models.Standing(nil), t.Standings...)
+		saved.Tournaments = append(saved.Tournaments, stored)
+	}
+	ts.mu.Unlock()
+
+	if err := ts.store.Save(tournamentsDocument, saved); err != nil {
+		slog.Error("saving tournaments", "err", err)
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+	"github.com/latestcomment/go-websocket-chat/internal/store"
+)
+
+func newTestTournaments(t *testing.T) (*TournamentService, *ChannelService) {
+	t.Helper()
+	st, err := store.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels := newTestService(t)
+	ratings, err := NewRatingService(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := NewTournamentService(channels, ratings, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts, channels
+}
+
+// startTournament registers players in seed order, all being rated alike, and starts the tournament
+func startTournament(t *testing.T, ts *TournamentService, format string, players ...string) (models.Tournament, map[string]string) {
+	t.Helper()
+	created, err := ts.Create("Spring Open", format, "Brackets are fair", "org", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make(map[string]string, len(players))
+	for _, player := range players {
+		if keys[player], err = ts.Register(created.Id, player); err != nil {
+			t.Fatal(err)
+		}
+	}
+	started, err := ts.Start(created.Id, created.OrganizerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return started, keys
+}
+
+// conclude reports a verdict on the match's channel as the channel service does
+func conclude(ts *TournamentService, match models.TournamentMatch, winner string) {
+	ts.RecordResult(&models.Archive{ChannelName: match.Channel, Winner: winner, ConcludedAt: time.Now()})
+}
+
+// TestTournamentElimination plays a three-player bracket while the bracket is read concurrently, as
+// the tournament page does, checking channels are opened, reopened and advanced through
+func TestTournamentElimination(t *testing.T) {
+	ts, channels := newTestTournaments(t)
+
+	// Readers summarize live matches throughout; run with -race
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			for _, listed := range ts.List() {
+				ts.Get(listed.Id)
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		readers.Wait()
+	}()
+
+	started, keys := startTournament(t, ts, models.TournamentSingleElimination, "ann", "bob", "cat")
+	first := started.Rounds[0].Matches
+	if len(first) != 2 || !first[0].Bye || first[0].Status != models.MatchCompleted {
+		t.Fatalf("first round = %+v, want the top seed's bye and a match", first)
+	}
+	if first[1].Status != models.MatchLive || channels.GetChannel(first[1].Channel) == nil {
+		t.Fatalf("first match = %+v, want it live in an open channel", first[1])
+	}
+
+	match, err := ts.PlayerMatch(started.Id, keys["bob"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match.Id != first[1].Id || match.Password == "" {
+		t.Errorf("bob's match = %+v, want %s with its password", match, first[1].Id)
+	}
+
+	conclude(ts, match, "bob")
+	final, err := ts.PlayerMatch(started.Id, keys["ann"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.Round != 2 || final.Opposition != "bob" {
+		t.Fatalf("final = %+v, want ann against bob in round 2", final)
+	}
+
+	// The final's channel expires before anyone joins; asking for the match opens a new one
+	channels.DeleteChannel(final.Channel)
+	reopened, err := ts.PlayerMatch(started.Id, keys["bob"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Channel == final.Channel || channels.GetChannel(reopened.Channel) == nil {
+		t.Errorf("final channel %q was not reopened, got %q", final.Channel, reopened.Channel)
+	}
+
+	conclude(ts, reopened, "ann")
+	finished, _ := ts.Get(started.Id)
+	if finished.Status != models.TournamentFinished || finished.Winner != "ann" {
+		t.Errorf("tournament = %s won by %q, want finished and won by ann", finished.Status, finished.Winner)
+	}
+}
+
+// TestTournamentUndecidedMatch checks that a match without a clear winner is replayed rather than left
+// live for good, and that the organizer can settle it instead
+func TestTournamentUndecidedMatch(t *testing.T) {
+	ts, channels := newTestTournaments(t)
+	started, _ := startTournament(t, ts, models.TournamentSingleElimination, "ann", "bob")
+	match := started.Rounds[0].Matches[0]
+	ch := channels.GetChannel(match.Channel)
+
+	// The judge names nobody
+	inChannel(ch, func() {
+		record(ch, models.ChannelEvent{Type: models.Verdict})
+	})
+	conclude(ts, match, "")
+	inChannel(ch, func() {
+		if ch.Status == models.ChannelArchived || ch.Round != 2 {
+			t.Errorf("channel %s in round %d, want the round replayed", ch.Status, ch.Round)
+		}
+	})
+	if got, _ := ts.Get(started.Id); got.Rounds[0].Matches[0].Status != models.MatchLive {
+		t.Errorf("match %s after an undecided round, want it still live", got.Rounds[0].Matches[0].Status)
+	}
+
+	ts.mu.Lock()
+	organizer := ts.tournaments[started.Id].OrganizerKey
+	ts.mu.Unlock()
+	if _, err := ts.AwardMatch(started.Id, "not-the-key", match.Id, "bob"); !errors.Is(err, ErrNotOrganizer) {
+		t.Errorf("awarding without the organizer key: err = %v, want %v", err, ErrNotOrganizer)
+	}
+	if _, err := ts.AwardMatch(started.Id, organizer, match.Id, "cat"); !errors.Is(err, ErrNotInMatch) {
+		t.Errorf("awarding to an outsider: err = %v, want %v", err, ErrNotInMatch)
+	}
+	awarded, err := ts.AwardMatch(started.Id, organizer, match.Id, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final := awarded.Rounds[0].Matches[0]; final.Winner != "bob" || !final.Awarded || awarded.Winner != "bob" {
+		t.Errorf("after awarding the final: match %+v, champion %q", final, awarded.Winner)
+	}
+	if _, err := ts.AwardMatch(started.Id, organizer, match.Id, "ann"); !errors.Is(err, ErrMatchDecided) {
+		t.Errorf("awarding a decided match: err = %v, want %v", err, ErrMatchDecided)
+	}
+
+	// A verdict arriving afterwards changes nothing
+	conclude(ts, match, "ann")
+	if got, _ := ts.Get(started.Id); got.Winner != "bob" {
+		t.Errorf("champion = %q after a late verdict, want bob", got.Winner)
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+var (
+	ErrNoTranscript  = errors.New("no debate recorded for this channel")
+	ErrRoundNotFound = errors.New("round not found")
+)
+
+// ExportTranscript returns one round of a channel's debate: the round in progress when it matches,
+// otherwise the latest archive of that round. Round 0 picks the round in progress, or the latest archive.
+func (s *ChannelService) ExportTranscript(name string, round int) (models.TranscriptExport, error) {
+	if ch := s.GetChannel(name); ch != nil {
+		var export models.TranscriptExport
+		live := false
+		ch.Actor.Do(func() {
+			live = ch.Status == models.ChannelOpen && (round == 0 || round == ch.Round)
+			if !live {
+				return
+			}
+			export = models.TranscriptExport{
+				Version:      models.TranscriptExportVersion,
+				ChannelId:    ch.ChannelId,
+				Channel:      ch.Name,
+				Round:        ch.Round,
+				Motion:       ch.Motion,
+				Participants: debateParticipants(ch),
+				Sides:        make(map[string]string, len(ch.Sides)),
+				CreatedAt:    ch.CreatedAt,
+				ExportedAt:   time.Now(),
+				Messages:     make([]models.Message, len(ch.Messages)),
+			}
+			for debater, side := range ch.Sides {
+				export.Sides[debater] = side
+			}
+			copy(export.Messages, ch.Messages)
+		})
+		if live {
+			return export, nil
+		}
+	}
+
+	archive, err := s.findArchive(name, round)
+	if err != nil {
+		return models.TranscriptExport{}, err
+	}
+	concludedAt := archive.ConcludedAt
+	return models.TranscriptExport{
+		Version:      models.TranscriptExportVersion,
+		ChannelId:    archive.ChannelId,
+		Channel:      archive.ChannelName,
+		Round:        archive.Round,
+		Motion:       archive.Motion,
+		Concluded:    true,
+		Participants: archive.Participants,
+		Sides:        archive.Sides,
+		Winner:       archive.Winner,
+		Forfeit:      archive.Forfeit,
+		CreatedAt:    archive.CreatedAt,
+		ConcludedAt:  &concludedAt,
+		ExportedAt:   time.Now(),
+		Verdict:      archive.Verdict,
+		Messages:     archive.Messages,
+	}, nil
+}
+
+// findArchive returns the latest archive of a round, or of any round when round is 0
+func (s *ChannelService) findArchive(name string, round int) (*models.Archive, error) {
+	archives := s.GetArchives(name)
+	if len(archives) == 0 {
+		return nil, ErrNoTranscript
+	}
+	for i := len(archives) - 1; i >= 0; i-- {
+		if round == 0 || archives[i].Round == round {
+			return archives[i], nil
+		}
+	}
+	return nil, ErrRoundNotFound
+}
+
+// TranscriptPhases groups messages by the phase they were sent in, in order, leaving out the judge's
+// verdict, which exports render on its own
+func TranscriptPhases(messages []models.Message) []models.TranscriptPhase {
+	var phases []models.TranscriptPhase
+	for _, msg := range messages {
+		if msg.JudgeData != nil {
+			continue
+		}
+		if len(phases) == 0 || phases[len(phases)-1].Id != msg.Phase {
+			phases = append(phases, models.TranscriptPhase{Id: msg.Phase, Title: phaseTitle(msg.Phase)})
+		}
+		last := &phases[len(phases)-1]
+		last.Messages = append(last.Messages, msg)
+	}
+	return phases
+}
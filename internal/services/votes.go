@@ -0,0 +1,179 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+// HandleVote records a spectator ballot. Accepted forms:
+//
+//	pre:for|against        stance on the motion, while in the lobby
+//	post:for|against       stance on the motion, from the closing statements until the verdict
+//	phase:<id>:<debater>   who won a phase, for the current or an earlier phase of the running debate
+func (s *ChannelService) HandleVote(ch *models.Channel, client *models.Client, arg string) {
+	if client.CanSend {
+		s.notifyClient(ch, client, "🗳️ Debaters cannot vote.")
+		return
+	}
+
+	parts := strings.SplitN(arg, ":", 3)
+	var errMsg string
+	switch {
+	case ch.Status != models.ChannelOpen:
+		errMsg = "🗳️ Voting has closed for this debate."
+	case parts[0] == models.BallotPre || parts[0] == models.BallotPost:
+		stance := ""
+		if len(parts) == 2 {
+			stance = parts[1]
+		}
+		if stance != models.VoteFor && stance != models.VoteAgainst {
+			errMsg = "🗳️ Vote for or against the motion."
+		} else if parts[0] == models.BallotPre {
+			if ch.Phase.Id != 0 {
+				errMsg = "🗳️ Pre-debate voting closed when the debate began."
+			} else {
+				record(ch, models.ChannelEvent{Type: models.VoteCast, Name: client.Name, Ballot: models.BallotPre, Choice: stance})
+			}
+		} else {
+			if ch.Phase.Id < 5 {
+				errMsg = "🗳️ Post-debate voting opens with the closing statements."
+			} else {
+				record(ch, models.ChannelEvent{Type: models.VoteCast, Name: client.Name, Ballot: models.BallotPost, Choice: stance})
+			}
+		}
+	case parts[0] == models.BallotPhase && len(parts) == 3:
+		phaseId, err := strconv.Atoi(parts[1])
+		debater := parts[2]
+		isDebater := false
+		for _, name := range debaterNames(ch) {
+			if name == debater {
+				isDebater = true
+			}
+		}
+		switch {
+		case err != nil || phaseId < 1 || phaseId > ch.Phase.Id || ch.Phase.Id > 5:
+			errMsg = "🗳️ You can only vote on phases that have started."
+		case !isDebater:
+			errMsg = fmt.Sprintf("🗳️ %s is not debating in this channel.", debater)
+		default:
+			record(ch, models.ChannelEvent{Type: models.VoteCast, Name: client.Name, Ballot: models.BallotPhase, Phase: phaseId, Choice: debater})
+		}
+	default:
+		errMsg = "🗳️ Unknown vote: " + arg
+	}
+	tally := voteTally(ch)
+
+	if errMsg != "" {
+		s.notifyClient(ch, client, errMsg)
+		return
+	}
+	s.broadcastEvent(ch, models.EventVoteTally, tally)
+}
+
+// voteTally counts the current ballots; runs on the channel's actor
+func voteTally(ch *models.Channel) models.VoteTally {
+	tally := models.VoteTally{Phases: make(map[int]map[string]int)}
+	for _, stance := range ch.Votes.Pre {
+		tally.Pre = addStance(tally.Pre, stance)
+	}
+	for _, stance := range ch.Votes.Post {
+		tally.Post = addStance(tally.Post, stance)
+	}
+	for phaseId, ballots := range ch.Votes.Phases {
+		tally.Phases[phaseId] = make(map[string]int)
+		for _, debater := range ballots {
+			tally.Phases[phaseId][debater]++
+		}
+	}
+	return tally
+}
+
+func addStance(t models.StanceTally, stance string) models.StanceTally {
+	if stance == models.VoteFor {
+		t.For++
+	} else {
+		t.Against++
+	}
+	return t
+}
+
+// audienceReport compares the spectators' swing with the judge's winner, nil if nobody voted; runs on the channel's actor
+func audienceReport(ch *models.Channel, judgeWinner string, debaters []string) *models.AudienceReport {
+	if len(ch.Votes.Pre) == 0 && len(ch.Votes.Post) == 0 && len(ch.Votes.Phases) == 0 {
+		return nil
+	}
+
+	tally := voteTally(ch)
+	report := &models.AudienceReport{
+		Pre:            tally.Pre,
+		Post:           tally.Post,
+		PreForPercent:  tally.Pre.ForPercent(),
+		PostForPercent: tally.Post.ForPercent(),
+		JudgeWinner:    judgeWinner,
+		PhaseWins:      make(map[string]int),
+	}
+	// A swing needs ballots on both sides of the debate; against an empty one it only measures who voted
+	if len(ch.Votes.Pre) > 0 && len(ch.Votes.Post) > 0 {
+		swing := report.PostForPercent - report.PreForPercent
+		report.Swing = &swing
+
+		// The side that moved the room wins the audience, as in Oxford-style debates
+		winningSide := ""
+		switch {
+		case swing > 0:
+			winningSide = models.SideProposition
+		case swing < 0:
+			winningSide = models.SideOpposition
+		}
+		for _, name := range debaters {
+			if winningSide != "" && ch.Sides[name] == winningSide {
+				report.AudienceWinner = name
+			}
+		}
+	}
+	report.AgreesWithJudge = report.AudienceWinner != "" && report.AudienceWinner == judgeWinner
+
+	for _, counts := range tally.Phases {
+		best, bestVotes, tied := "", 0, false
+		for debater, votes := range counts {
+			switch {
+			case votes > bestVotes:
+				best, bestVotes, tied = debater, votes, false
+			case votes == bestVotes:
+				tied = true
+			}
+		}
+		if best != "" && !tied {
+			report.PhaseWins[best]++
+		}
+	}
+	return report
+}
+
+// audienceSummary renders the audience report as text appended to the verdict
+func audienceSummary(report *models.AudienceReport) string {
+	var builder strings.Builder
+	builder.WriteString("#### Audience Verdict\n")
+	builder.WriteString(fmt.Sprintf("Before: %.0f%% for the motion (%d for, %d against). After: %.0f%% (%d for, %d against).",
+		report.PreForPercent, report.Pre.For, report.Pre.Against,
+		report.PostForPercent, report.Post.For, report.Post.Against))
+	if report.Swing == nil {
+		builder.WriteString("\nThe audience did not vote both before and after, so no swing was measured.")
+		return builder.String()
+	}
+	builder.WriteString(fmt.Sprintf(" Swing: %+.0f points.\n", *report.Swing))
+	if report.AudienceWinner != "" {
+		builder.WriteString(fmt.Sprintf("The audience swung toward %s", report.AudienceWinner))
+		if report.AgreesWithJudge {
+			builder.WriteString(", agreeing with the AI Judge.")
+		} else {
+			builder.WriteString(", disagreeing with the AI Judge.")
+		}
+	} else {
+		builder.WriteString("The audience did not swing either way.")
+	}
+	return builder.String()
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/latestcomment/go-websocket-chat/internal/models"
+)
+
+func TestAudienceReportSwing(t *testing.T) {
+	debaters := []string{"ann", "bob"}
+	sides := map[string]string{"ann": models.SideProposition, "bob": models.SideOpposition}
+
+	for _, tc := range []struct {
+		name      string
+		pre, post map[string]string
+		swing     *float64
+		winner    string
+	}{
+		{"toward the motion", map[string]string{"v1": models.VoteFor, "v2": models.VoteAgainst}, map[string]string{"v1": models.VoteFor, "v2": models.VoteFor}, ptr(50.0), "ann"},
+		{"against the motion", map[string]string{"v1": models.VoteFor}, map[string]string{"v1": models.VoteAgainst}, ptr(-100.0), "bob"},
+		{"unmoved", map[string]string{"v1": models.VoteFor}, map[string]string{"v1": models.VoteFor}, ptr(0.0), ""},
+		{"no closing ballots", map[string]string{"v1": models.VoteAgainst}, map[string]string{}, nil, ""},
+		{"no opening ballots", map[string]string{}, map[string]string{"v1": models.VoteFor}, nil, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ch := &models.Channel{Sides: sides, Votes: models.NewAudienceVotes()}
+			ch.Votes.Pre, ch.Votes.Post = tc.pre, tc.post
+
+			report := audienceReport(ch, "ann", debaters)
+			switch {
+			case tc.swing == nil && report.Swing != nil:
+				t.Errorf("swing = %+.0f, want none", *report.Swing)
+			case tc.swing != nil && (report.Swing == nil || *report.Swing != *tc.swing):
+				t.Errorf("swing = %v, want %+.0f", report.Swing, *tc.swing)
+			}
+			if report.AudienceWinner != tc.winner {
+				t.Errorf("audience winner = %q, want %q", report.AudienceWinner, tc.winner)
+			}
+			if report.AgreesWithJudge != (tc.winner == "ann") {
+				t.Errorf("agrees with the judge = %v", report.AgreesWithJudge)
+			}
+		})
+	}
+}
+
+func ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,130 @@
+// Package socket writes to a WebSocket from a goroutine of its own, so whoever sends to a slow
+// client is never held up by it.
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+const (
+	// Queue is how many frames may wait for a socket before its client counts as too slow; it fits
+	// the state events and side-chat history sent on joining
+	Queue = 256
+
+	// WriteTimeout bounds each write, so a client that stops reading is dropped rather than waited on
+	WriteTimeout = 10 * time.Second
+)
+
+var (
+	ErrClosed  = errors.New("socket closed")
+	ErrTooSlow = errors.New("client too slow, socket closed")
+)
+
+// Writer queues frames for a socket and writes them in order. A client whose queue fills up, or
+// whose write times out, is disconnected: its read loop then fails and the client leaves.
+type Writer struct {
+	conn    *websocket.Conn
+	frames  chan frame
+	timeout time.Duration
+
+	quit chan struct{} // Closed by Stop
+	done chan struct{} // Closed when the goroutine has exited
+	stop sync.Once
+}
+
+type frame struct {
+	data   []byte
+	close  bool // Send a close frame with code and reason, then close the socket
+	code   int
+	reason string
+}
+
+// New starts a writer for conn with the default queue and write timeout
+func New(conn *websocket.Conn) *Writer {
+	w := &Writer{
+		conn:    conn,
+		frames:  make(chan frame, Queue),
+		timeout: WriteTimeout,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	defer w.conn.Close()
+	for {
+		select {
+		case <-w.quit:
+			return
+		case f := <-w.frames:
+			deadline := time.Now().Add(w.timeout)
+			if f.close {
+				w.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(f.code, f.reason), deadline)
+				return
+			}
+			w.conn.SetWriteDeadline(deadline)
+			if err := w.conn.WriteMessage(websocket.TextMessage, f.data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Send queues v as a JSON text frame. It returns ErrTooSlow when the client has fallen too far behind,
+// closing the socket, and ErrClosed for every frame after that.
+func (w *Writer) Send(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return w.SendRaw(data)
+}
+
+// SendRaw queues an encoded text frame like Send
+func (w *Writer) SendRaw(data []byte) error {
+	return w.queue(frame{data: data})
+}
+
+// Close queues a close frame with code and reason behind the frames already queued, after which the
+// socket is closed
+func (w *Writer) Close(code int, reason string) {
+	w.queue(frame{close: true, code: code, reason: reason})
+}
+
+func (w *Writer) queue(f frame) error {
+	select {
+	case <-w.quit:
+		return ErrClosed
+	case <-w.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case w.frames <- f:
+		return nil
+	default:
+		// The writer closes the socket on quitting, which ends the client's read loop
+		w.stop.Do(func() { close(w.quit) })
+		return ErrTooSlow
+	}
+}
+
+// Stop ends the writer, dropping queued frames, and waits for a write in progress to finish. Call
+// it before the socket's handler returns, after which the socket must not be written.
+func (w *Writer) Stop() {
+	w.stop.Do(func() { close(w.quit) })
+	<-w.done
+}
+
+// Done is closed once the writer has stopped, whether by Stop, Close or a failed write
+func (w *Writer) Done() <-chan struct{} {
+	return w.done
+}